@@ -95,9 +95,9 @@ func main() {
 
 		r.Route("/:articleID", func(r chi.Router) {
 			r.Use(ArticleCtx)
-			r.Get("/", getArticle)       // GET /articles/123
-			r.Put("/", updateArticle)    // PUT /articles/123
-			r.Delete("/", deleteArticle) // DELETE /articles/123
+			r.Get("/", getArticle).Name("article") // GET /articles/123
+			r.Put("/", updateArticle)              // PUT /articles/123
+			r.Delete("/", deleteArticle)           // DELETE /articles/123
 		})
 	})
 
@@ -141,9 +141,11 @@ func createArticle(ctx context.Context, fctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	// should really send back the json marshalled new article.
-	// build your own responder :)
-	fctx.Write([]byte(article.Title))
+	article.ID = fmt.Sprintf("%d", rand.Intn(100)+10)
+
+	// Respond with a 201, the location of the new article and the article
+	// in the format the client accepts
+	render.Created(fctx, ctx, "article", map[string]string{"articleID": article.ID}, article)
 }
 
 func getArticle(ctx context.Context, fctx *fasthttp.RequestCtx) {
@@ -0,0 +1,106 @@
+package chi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Assets resolves the fingerprinted URLs of the assets registered with
+// Mux.Fingerprint.
+type Assets struct {
+	urls map[string]string
+}
+
+// Fingerprint registers a route for each file under the `root` directory on
+// a URL made of the `prefix`, the file path and a hash of its content, ie.
+// `/assets/js/app.3f2a9c81d0.js` for root/js/app.js. The files are served
+// from memory as immutable, see Static, so clients cache them until their
+// content, and so their URL, changes. Dot files are left out.
+//
+// The plain paths can be served with FileServer alongside, for clients
+// still holding them, revalidating them with their ETag:
+//
+//	assets, err := r.Fingerprint("/assets", "./public")
+//	r.FileServer("/assets/*", "./public")
+//	tmpl := template.New("page").Funcs(assets.FuncMap())
+//
+// It returns an error if the files can't be read, before any route is
+// registered.
+func (mx *Mux) Fingerprint(prefix, root string) (*Assets, error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	assets := &Assets{urls: make(map[string]string)}
+	manifest := make(StaticManifest)
+
+	err := filepath.Walk(root, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(fi.Name(), ".") && file != root {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if fi.IsDir() {
+			return nil
+		}
+
+		body, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		url := prefix + "/" + fingerprintName(rel, body)
+		assets.urls[prefix+"/"+rel] = url
+		manifest[url] = StaticAsset{File: rel, Immutable: true}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mx.Static(root, manifest); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// fingerprintName inserts the hash of the content in the file name, before
+// its extension.
+func fingerprintName(name string, body []byte) string {
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:5])
+
+	ext := path.Ext(name)
+	if ext == "" {
+		return name + "." + hash
+	}
+	return name[:len(name)-len(ext)] + "." + hash + ext
+}
+
+// URL returns the fingerprinted URL of the asset served on `path` without
+// fingerprint, ie. `/assets/js/app.3f2a9c81d0.js` for `/assets/js/app.js`,
+// or the path itself if it isn't a fingerprinted asset.
+func (a *Assets) URL(path string) string {
+	if url, ok := a.urls[path]; ok {
+		return url
+	}
+	return path
+}
+
+// FuncMap returns the template funcs resolving asset URLs, ie.
+// `<script src="{{ asset "/assets/js/app.js" }}"></script>`.
+func (a *Assets) FuncMap() template.FuncMap {
+	return template.FuncMap{"asset": a.URL}
+}
@@ -0,0 +1,96 @@
+package chi
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// ErrBodyReadTimeout is the error of the reads of a request body taking
+// longer than allowed by WithBodyReadTimeout. Returned by a handler, it's
+// rendered with a 408.
+var ErrBodyReadTimeout error = &StatusError{
+	Status: fasthttp.StatusRequestTimeout,
+	Err:    errors.New("chi: request body read timed out"),
+}
+
+// bodyReadTimeout returns the handler reading the streamed request bodies
+// within the timeout, see WithBodyReadTimeout.
+func bodyReadTimeout(h Handler, timeout time.Duration) Handler {
+	return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		if !fctx.Request.IsBodyStream() {
+			h.ServeHTTPC(ctx, fctx)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		body := &budgetReader{
+			r:        fctx.Request.BodyStream(),
+			deadline: time.Now().Add(timeout),
+			cancel:   cancel,
+		}
+		fctx.Request.SetBodyStream(body, fctx.Request.Header.ContentLength())
+
+		// Reads blocked on a stalled client are failed by the connection
+		if c := fctx.Conn(); c != nil {
+			c.SetReadDeadline(body.deadline)
+			defer c.SetReadDeadline(time.Time{})
+		}
+
+		h.ServeHTTPC(ctx, fctx)
+
+		if body.timedOut() {
+			// The rest of the body is left unread on the connection
+			fctx.SetConnectionClose()
+			if fctx.Response.StatusCode() != fasthttp.StatusRequestTimeout {
+				if rctx := RouteContext(ctx); rctx != nil {
+					rctx.renderError(fctx, fasthttp.StatusRequestTimeout, ErrBodyReadTimeout)
+				} else {
+					renderError(fctx, fasthttp.StatusRequestTimeout, ErrBodyReadTimeout)
+				}
+			}
+		}
+	})
+}
+
+// A budgetReader reads a request body stream until its deadline, then
+// cancels the request context.
+type budgetReader struct {
+	r        io.Reader
+	deadline time.Time
+	cancel   context.CancelFunc
+	expired  int32
+}
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	if b.timedOut() {
+		return 0, ErrBodyReadTimeout
+	}
+	if time.Now().After(b.deadline) {
+		b.expire()
+		return 0, ErrBodyReadTimeout
+	}
+
+	n, err := b.r.Read(p)
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		b.expire()
+		return n, ErrBodyReadTimeout
+	}
+	return n, err
+}
+
+func (b *budgetReader) expire() {
+	atomic.StoreInt32(&b.expired, 1)
+	b.cancel()
+}
+
+func (b *budgetReader) timedOut() bool {
+	return atomic.LoadInt32(&b.expired) == 1
+}
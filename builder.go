@@ -0,0 +1,118 @@
+package chi
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// A Builder assembles the routes of a Mux from several packages, ie. from
+// their init funcs, and builds the Mux once they're all defined. Its
+// methods are safe for concurrent use, and record the routes without
+// touching a Mux until Build:
+//
+//	var Routes = chi.NewBuilder()
+//
+//	// in package users
+//	func init() {
+//		app.Routes.Route("/users", func(r chi.Router) { ... })
+//	}
+//
+//	// in main
+//	r := app.Routes.Build()
+type Builder struct {
+	parent []context.Context
+
+	mu          sync.Mutex
+	middlewares []interface{}
+	steps       []func(mx *Mux)
+}
+
+// NewBuilder returns a new Builder of Muxes with an optional parent context.
+func NewBuilder(parent ...context.Context) *Builder {
+	return &Builder{parent: parent}
+}
+
+// Use appends middlewares to the stack of the Mux, in front of all of its
+// routes whenever they're recorded.
+func (b *Builder) Use(middlewares ...interface{}) {
+	b.mu.Lock()
+	b.middlewares = append(b.middlewares, middlewares...)
+	b.mu.Unlock()
+}
+
+// Group records `fn` to define routes with a fresh middleware stack, like
+// Mux.Group.
+func (b *Builder) Group(fn func(r Router)) {
+	b.add(func(mx *Mux) {
+		mx.Group(fn)
+	})
+}
+
+// Route records `fn` to define the routes of a subrouter mounted along the
+// `pattern`, behind the inline `middlewares`, like Mux.Route.
+func (b *Builder) Route(pattern string, fn func(r Router), middlewares ...interface{}) {
+	b.add(func(mx *Mux) {
+		mx.Route(pattern, fn, middlewares...)
+	})
+}
+
+// Mount records the subrouter to mount along the `pattern`, like Mux.Mount.
+func (b *Builder) Mount(pattern string, handlers ...interface{}) {
+	b.add(func(mx *Mux) {
+		mx.Mount(pattern, handlers...)
+	})
+}
+
+func (b *Builder) add(step func(mx *Mux)) {
+	b.mu.Lock()
+	b.steps = append(b.steps, step)
+	b.mu.Unlock()
+}
+
+// Build returns a new Mux with the routes recorded so far, middlewares
+// first, then the routes in the order they were recorded. The Mux and its
+// subrouters are sealed: adding or removing routes panics, so the routes
+// can't change once built. Route conflicts panic like on a Mux.
+func (b *Builder) Build() *Mux {
+	b.mu.Lock()
+	middlewares := append([]interface{}{}, b.middlewares...)
+	steps := append([]func(mx *Mux){}, b.steps...)
+	b.mu.Unlock()
+
+	mx := NewMux(b.parent...)
+	mx.Use(middlewares...)
+	for _, step := range steps {
+		step(mx)
+	}
+	mx.buildHandler()
+	mx.seal()
+	return mx
+}
+
+// seal forbids route changes on the Mux and its mounted subrouters, whose
+// trees are read without locking from then on, see Mux.Freeze.
+func (mx *Mux) seal() {
+	tr := mx.router
+	tr.mu.Lock()
+	tr.sealed = true
+	entries := tr.entries
+	hosts := tr.hosts
+	tr.mu.Unlock()
+
+	for _, e := range entries {
+		if e.mount != nil {
+			e.mount.seal()
+		}
+	}
+	for _, h := range hosts {
+		h.mux.seal()
+	}
+}
+
+// checkSealed panics if the routes are sealed by a Builder.
+func (tr *treeRouter) checkSealed() {
+	if tr.sealed {
+		panic("chi: routes of a Mux built by a Builder can't be changed")
+	}
+}
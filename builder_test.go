@@ -0,0 +1,66 @@
+package chi
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b.Route(fmt.Sprintf("/pkg%d", i), func(r Router) {
+				r.Get("/", func(fctx *fasthttp.RequestCtx) {
+					fctx.Write([]byte(fmt.Sprintf("pkg%d", i)))
+				})
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	b.Group(func(r Router) {
+		r.Get("/", func(fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte("index"))
+		})
+	})
+	b.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte("mw "))
+			next.ServeHTTPC(ctx, fctx)
+		})
+	})
+
+	r := b.Build()
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	if resp := testRequest(t, ts, "GET", "/"); resp != "mw index" {
+		t.Fatalf("got '%s'", resp)
+	}
+	for i := 0; i < 10; i++ {
+		if resp := testRequest(t, ts, "GET", fmt.Sprintf("/pkg%d", i)); resp != fmt.Sprintf("mw pkg%d", i) {
+			t.Fatalf("got '%s'", resp)
+		}
+	}
+
+	if recv := catchPanic(func() { r.Get("/late", func(fctx *fasthttp.RequestCtx) {}) }); recv == nil {
+		t.Fatalf("expecting a panic for a route added to a built Mux")
+	}
+	if recv := catchPanic(func() { r.Remove("GET", "/pkg1") }); recv == nil {
+		t.Fatalf("expecting a panic for a route removed from a built Mux")
+	}
+
+	// Every build gets its own routes
+	if b.Build() == r {
+		t.Fatalf("expecting a new Mux")
+	}
+}
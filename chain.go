@@ -0,0 +1,33 @@
+package chi
+
+// Middlewares is a stack of middlewares defined once and applied to several
+// routers or to standalone handlers:
+//
+//	var api = chi.Chain(middleware.RequestID, middleware.Recoverer)
+//
+//	r.Use(api)
+//	admin.With(api, adminOnly).Get("/stats", stats)
+//	h := api.HandlerFunc(healthCheck)
+//
+// Routers add the middlewares of the stack one by one, so they're listed
+// and disabled by name like the others.
+type Middlewares []func(Handler) Handler
+
+// Chain returns the Middlewares stack of the `middlewares`, in order.
+func Chain(middlewares ...func(Handler) Handler) Middlewares {
+	return Middlewares(middlewares)
+}
+
+// Handler returns `h` wrapped with the middlewares, the first one being the
+// outermost.
+func (mws Middlewares) Handler(h Handler) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// HandlerFunc returns `h` wrapped with the middlewares, like Handler.
+func (mws Middlewares) HandlerFunc(h HandlerFunc) Handler {
+	return mws.Handler(h)
+}
@@ -0,0 +1,91 @@
+package chi
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func tagMiddleware(tag string) func(Handler) Handler {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.WriteString(tag)
+			next.ServeHTTPC(ctx, fctx)
+		})
+	}
+}
+
+func TestMiddlewares(t *testing.T) {
+	stack := Chain(tagMiddleware("a"), tagMiddleware("b"))
+	endpoint := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.WriteString(".")
+	}
+
+	r := NewRouter()
+	r.Use(stack)
+	r.Get("/", endpoint)
+	r.With(stack, tagMiddleware("c")).Get("/with", endpoint)
+	r.Get("/inline", stack, endpoint)
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/", "ab."},
+		{"/with", "ababc."},
+		{"/inline", "abab."},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.path)
+		r.ServeHTTP(&fctx)
+		if body := string(fctx.Response.Body()); body != tt.body {
+			t.Fatalf("%s: got '%s', want '%s'", tt.path, body, tt.body)
+		}
+	}
+	if n := len(r.Middlewares()); n != 2 {
+		t.Fatalf("expecting the 2 middlewares of the stack, got %d", n)
+	}
+
+	var fctx fasthttp.RequestCtx
+	stack.HandlerFunc(endpoint).ServeHTTPC(context.Background(), &fctx)
+	if body := string(fctx.Response.Body()); body != "ab." {
+		t.Fatalf("got '%s'", body)
+	}
+}
+
+func TestMuxRouteMiddlewares(t *testing.T) {
+	endpoint := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.WriteString(".")
+	}
+
+	r := NewRouter()
+	r.Use(tagMiddleware("r"))
+	r.Route("/admin", func(r Router) {
+		r.Use(tagMiddleware("s"))
+		r.Get("/", endpoint)
+	}, tagMiddleware("a"), Chain(tagMiddleware("b")))
+
+	sr := NewRouter()
+	sr.Get("/", endpoint)
+	r.Mount("/reports", tagMiddleware("m"), sr)
+	r.Get("/", endpoint)
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/admin", "rabs."},
+		{"/reports", "rm."},
+		{"/", "r."},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.path)
+		r.ServeHTTP(&fctx)
+		if body := string(fctx.Response.Body()); body != tt.body {
+			t.Fatalf("%s: got '%s', want '%s'", tt.path, body, tt.body)
+		}
+	}
+}
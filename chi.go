@@ -1,16 +1,35 @@
 package chi
 
 import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
 	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
 
 	"golang.org/x/net/context"
 )
 
-// NewRouter returns a new Mux object that implements the Router interface.
-// It accepts an optional parent context.Context argument used by all
-// request contexts useful for signaling a server shutdown.
-func NewRouter(parent ...context.Context) *Mux {
-	return NewMux(parent...)
+// NewRouter returns a new Mux object that implements the Router interface,
+// configured by the options before it serves, ie.
+//
+//	r := chi.NewRouter(chi.WithTrailingSlashRedirect(), chi.WithNotFound(notFound))
+func NewRouter(opts ...Option) *Mux {
+	return NewRouterContext(context.Background(), opts...)
+}
+
+// NewRouterContext is NewRouter with the parent context.Context used by all
+// request contexts, useful for signaling a server shutdown, ie.
+//
+//	r := chi.NewRouterContext(ctx, chi.WithCaseInsensitive())
+func NewRouterContext(parent context.Context, opts ...Option) *Mux {
+	mx := NewMux(parent)
+	for _, o := range opts {
+		o(mx)
+	}
+	return mx
 }
 
 // A Router consisting of the core routing methods used by chi's Mux.
@@ -23,22 +42,35 @@ type Router interface {
 	Handler
 
 	Use(middlewares ...interface{})
+	With(middlewares ...interface{}) Router
 	Group(fn func(r Router)) Router
-	Route(pattern string, fn func(r Router)) Router
+	Prefix(prefix string, fn func(r Router)) Router
+	Route(pattern string, fn func(r Router), middlewares ...interface{}) Router
 	Mount(pattern string, handlers ...interface{})
+	Host(pattern string) Router
+	Header(key, value string) Router
+	Produces(contentType string) Router
+	Cookie(name, value string) Router
+	Canary(percent int, sticky string) Router
 
-	Handle(pattern string, handlers ...interface{})
+	Handle(pattern string, handlers ...interface{}) *Route
+	Any(pattern string, handlers ...interface{}) *Route
+	Method(method, pattern string, handlers ...interface{}) *Route
+	Remove(method, pattern string) bool
 	NotFound(h HandlerFunc)
+	NotFoundMethod(method string, h HandlerFunc)
+	NotFoundFile(file string)
+	MethodNotAllowed(h HandlerFunc)
 
-	Connect(pattern string, handlers ...interface{})
-	Head(pattern string, handlers ...interface{})
-	Get(pattern string, handlers ...interface{})
-	Post(pattern string, handlers ...interface{})
-	Put(pattern string, handlers ...interface{})
-	Patch(pattern string, handlers ...interface{})
-	Delete(pattern string, handlers ...interface{})
-	Trace(pattern string, handlers ...interface{})
-	Options(pattern string, handlers ...interface{})
+	Connect(pattern string, handlers ...interface{}) *Route
+	Head(pattern string, handlers ...interface{}) *Route
+	Get(pattern string, handlers ...interface{}) *Route
+	Post(pattern string, handlers ...interface{}) *Route
+	Put(pattern string, handlers ...interface{}) *Route
+	Patch(pattern string, handlers ...interface{}) *Route
+	Delete(pattern string, handlers ...interface{}) *Route
+	Trace(pattern string, handlers ...interface{}) *Route
+	Options(pattern string, handlers ...interface{}) *Route
 }
 
 // Handler is like net/http's http.Handler, but also includes a
@@ -61,16 +93,107 @@ func (h HandlerFunc) ServeHTTP(fctx *fasthttp.RequestCtx) {
 	h(context.Background(), fctx)
 }
 
+// WrapF returns a Handler serving requests with the fasthttp handler, ie.
+// one of fasthttpadaptor.NewFastHTTPHandler. Routing methods and Mount
+// accept fasthttp.RequestHandler handlers as is too.
+func WrapF(h fasthttp.RequestHandler) Handler {
+	return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		h(fctx)
+	})
+}
+
+// WrapHTTP returns a Handler serving requests with the net/http handler,
+// converting them with fasthttpadaptor, ie. to mount net/http/pprof or a
+// Prometheus handler. Routing methods and Mount accept http.Handler and
+// http.HandlerFunc handlers as is too. The URL params of the route aren't
+// passed to the handler.
+func WrapHTTP(h http.Handler) Handler {
+	return WrapF(fasthttpadaptor.NewFastHTTPHandler(h))
+}
+
 // RouteContext returns chi's routing context object that holds url params
 // and a routing path for subrouters.
 func RouteContext(ctx context.Context) *Context {
 	rctx, _ := ctx.(*Context)
 	if rctx == nil {
-		rctx = ctx.Value(routeCtxKey).(*Context)
+		rctx, _ = ctx.Value(routeCtxKey).(*Context)
 	}
 	return rctx
 }
 
+// The user value of the fasthttp request holding the context of its route
+// handler, see RequestContext
+const requestCtxKey = "chi.request"
+
+// RequestContext returns the context the route handler of the request is
+// served with, for code handed the fasthttp request alone, ie. the package
+// funcs of render. It's nil for requests not routed by a Mux.
+func RequestContext(fctx *fasthttp.RequestCtx) context.Context {
+	ctx, _ := fctx.UserValue(requestCtxKey).(context.Context)
+	return ctx
+}
+
+// RouteMeta returns the metadata attached under `key` to the matched route
+// with Route.Meta, or nil. Metadata is set once the route matched, so it's
+// available to the handler, its inline and Group middlewares, and to the
+// router middlewares once the handler returned.
+func RouteMeta(ctx context.Context, key string) interface{} {
+	if rctx := RouteContext(ctx); rctx != nil {
+		for i := len(rctx.metas) - 1; i >= 0; i-- {
+			if v, ok := rctx.metas[i][key]; ok {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// RoutePattern returns the pattern of the route matched by the request, ie.
+// `/articles/:articleID`, to aggregate logs and metrics by route rather
+// than by URL. Like RouteMeta, it's set once the route matched.
+func RoutePattern(ctx context.Context) string {
+	if rctx := RouteContext(ctx); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+// MountPoint returns the concrete path prefix of the subrouter serving the
+// request, ie. `/hubs/123/users`, to build links relative to it.
+func MountPoint(ctx context.Context) string {
+	if rctx := RouteContext(ctx); rctx != nil {
+		return rctx.MountPoint()
+	}
+	return ""
+}
+
+// URLFor returns the URL path of the route named `name`, see Route.Name,
+// with its params set to `params`, ie. `/hubs/123/users/5` for the route
+// `/:userID` of a router mounted on `/hubs/:hubID/users`. Names are looked
+// up from the router of the route matched by the request, whose resolved
+// mount point is prefixed to the path. Params are percent-encoded, so the
+// URL is safe to redirect to, see ValidRedirect.
+func URLFor(ctx context.Context, name string, params map[string]string) (string, error) {
+	rctx := RouteContext(ctx)
+	if rctx == nil || rctx.router == nil {
+		return "", fmt.Errorf("chi: no route named '%s'", name)
+	}
+	path, err := rctx.router.url(name, params)
+	if err != nil {
+		return "", err
+	}
+	mountPoint := escapePath(rctx.mountPoint)
+	if path == "/" && mountPoint != "" {
+		// Index of the subrouter, served on its mount path
+		return mountPoint, nil
+	}
+	if path = mountPoint + path; strings.HasPrefix(path, "//") {
+		// An empty leading param, not a protocol-relative URL
+		path = "/%2F" + path[2:]
+	}
+	return path, nil
+}
+
 // URLParam returns a url paramter from the routing context.
 func URLParam(ctx context.Context, key string) string {
 	if rctx := RouteContext(ctx); rctx != nil {
@@ -78,3 +201,27 @@ func URLParam(ctx context.Context, key string) string {
 	}
 	return ""
 }
+
+// URLParams returns the url parameters of the routing context as a map, ie.
+// for loggers to record all of them. Like URLParam, a key set by a parent
+// router and a subrouter alike maps to the value of the parent.
+func URLParams(ctx context.Context) map[string]string {
+	rctx := RouteContext(ctx)
+	if rctx == nil {
+		return nil
+	}
+	m := make(map[string]string, len(rctx.Params))
+	for _, p := range rctx.Params {
+		if _, ok := m[p.Key]; !ok {
+			m[p.Key] = p.Value
+		}
+	}
+	return m
+}
+
+// URLParamInt returns a url parameter from the routing context parsed as
+// an int. Routes declaring the param as `:key|int` are guaranteed to hold
+// a valid value.
+func URLParamInt(ctx context.Context, key string) (int, error) {
+	return strconv.Atoi(URLParam(ctx, key))
+}
@@ -0,0 +1,25 @@
+// Package chitest provides helpers for unit testing handlers composed on a
+// chi router.
+package chitest
+
+import "github.com/hmgle/chi"
+
+// NewRouter returns a router with the routes defined by `fn`, leaving out
+// the middlewares named in `disabled` from every stack of the router, ie.
+// to test handlers behind an authentication middleware:
+//
+//	func Routes(r chi.Router) {
+//		r.Use(middleware.RequestID)
+//		r.Use(AdminOnly)
+//		r.Get("/accounts", listAccounts)
+//	}
+//
+//	r := chitest.NewRouter(Routes, "AdminOnly")
+//
+// Names match chi.MiddlewareEntry names, see chi.Mux.DisableMiddleware.
+func NewRouter(fn func(r chi.Router), disabled ...string) *chi.Mux {
+	r := chi.NewRouter()
+	r.DisableMiddleware(disabled...)
+	fn(r)
+	return r
+}
@@ -0,0 +1,159 @@
+package compat
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+type ctxKey int
+
+const exchangeCtxKey ctxKey = iota
+
+// exchange is the net/http request and response writer of a fasthttp
+// request, carried by the request context across the handlers of a chain so
+// net/http middlewares and handlers share them.
+type exchange struct {
+	fctx *fasthttp.RequestCtx
+	w    http.ResponseWriter
+	r    *http.Request
+}
+
+// adaptHandler returns the chi handler of a net/http handler, or `h` as is.
+func adaptHandler(h interface{}) interface{} {
+	switch h := h.(type) {
+	case http.Handler:
+		return httpHandler{h}
+	case func(http.ResponseWriter, *http.Request):
+		return httpHandler{http.HandlerFunc(h)}
+	default:
+		return h
+	}
+}
+
+// adaptMiddlewares returns the chi middlewares of net/http middlewares,
+// leaving the others as is.
+func adaptMiddlewares(middlewares []interface{}) []interface{} {
+	mws := make([]interface{}, len(middlewares))
+	for i, mw := range middlewares {
+		if hmw, ok := mw.(func(http.Handler) http.Handler); ok {
+			mws[i] = httpMiddleware(hmw)
+			continue
+		}
+		mws[i] = mw
+	}
+	return mws
+}
+
+// httpMiddleware returns the chi middleware of a net/http middleware, whose
+// next handler is served with the request and response writer it passes.
+func httpMiddleware(mw func(http.Handler) http.Handler) func(chi.Handler) chi.Handler {
+	return func(next chi.Handler) chi.Handler {
+		return httpHandler{mw(nextHandler{next})}
+	}
+}
+
+// httpHandler serves a fasthttp request with a net/http handler, passing it
+// the route params in the request context.
+type httpHandler struct {
+	h http.Handler
+}
+
+func (a httpHandler) ServeHTTPC(ctx context.Context, fctx *fasthttp.RequestCtx) {
+	// Within the chain of a net/http middleware
+	if ex, _ := ctx.Value(exchangeCtxKey).(*exchange); ex != nil && ex.fctx == fctx {
+		a.h.ServeHTTP(ex.w, ex.r.WithContext(ctx))
+		return
+	}
+
+	r, err := newRequest(fctx)
+	if err != nil {
+		fctx.Error(http.StatusText(http.StatusBadRequest), fasthttp.StatusBadRequest)
+		return
+	}
+	w := &responseWriter{fctx: fctx, header: http.Header{}}
+	ex := &exchange{fctx: fctx, w: w}
+	ex.r = r.WithContext(context.WithValue(ctx, exchangeCtxKey, ex))
+
+	a.h.ServeHTTP(w, ex.r)
+	w.flush()
+}
+
+// nextHandler serves the next handler of a net/http middleware with the
+// request and response writer passed by the middleware.
+type nextHandler struct {
+	next chi.Handler
+}
+
+func (n nextHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ex := r.Context().Value(exchangeCtxKey).(*exchange)
+	next := &exchange{fctx: ex.fctx, w: w}
+	ctx := context.WithValue(r.Context(), exchangeCtxKey, next)
+	next.r = r.WithContext(ctx)
+	n.next.ServeHTTPC(ctx, ex.fctx)
+}
+
+// newRequest returns the net/http request of a fasthttp request.
+func newRequest(fctx *fasthttp.RequestCtx) (*http.Request, error) {
+	r, err := http.NewRequest(string(fctx.Method()), string(fctx.RequestURI()), bytes.NewReader(fctx.PostBody()))
+	if err != nil {
+		return nil, err
+	}
+	fctx.Request.Header.VisitAll(func(k, v []byte) {
+		r.Header.Add(string(k), string(v))
+	})
+	r.Host = string(fctx.Host())
+	r.RequestURI = string(fctx.RequestURI())
+	if addr := fctx.RemoteAddr(); addr != nil {
+		r.RemoteAddr = addr.String()
+	}
+	return r, nil
+}
+
+// responseWriter is an http.ResponseWriter writing the fasthttp response.
+type responseWriter struct {
+	fctx        *fasthttp.RequestCtx
+	header      http.Header
+	wroteHeader bool
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.copyHeader()
+	w.fctx.SetStatusCode(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.fctx.Write(p)
+}
+
+// flush sets the headers of a response written by chi handlers past the
+// net/http ones, leaving their status as is.
+func (w *responseWriter) flush() {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.copyHeader()
+	}
+}
+
+func (w *responseWriter) copyHeader() {
+	for k, vs := range w.header {
+		w.fctx.Response.Header.Del(k)
+		for _, v := range vs {
+			w.fctx.Response.Header.Add(k, v)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Package compat mirrors the routing API of upstream chi,
+// github.com/pressly/chi, on the fasthttp Mux of chi, so services built on
+// upstream chi switch transports by changing their import path:
+//
+//	r := compat.NewRouter()
+//	r.Use(authenticate) // func(http.Handler) http.Handler
+//	r.Get("/users/{userID}", func(w http.ResponseWriter, r *http.Request) {
+//		w.Write([]byte(compat.URLParam(r, "userID")))
+//	})
+//	fasthttp.ListenAndServe(":3333", r.ServeHTTP)
+//
+// Patterns use the brace syntax of upstream chi. The routing methods take
+// the net/http handlers and middlewares of upstream chi, served with their
+// URL params, and the handlers and middlewares of chi too, so handlers can
+// move to the fasthttp signatures one at a time. Native returns the
+// underlying chi.Mux for the rest of its API.
+package compat
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// Router is the routing API of upstream chi. Handlers and middlewares are
+// either of net/http, see Mux.Handle and Mux.Use, or of chi.
+type Router interface {
+	chi.Handler
+
+	Use(middlewares ...interface{})
+	With(middlewares ...interface{}) Router
+	Group(fn func(r Router)) Router
+	Route(pattern string, fn func(r Router)) Router
+	Mount(pattern string, h interface{})
+
+	Handle(pattern string, h interface{})
+	HandleFunc(pattern string, h interface{})
+	Method(method, pattern string, h interface{})
+	MethodFunc(method, pattern string, h interface{})
+
+	Connect(pattern string, h interface{})
+	Delete(pattern string, h interface{})
+	Get(pattern string, h interface{})
+	Head(pattern string, h interface{})
+	Options(pattern string, h interface{})
+	Patch(pattern string, h interface{})
+	Post(pattern string, h interface{})
+	Put(pattern string, h interface{})
+	Trace(pattern string, h interface{})
+
+	NotFound(h interface{})
+	MethodNotAllowed(h interface{})
+}
+
+var _ Router = &Mux{}
+
+// Mux is a Router routing on a chi.Mux.
+type Mux struct {
+	mx *chi.Mux
+}
+
+// NewRouter returns a new Mux with the brace syntax of upstream chi, and an
+// optional parent context, see chi.NewMux.
+func NewRouter(parent ...context.Context) *Mux {
+	mx := chi.NewMux(parent...)
+	mx.Syntax(chi.BraceSyntax)
+	return &Mux{mx: mx}
+}
+
+// NewMux is NewRouter, like in upstream chi.
+func NewMux(parent ...context.Context) *Mux {
+	return NewRouter(parent...)
+}
+
+// Native returns the chi.Mux the Mux routes on.
+func (mx *Mux) Native() *chi.Mux {
+	return mx.mx
+}
+
+// ServeHTTP is the fasthttp request handler of the Mux.
+func (mx *Mux) ServeHTTP(fctx *fasthttp.RequestCtx) {
+	mx.mx.ServeHTTP(fctx)
+}
+
+// ServeHTTPC implements chi.Handler, so the Mux can be mounted on a chi
+// router.
+func (mx *Mux) ServeHTTPC(ctx context.Context, fctx *fasthttp.RequestCtx) {
+	mx.mx.ServeHTTPC(ctx, fctx)
+}
+
+// Use appends middlewares to the stack of the Mux, either
+// `func(http.Handler) http.Handler` ones or chi middlewares.
+func (mx *Mux) Use(middlewares ...interface{}) {
+	mx.mx.Use(adaptMiddlewares(middlewares)...)
+}
+
+// With returns an inline Router with the middlewares appended to the stack.
+func (mx *Mux) With(middlewares ...interface{}) Router {
+	return wrap(mx.mx.With(adaptMiddlewares(middlewares)...))
+}
+
+// Group creates an inline Router with a fresh middleware stack.
+func (mx *Mux) Group(fn func(r Router)) Router {
+	return wrap(mx.mx.Group(func(r chi.Router) {
+		if fn != nil {
+			fn(wrap(r))
+		}
+	}))
+}
+
+// Route mounts a subrouter along the `pattern` with the routes of `fn`.
+func (mx *Mux) Route(pattern string, fn func(r Router)) Router {
+	return wrap(mx.mx.Route(pattern, func(r chi.Router) {
+		if fn != nil {
+			fn(wrap(r))
+		}
+	}))
+}
+
+// Mount attaches a subrouter or handler along the `pattern`, ie. another
+// Mux.
+func (mx *Mux) Mount(pattern string, h interface{}) {
+	if sr, ok := h.(*Mux); ok {
+		mx.mx.Mount(pattern, sr.mx)
+		return
+	}
+	mx.mx.Mount(pattern, adaptHandler(h))
+}
+
+// Handle adds a route for all methods, for an http.Handler or a chi handler.
+func (mx *Mux) Handle(pattern string, h interface{}) {
+	mx.mx.Handle(pattern, adaptHandler(h))
+}
+
+// HandleFunc adds a route for all methods, for an http.HandlerFunc.
+func (mx *Mux) HandleFunc(pattern string, h interface{}) {
+	mx.Handle(pattern, h)
+}
+
+// Method adds a route for the http `method`.
+func (mx *Mux) Method(method, pattern string, h interface{}) {
+	mx.mx.Method(method, pattern, adaptHandler(h))
+}
+
+// MethodFunc adds a route for the http `method`, for an http.HandlerFunc.
+func (mx *Mux) MethodFunc(method, pattern string, h interface{}) {
+	mx.Method(method, pattern, h)
+}
+
+// Connect adds a route for CONNECT requests.
+func (mx *Mux) Connect(pattern string, h interface{}) {
+	mx.mx.Connect(pattern, adaptHandler(h))
+}
+
+// Delete adds a route for DELETE requests.
+func (mx *Mux) Delete(pattern string, h interface{}) {
+	mx.mx.Delete(pattern, adaptHandler(h))
+}
+
+// Get adds a route for GET requests.
+func (mx *Mux) Get(pattern string, h interface{}) {
+	mx.mx.Get(pattern, adaptHandler(h))
+}
+
+// Head adds a route for HEAD requests.
+func (mx *Mux) Head(pattern string, h interface{}) {
+	mx.mx.Head(pattern, adaptHandler(h))
+}
+
+// Options adds a route for OPTIONS requests.
+func (mx *Mux) Options(pattern string, h interface{}) {
+	mx.mx.Options(pattern, adaptHandler(h))
+}
+
+// Patch adds a route for PATCH requests.
+func (mx *Mux) Patch(pattern string, h interface{}) {
+	mx.mx.Patch(pattern, adaptHandler(h))
+}
+
+// Post adds a route for POST requests.
+func (mx *Mux) Post(pattern string, h interface{}) {
+	mx.mx.Post(pattern, adaptHandler(h))
+}
+
+// Put adds a route for PUT requests.
+func (mx *Mux) Put(pattern string, h interface{}) {
+	mx.mx.Put(pattern, adaptHandler(h))
+}
+
+// Trace adds a route for TRACE requests.
+func (mx *Mux) Trace(pattern string, h interface{}) {
+	mx.mx.Trace(pattern, adaptHandler(h))
+}
+
+// NotFound sets the handler of missing routes.
+func (mx *Mux) NotFound(h interface{}) {
+	mx.mx.NotFound(handlerFunc(h))
+}
+
+// MethodNotAllowed sets the handler of paths routed for other methods only.
+func (mx *Mux) MethodNotAllowed(h interface{}) {
+	mx.mx.MethodNotAllowed(handlerFunc(h))
+}
+
+// URLParam returns the url param `key` of the request, like in upstream
+// chi.
+func URLParam(r *http.Request, key string) string {
+	return chi.URLParam(r.Context(), key)
+}
+
+// URLParamFromCtx returns the url param `key` from the request context.
+func URLParamFromCtx(ctx context.Context, key string) string {
+	return chi.URLParam(ctx, key)
+}
+
+// RouteContext returns the routing context of chi.
+func RouteContext(ctx context.Context) *chi.Context {
+	return chi.RouteContext(ctx)
+}
+
+// wrap returns the Router of a chi.Router.
+func wrap(r chi.Router) *Mux {
+	return &Mux{mx: r.(*chi.Mux)}
+}
+
+// handlerFunc returns the chi.HandlerFunc of a net/http or chi handler.
+func handlerFunc(h interface{}) chi.HandlerFunc {
+	switch h := adaptHandler(h).(type) {
+	case chi.HandlerFunc:
+		return h
+	case func(context.Context, *fasthttp.RequestCtx):
+		return h
+	case chi.Handler:
+		return h.ServeHTTPC
+	case fasthttp.RequestHandler:
+		return chi.WrapF(h).ServeHTTPC
+	case func(*fasthttp.RequestCtx):
+		return chi.WrapF(h).ServeHTTPC
+	default:
+		panic(fmt.Sprintf("compat: unsupported handler type %T", h))
+	}
+}
@@ -0,0 +1,87 @@
+package compat
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// statusRecorder records the status of the response in a header, like the
+// net/http logging middlewares wrapping the response writer.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func TestMux(t *testing.T) {
+	var statuses []int
+
+	r := NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w, status: 200}
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), "user", "peter")))
+			statuses = append(statuses, rec.status)
+		})
+	})
+	r.Get("/users/{userID:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(201)
+		w.Write([]byte(URLParam(r, "userID") + " " + r.Context().Value("user").(string)))
+	})
+	r.Route("/hubs/{hubID}", func(r Router) {
+		r.Get("/", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.WriteString("hub " + URLParamFromCtx(ctx, "hubID"))
+		})
+	})
+
+	admin := NewRouter()
+	admin.With(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Admin", "1")
+			next.ServeHTTP(w, r)
+		})
+	}).Get("/{page}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("admin " + URLParam(r, "page")))
+	}))
+	r.Mount("/admin", admin)
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "lost", 404)
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+		admin  string
+	}{
+		{"/users/5", 201, "5 peter", ""},
+		{"/hubs/123", 200, "hub 123", ""},
+		{"/admin/stats", 200, "admin stats", "1"},
+		{"/users/me", 404, "lost\n", ""},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.path)
+		r.ServeHTTP(&fctx)
+		if fctx.Response.StatusCode() != tt.status || string(fctx.Response.Body()) != tt.body {
+			t.Fatalf("%s: got %d '%s'", tt.path, fctx.Response.StatusCode(), fctx.Response.Body())
+		}
+		if admin := string(fctx.Response.Header.Peek("X-Admin")); admin != tt.admin {
+			t.Fatalf("%s: expecting X-Admin '%s', got '%s'", tt.path, tt.admin, admin)
+		}
+	}
+
+	// The writer of the middleware saw the status of the net/http handler
+	if len(statuses) != 4 || statuses[0] != 201 {
+		t.Fatalf("expecting the statuses of the 4 requests, got %v", statuses)
+	}
+}
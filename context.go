@@ -1,6 +1,13 @@
 package chi
 
-import "golang.org/x/net/context"
+import (
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+
+	"golang.org/x/net/context"
+)
 
 var _ context.Context = &Context{}
 
@@ -18,20 +25,125 @@ type Context struct {
 	// URL parameter key and values
 	Params params
 
+	// Param values of the route being matched, as slices of the path
+	pending []pendingParam
+
+	// Copy of RoutePath routed by a subrouter
+	pathBuf []byte
+
 	// Routing path override used by subrouters
 	RoutePath string
+
+	// Named durations recorded with Timing
+	spans []Span
+	mu    sync.Mutex
+
+	// Errors reported with ReportError, guarded by mu
+	errs []error
+
+	// Set once a NotFound handler served the request
+	notFound bool
+
+	// Pattern of the matched route, joined across mounted subrouters
+	routePattern string
+
+	// Request path prefix routed to the current subrouter
+	mountPoint string
+
+	// Router of the matched route, naming the routes of URLFor
+	router *treeRouter
+
+	// Renderer of the errors returned by handlers, of the innermost router
+	// setting one
+	errorRenderer func(fctx *fasthttp.RequestCtx, status int, err error)
+
+	// Metadata of the routes matched by the routers serving the request
+	metas []map[string]interface{}
+
+	// Set by a root router storing the params as user values, see
+	// Mux.UserValueParams
+	userValueParams bool
+
+	// Share of the request splitting it at random across canaries, from 1
+	// to 100, 0 until drawn, see Mux.Canary
+	canaryDraw int
+
+	// Routing trace of the request, recorded while tracing, and number of
+	// tree nodes visited, see Mux.TraceHeader
+	tracing bool
+	trace   []string
+	visited int
+}
+
+// A pendingParam is a param value of the route being matched, see
+// tree.findRoute.
+type pendingParam struct {
+	key   string
+	value []byte
 }
 
 // neContext returns a new routing context object.
 func newContext(parent context.Context) *Context {
-	rctx := &Context{}
-	ctx := context.WithValue(parent, routeCtxKey, rctx)
-	rctx.Context = ctx
-	return rctx
+	return &Context{Context: parent}
+}
+
+// Value returns the routing context itself for its key, so RouteContext
+// finds it in the contexts derived from it, and the values of its parent
+// context otherwise. The parent is swapped in place by a Mux served with
+// a context of its own, without deriving one per request.
+func (x *Context) Value(key interface{}) interface{} {
+	if key == routeCtxKey {
+		return x
+	}
+	return x.Context.Value(key)
 }
 
 // reset a routing context to its initial state.
 func (x *Context) reset() {
 	x.Params = x.Params[:0]
+	x.pending = x.pending[:0]
 	x.RoutePath = ""
+	x.spans = x.spans[:0]
+	x.errs = x.errs[:0]
+	x.notFound = false
+	x.routePattern = ""
+	x.mountPoint = ""
+	x.router = nil
+	x.errorRenderer = nil
+	x.metas = x.metas[:0]
+	x.userValueParams = false
+	x.canaryDraw = 0
+	x.tracing = false
+	x.trace = x.trace[:0]
+	x.visited = 0
+}
+
+// RoutePattern returns the pattern of the route matched by the request,
+// including the patterns subrouters are mounted on, ie.
+// `/admin/users/:id`. It's empty until a route matched.
+func (x *Context) RoutePattern() string {
+	return x.routePattern
+}
+
+// MountPoint returns the request path the subrouter serving the request is
+// mounted on, with its params resolved, ie. `/hubs/123/users` for a router
+// mounted on `/hubs/:hubID/users`. It's empty outside of subrouters.
+func (x *Context) MountPoint() string {
+	return x.mountPoint
+}
+
+// addRoutePattern appends the pattern matched by a router to the pattern
+// of the route the router is mounted on, if any.
+func (x *Context) addRoutePattern(pattern string) {
+	base := x.routePattern
+	switch {
+	case base == "":
+		x.routePattern = pattern
+	case strings.HasSuffix(base, "/*"):
+		x.routePattern = base[:len(base)-2] + pattern
+	case pattern == "/":
+		// Subrouter matched on its mount path, ie. `/admin`
+	default:
+		x.routePattern = base + pattern
+	}
 }
@@ -0,0 +1,115 @@
+package chi
+
+import (
+	"fmt"
+	"time"
+)
+
+// A ChangeKind is the kind of a route Change reported by DiffRoutes.
+type ChangeKind int
+
+const (
+	RouteAdded ChangeKind = iota
+	RouteRemoved
+	RouteChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case RouteAdded:
+		return "added"
+	case RouteRemoved:
+		return "removed"
+	case RouteChanged:
+		return "changed"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// A Change is a route difference between two routers. Method is "*" for
+// routes of all methods, and Conditions are the request conditions of the
+// route, ie. `[x-api-version=2]`, see RouteInfo. Middlewares counts the middlewares in front of the
+// route's handler, from the router stacks down to the inline ones, and
+// Timeout is the one set with WithTimeout.
+type Change struct {
+	Kind       ChangeKind
+	Method     string
+	Pattern    string
+	Conditions string
+
+	OldMiddlewares int
+	NewMiddlewares int
+
+	OldTimeout time.Duration
+	NewTimeout time.Duration
+}
+
+func (c Change) String() string {
+	route := c.Method + " " + c.Pattern
+	if c.Conditions != "" {
+		route += " " + c.Conditions
+	}
+	switch c.Kind {
+	case RouteAdded:
+		return "+ " + route
+	case RouteRemoved:
+		return "- " + route
+	}
+	if c.OldTimeout != c.NewTimeout {
+		return fmt.Sprintf("~ %s (middlewares %d -> %d, timeout %s -> %s)", route,
+			c.OldMiddlewares, c.NewMiddlewares, c.OldTimeout, c.NewTimeout)
+	}
+	return fmt.Sprintf("~ %s (middlewares %d -> %d)", route, c.OldMiddlewares, c.NewMiddlewares)
+}
+
+// DiffRoutes reports the routes added, removed or whose middleware count or
+// timeout changed from the `old` router to the `new` one, including the routes of
+// mounted subrouters, ie. to guard against dropping routes in a refactor:
+//
+//	func TestRoutes(t *testing.T) {
+//		for _, c := range chi.DiffRoutes(legacyRouter(), newRouter()) {
+//			if c.Kind != chi.RouteAdded {
+//				t.Error(c)
+//			}
+//		}
+//	}
+//
+// Routes are matched by method, pattern, ignoring param names, and request
+// conditions, so the variants of a path, ie. the routes of Header or
+// Canary, are diffed on their own.
+func DiffRoutes(old, new *Mux) []Change {
+	oldRoutes := make(map[string]RouteInfo)
+	for _, ri := range old.routeList() {
+		oldRoutes[diffKey(ri)] = ri
+	}
+
+	var changes []Change
+	seen := make(map[string]bool)
+	for _, ri := range new.routeList() {
+		key := diffKey(ri)
+		seen[key] = true
+
+		o, ok := oldRoutes[key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: RouteAdded, Method: ri.Method, Pattern: ri.Pattern,
+				Conditions: ri.Conditions, NewMiddlewares: ri.Middlewares, NewTimeout: ri.Timeout})
+		case o.Middlewares != ri.Middlewares || o.Timeout != ri.Timeout:
+			changes = append(changes, Change{Kind: RouteChanged, Method: ri.Method, Pattern: ri.Pattern,
+				Conditions: ri.Conditions, OldMiddlewares: o.Middlewares, NewMiddlewares: ri.Middlewares,
+				OldTimeout: o.Timeout, NewTimeout: ri.Timeout})
+		}
+	}
+	for _, ri := range old.routeList() {
+		if !seen[diffKey(ri)] {
+			changes = append(changes, Change{Kind: RouteRemoved, Method: ri.Method, Pattern: ri.Pattern,
+				Conditions: ri.Conditions, OldMiddlewares: ri.Middlewares, OldTimeout: ri.Timeout})
+		}
+	}
+	return changes
+}
+
+// diffKey is the key matching the routes of two routers in DiffRoutes.
+func diffKey(ri RouteInfo) string {
+	return ri.Method + " " + normalizePattern(ri.native) + " " + ri.Conditions
+}
@@ -0,0 +1,273 @@
+package chi
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// condKind is the kind of request condition of a route.
+type condKind int
+
+const (
+	condHeader   condKind = iota // see Mux.Header
+	condCookie                   // see Mux.Cookie
+	condProduces                 // see Mux.Produces
+	condCanary                   // see Mux.Canary
+)
+
+// A routeCond is a request condition of a route.
+type routeCond struct {
+	kind condKind
+
+	// Header or cookie name, the sticky cookie of canaries
+	name string
+
+	// Value, lowercased but for cookies, matching any value with its
+	// prefix when ending with a '*', or the media type of condProduces
+	value string
+
+	// Share of the requests of condCanary, in percent
+	percent int
+}
+
+// quality returns how well the request matches the condition, from 0 when
+// it doesn't to 1, the quality of the Accept header for negotiated media
+// types.
+func (c routeCond) quality(rctx *Context, fctx *fasthttp.RequestCtx) float64 {
+	var v string
+	switch c.kind {
+	case condProduces:
+		return acceptQuality(fctx, c.value)
+	case condCanary:
+		if canaryPick(rctx, fctx, c.name, c.percent) {
+			return 1
+		}
+		return 0
+	case condCookie:
+		v = string(fctx.Request.Header.Cookie(c.name))
+	default:
+		v = strings.ToLower(string(fctx.Request.Header.Peek(c.name)))
+	}
+	if strings.HasSuffix(c.value, "*") {
+		if strings.HasPrefix(v, c.value[:len(c.value)-1]) {
+			return 1
+		}
+		return 0
+	}
+	if v == c.value {
+		return 1
+	}
+	return 0
+}
+
+// routeConds are the conditions a request must all match to be served by
+// a route.
+type routeConds []routeCond
+
+func (cs routeConds) quality(rctx *Context, fctx *fasthttp.RequestCtx) float64 {
+	q := 1.0
+	for _, c := range cs {
+		if q *= c.quality(rctx, fctx); q == 0 {
+			break
+		}
+	}
+	return q
+}
+
+// negotiated reports whether the conditions include a media type.
+func (cs routeConds) negotiated() bool {
+	for _, c := range cs {
+		if c.kind == condProduces {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the conditions in a canonical form, telling apart routes of
+// the same pattern.
+func (cs routeConds) key() string {
+	if len(cs) == 0 {
+		return ""
+	}
+	keys := make([]string, len(cs))
+	for i, c := range cs {
+		switch c.kind {
+		case condCookie:
+			keys[i] = "cookie " + c.name + "=" + c.value
+		case condProduces:
+			keys[i] = "produces " + c.value
+		case condCanary:
+			keys[i] = fmt.Sprintf("canary %d%%", c.percent)
+			if c.name != "" {
+				keys[i] += " sticky " + c.name
+			}
+		default:
+			keys[i] = strings.ToLower(c.name) + "=" + c.value
+		}
+	}
+	sort.Strings(keys)
+	return " [" + strings.Join(keys, ", ") + "]"
+}
+
+// A variant is a handler of a leaf node serving the requests matching its
+// conditions.
+type variant struct {
+	conds   routeConds
+	handler Handler
+	route   *routeEntry
+}
+
+// Header returns an inline router whose routes only serve the requests
+// whose `key` header matches the `value`, ignoring case, so requests on
+// the same path are dispatched by the router to different handlers, ie.
+//
+//	r.Get("/items", listItems)
+//	r.Header("X-API-Version", "2").Get("/items", listItemsV2)
+//	r.Header("Content-Type", "multipart/*").Post("/items", uploadItems)
+//
+// A value ending with a '*' matches the values with its prefix. Routes
+// with conditions are tried in the order they were registered, before the
+// route of the path without conditions if any. Requests matching none of
+// the routes of a path are answered with the NotFound handler.
+func (mx *Mux) Header(key, value string) Router {
+	return mx.withCond(routeCond{kind: condHeader, name: key, value: strings.ToLower(value)})
+}
+
+// Cookie returns an inline router whose routes only serve the requests
+// whose cookie `name` matches the `value`, like Header does but for the
+// case of the values, which matters in cookies, ie. to let clients opt in
+// a canary with a `beta=1` cookie.
+func (mx *Mux) Cookie(name, value string) Router {
+	return mx.withCond(routeCond{kind: condCookie, name: name, value: value})
+}
+
+// Canary returns an inline router whose routes serve a `percent` share of
+// the requests of their paths, the other requests going on to the other
+// routes of the paths, for gradual rollouts, ie.
+//
+//	r.Get("/search", search)
+//	r.Canary(5, "session").Get("/search", searchV2)
+//
+// Requests with the `sticky` cookie are split on a hash of its value, so a
+// client stays on one side for as long as the share doesn't change. The
+// others, and all requests with an empty `sticky`, are split at random,
+// drawn once per request so it stays on one side across the canaries of
+// the routers serving it.
+func (mx *Mux) Canary(percent int, sticky string) Router {
+	if percent < 0 || percent > 100 {
+		panic(fmt.Sprintf("chi: canary share of %d%% out of 0-100", percent))
+	}
+	return mx.withCond(routeCond{kind: condCanary, name: sticky, percent: percent})
+}
+
+// canaryPick reports whether the request falls in the share of a canary.
+func canaryPick(rctx *Context, fctx *fasthttp.RequestCtx, sticky string, percent int) bool {
+	var v []byte
+	if sticky != "" {
+		v = fctx.Request.Header.Cookie(sticky)
+	}
+	if len(v) == 0 {
+		if rctx.canaryDraw == 0 {
+			rctx.canaryDraw = rand.Intn(100) + 1
+		}
+		return rctx.canaryDraw <= percent
+	}
+	h := fnv.New32a()
+	h.Write(v)
+	return int(h.Sum32()%100) < percent
+}
+
+// withCond returns an inline router adding the condition to the routes of
+// the Mux.
+func (mx *Mux) withCond(c routeCond) Router {
+	w := mx.With().(*Mux)
+	w.conds = append(append(routeConds(nil), mx.conds...), c)
+	return w
+}
+
+// Produces returns an inline router whose routes only serve the requests
+// accepting the media type `contentType`, so the router negotiates the
+// format of a resource with the Accept header, ie.
+//
+//	r.Produces("application/json").Get("/reports", listReportsJSON)
+//	r.Produces("text/csv").Get("/reports", listReportsCSV)
+//
+// The route of the media type of the highest quality in the Accept header
+// serves the request, the first one registered for requests without an
+// Accept header. Requests accepting none of the media types of a path are
+// answered with a 406 rendered by the ErrorRenderer, unless the path has a
+// route without conditions. The responses get a `Vary: Accept` header.
+func (mx *Mux) Produces(contentType string) Router {
+	return mx.withCond(routeCond{kind: condProduces, value: strings.ToLower(contentType)})
+}
+
+// acceptQuality returns the quality of the media type in the Accept header
+// of the request, taken from its most specific media range, or 1 without an
+// Accept header.
+func acceptQuality(fctx *fasthttp.RequestCtx, mediaType string) float64 {
+	list := HeaderList(fctx, "Accept")
+	if len(list) == 0 {
+		return 1
+	}
+
+	typ := mediaType
+	if i := strings.IndexByte(typ, '/'); i >= 0 {
+		typ = typ[:i]
+	}
+	q, specificity := 0.0, 0
+	for _, elem := range list {
+		qv := parseQValue(elem)
+		s := 0
+		switch r := strings.ToLower(qv.Value); r {
+		case mediaType:
+			s = 3
+		case typ + "/*":
+			s = 2
+		case "*/*":
+			s = 1
+		}
+		if s > specificity {
+			q, specificity = qv.Q, s
+		}
+	}
+	return q
+}
+
+// handlerFor returns the handler of the leaf node serving the request, of
+// the variant whose conditions the request matches best, the first one
+// registered on ties, or the handler without conditions. Without a request,
+// the handler without conditions comes first.
+func (n *node) handlerFor(rctx *Context, fctx *fasthttp.RequestCtx) (Handler, *routeEntry) {
+	if fctx == nil && n.handler == nil && len(n.variants) > 0 {
+		return n.variants[0].handler, n.variants[0].route
+	}
+	if fctx != nil {
+		var best *variant
+		var bestQ float64
+		for _, v := range n.variants {
+			if q := v.conds.quality(rctx, fctx); q > bestQ {
+				best, bestQ = v, q
+			}
+		}
+		if best != nil {
+			return best.handler, best.route
+		}
+	}
+	return n.handler, n.route
+}
+
+// negotiated reports whether a variant of the leaf node has a media type.
+func (n *node) negotiated() bool {
+	for _, v := range n.variants {
+		if v.conds.negotiated() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+// Package docgen generates documentation from the routes of a chi router.
+package docgen
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hmgle/chi"
+)
+
+// MetaKey is the route metadata key of the Operation merged into the one
+// generated for a route, see chi.Route.Meta, ie.
+//
+//	r.Get("/articles/:id", getArticle).Meta(docgen.MetaKey, docgen.Operation{
+//		Summary: "Get an article",
+//		Tags:    []string{"articles"},
+//	})
+const MetaKey = "docgen.operation"
+
+// A Spec is an OpenAPI 3 document.
+type Spec struct {
+	OpenAPI string              `json:"openapi"`
+	Info    Info                `json:"info"`
+	Paths   map[string]PathItem `json:"paths"`
+}
+
+// Info is the metadata of the API of a Spec.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// A PathItem holds the operations of a path, keyed by lowercase method.
+type PathItem map[string]*Operation
+
+// An Operation is an API operation on a path.
+type Operation struct {
+	OperationID string              `json:"operationId,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// A Parameter is a parameter of an Operation.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// A Schema is the type of a Parameter.
+type Schema struct {
+	Type    string `json:"type"`
+	Format  string `json:"format,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// A Response is a response of an Operation.
+type Response struct {
+	Description string `json:"description"`
+}
+
+// methods are the methods documented for the routes of all methods.
+var methods = []string{"get", "put", "post", "delete", "patch"}
+
+// OpenAPI returns the OpenAPI 3 skeleton of the routes of the router and
+// its mounted subrouters: their paths with params in braces, ie.
+// `/articles/{id}`, methods and path params, typed after the param types
+// of the patterns. Route names are used as operation IDs, and each
+// Operation attached to a route under MetaKey is merged into the generated
+// one. Operations get a default 200 response unless they set responses.
+//
+// Routes of all methods are documented for the GET, PUT, POST, DELETE and
+// PATCH methods, and routes of other methods than OpenAPI's are left out.
+func OpenAPI(r *chi.Mux, info Info) *Spec {
+	spec := &Spec{OpenAPI: "3.0.3", Info: info, Paths: make(map[string]PathItem)}
+
+	for _, ri := range r.Routes() {
+		path, params := convertPattern(ri.Pattern)
+		item := spec.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+		}
+
+		ms := methods
+		if ri.Method != "*" {
+			ms = strings.Split(strings.ToLower(ri.Method), ",")
+		}
+		for _, m := range ms {
+			if !isOpenAPIMethod(m) {
+				continue
+			}
+			if _, ok := item[m]; ok {
+				// Routes of the same path with request conditions
+				continue
+			}
+			item[m] = operation(ri, params)
+		}
+		if len(item) > 0 {
+			spec.Paths[path] = item
+		}
+	}
+	return spec
+}
+
+// JSON returns the OpenAPI 3 document of the routes of the router as
+// indented JSON, see OpenAPI.
+func JSON(r *chi.Mux, info Info) ([]byte, error) {
+	return json.MarshalIndent(OpenAPI(r, info), "", "  ")
+}
+
+// operation returns the Operation of a route.
+func operation(ri chi.RouteInfo, params []Parameter) *Operation {
+	op := &Operation{OperationID: ri.Name, Parameters: params}
+	if meta, ok := ri.Meta[MetaKey].(Operation); ok {
+		if meta.OperationID != "" {
+			op.OperationID = meta.OperationID
+		}
+		op.Summary = meta.Summary
+		op.Description = meta.Description
+		op.Tags = meta.Tags
+		op.Parameters = append(append([]Parameter(nil), params...), meta.Parameters...)
+		op.Responses = meta.Responses
+	}
+	if len(op.Responses) == 0 {
+		op.Responses = map[string]Response{"200": {Description: "OK"}}
+	}
+	return op
+}
+
+// convertPattern returns the OpenAPI path of a route pattern and its path
+// params.
+func convertPattern(pattern string) (string, []Parameter) {
+	var params []Parameter
+	segments := strings.Split(pattern, "/")
+	for i, s := range segments {
+		switch {
+		case strings.HasPrefix(s, ":"):
+			name, typ := s[1:], ""
+			if p := strings.IndexByte(name, '|'); p >= 0 {
+				name, typ = name[:p], name[p+1:]
+			}
+			params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: paramSchema(typ)})
+			segments[i] = "{" + name + "}"
+		case strings.HasPrefix(s, "*"):
+			name := s[1:]
+			if name == "" {
+				name = "wildcard"
+			}
+			params = append(params, Parameter{Name: name, In: "path", Required: true,
+				Description: "Rest of the path, slashes included", Schema: &Schema{Type: "string"}})
+			segments[i] = "{" + name + "}"
+		}
+	}
+	path := strings.Join(segments, "/")
+	if len(path) > 1 && strings.HasSuffix(path, "/") {
+		// Index of a subrouter, served on its mount path
+		path = path[:len(path)-1]
+	}
+	return path, params
+}
+
+// paramSchema returns the schema of a param type.
+func paramSchema(typ string) *Schema {
+	switch {
+	case typ == "int":
+		return &Schema{Type: "integer"}
+	case typ == "uuid":
+		return &Schema{Type: "string", Format: "uuid"}
+	case typ == "slug":
+		return &Schema{Type: "string", Pattern: "^[a-z0-9]+(-[a-z0-9]+)*$"}
+	case strings.HasPrefix(typ, "^"):
+		return &Schema{Type: "string", Pattern: typ}
+	}
+	return &Schema{Type: "string"}
+}
+
+func isOpenAPIMethod(m string) bool {
+	switch m {
+	case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package docgen
+
+import (
+	"testing"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestOpenAPI(t *testing.T) {
+	h := func(ctx context.Context, fctx *fasthttp.RequestCtx) {}
+
+	r := chi.NewRouter()
+	r.Route("/articles", func(r chi.Router) {
+		r.Get("/", h)
+		r.Post("/", h).Meta(MetaKey, Operation{
+			Summary:   "Create an article",
+			Tags:      []string{"articles"},
+			Responses: map[string]Response{"201": {Description: "Created"}},
+		})
+		r.Get("/:id|int", h).Name("getArticle")
+	})
+	r.Get("/files/*path", h)
+	r.Method("PROPFIND", "/dav", h)
+
+	spec := OpenAPI(r, Info{Title: "Blog", Version: "1.0"})
+	if len(spec.Paths) != 3 {
+		t.Fatalf("expecting 3 paths, got %v", spec.Paths)
+	}
+
+	op := spec.Paths["/articles/{id}"]["get"]
+	if op == nil || op.OperationID != "getArticle" || len(op.Parameters) != 1 || op.Parameters[0].Schema.Type != "integer" {
+		t.Fatalf("got %+v", op)
+	}
+	op = spec.Paths["/articles"]["post"]
+	if op == nil || op.Summary != "Create an article" || op.Responses["201"].Description != "Created" {
+		t.Fatalf("got %+v", op)
+	}
+	if op := spec.Paths["/files/{path}"]["get"]; op == nil || op.Parameters[0].Name != "path" {
+		t.Fatalf("got %+v", op)
+	}
+
+	if _, err := JSON(r, Info{Title: "Blog", Version: "1.0"}); err != nil {
+		t.Fatal(err)
+	}
+}
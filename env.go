@@ -0,0 +1,23 @@
+package chi
+
+import "os"
+
+// Environments of an application, see OSEnv.
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// OSEnv returns the environment the application runs in, ie. EnvProduction,
+// as named by the CHI_ENV environment variable, or EnvDevelopment when it's
+// unset. Middlewares like middleware.DevOnly are given the environment
+// explicitly, so it's read once at startup:
+//
+//	env := chi.OSEnv()
+//	r.Use(middleware.DevOnly(env, middleware.Logger))
+func OSEnv() string {
+	if name := os.Getenv("CHI_ENV"); name != "" {
+		return name
+	}
+	return EnvDevelopment
+}
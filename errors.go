@@ -0,0 +1,145 @@
+package chi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+
+	"github.com/valyala/fasthttp"
+
+	"golang.org/x/net/context"
+)
+
+// ErrorRenderer sets `fn` to render the errors fasthttp answers before the
+// request reaches the router, see ServerErrorHandler, and the errors
+// returned by the handlers of the Mux and of its subrouters without a
+// renderer of their own. `err` is the error raised, which may be logged
+// but shouldn't be exposed to the client. Errors are rendered as
+// {"error": "Bad Request"} by default.
+func (mx *Mux) ErrorRenderer(fn func(fctx *fasthttp.RequestCtx, status int, err error)) {
+	mx.router.errorRenderer = fn
+}
+
+// ServerErrorHandler returns the fasthttp.Server ErrorHandler rendering the
+// errors raised while reading requests, ie. bad requests or oversized
+// headers, with the ErrorRenderer of the Mux, so clients get errors in
+// the same format as the ones of the handlers:
+//
+//	s := &fasthttp.Server{
+//		Handler:      r.ServeHTTP,
+//		ErrorHandler: r.ServerErrorHandler(),
+//	}
+func (mx *Mux) ServerErrorHandler() func(fctx *fasthttp.RequestCtx, err error) {
+	return func(fctx *fasthttp.RequestCtx, err error) {
+		render := mx.current().router.errorRenderer
+		if render == nil {
+			render = renderError
+		}
+		render(fctx, serverErrorStatus(err), err)
+	}
+}
+
+// A StatusError is an error returned by a handler with the status of the
+// response, ie.
+//
+//	r.Get("/articles/:id", func(ctx context.Context, fctx *fasthttp.RequestCtx) error {
+//		article, err := dbGetArticle(chi.URLParam(ctx, "id"))
+//		if err == sql.ErrNoRows {
+//			return &chi.StatusError{Status: 404, Err: err}
+//		}
+//		...
+//	})
+//
+// Returned errors of other types get a 500 status, unless they have a
+// StatusCode() int method too.
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (e *StatusError) Error() string {
+	if e.Err == nil {
+		return fasthttp.StatusMessage(e.Status)
+	}
+	return e.Err.Error()
+}
+
+// StatusCode returns the status of the response.
+func (e *StatusError) StatusCode() int {
+	return e.Status
+}
+
+// errorHandler returns the Handler of an error returning handler, rendering
+// its errors with the ErrorRenderer of the routers serving the request.
+func errorHandler(fn func(ctx context.Context, fctx *fasthttp.RequestCtx) error) Handler {
+	return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		err := fn(ctx, fctx)
+		if err == nil {
+			return
+		}
+
+		status := fasthttp.StatusInternalServerError
+		if sc, ok := err.(interface {
+			StatusCode() int
+		}); ok {
+			status = sc.StatusCode()
+		}
+
+		if rctx := RouteContext(ctx); rctx != nil {
+			rctx.renderError(fctx, status, err)
+		} else {
+			renderError(fctx, status, err)
+		}
+	})
+}
+
+// renderError renders the error with the ErrorRenderer of the routers
+// serving the request.
+func (x *Context) renderError(fctx *fasthttp.RequestCtx, status int, err error) {
+	if x.errorRenderer != nil {
+		x.errorRenderer(fctx, status, err)
+		return
+	}
+	renderError(fctx, status, err)
+}
+
+var errMalformedPath = errors.New("chi: malformed percent-encoding in the request path")
+
+var errNotAcceptable = errors.New("chi: no route produces a media type accepted by the request")
+
+// validPathEncoding reports whether the percent signs of the path are all
+// followed by two hex digits.
+func validPathEncoding(path []byte) bool {
+	for i := bytes.IndexByte(path, '%'); i >= 0; i = bytes.IndexByte(path, '%') {
+		if i+2 >= len(path) || !isHex(path[i+1]) || !isHex(path[i+2]) {
+			return false
+		}
+		path = path[i+3:]
+	}
+	return true
+}
+
+// serverErrorStatus returns the status of an error raised by fasthttp while
+// reading a request, like its default error handler does.
+func serverErrorStatus(err error) int {
+	if _, ok := err.(*fasthttp.ErrSmallBuffer); ok {
+		return fasthttp.StatusRequestHeaderFieldsTooLarge
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return fasthttp.StatusRequestTimeout
+	}
+	if err == fasthttp.ErrBodyTooLarge {
+		return fasthttp.StatusRequestEntityTooLarge
+	}
+	return fasthttp.StatusBadRequest
+}
+
+// renderError is the default ErrorRenderer, rendering the status text as
+// a JSON error.
+func renderError(fctx *fasthttp.RequestCtx, status int, err error) {
+	b, _ := json.Marshal(map[string]string{"error": fasthttp.StatusMessage(status)})
+	fctx.Response.Header.Set("Content-Type", "application/json; charset=utf-8")
+	fctx.SetStatusCode(status)
+	fctx.SetBody(b)
+}
@@ -0,0 +1,141 @@
+package chi
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// FileServerOptions tunes the caching of the files served by FileServerWith.
+type FileServerOptions struct {
+	// Max age of the files in the Cache-Control header, which is left out
+	// when zero
+	MaxAge time.Duration
+
+	// Serve the files compressed to the clients accepting it, see
+	// fasthttp.FS.Compress
+	Compress bool
+
+	// Duration fasthttp keeps the served files open, see
+	// fasthttp.FS.CacheDuration
+	CacheDuration time.Duration
+
+	// Leave out the ETag header and If-None-Match handling
+	NoETag bool
+}
+
+// FileServerWith serves files from the root like FileServer, with caching
+// options. Files are served with a Last-Modified header and a weak ETag,
+// and the requests revalidating them with If-Modified-Since or
+// If-None-Match are answered with a 304 Not Modified, ie.
+//
+//	r.FileServerWith("/assets/*", "./public", chi.FileServerOptions{
+//		MaxAge: 24 * time.Hour,
+//	})
+func (mx *Mux) FileServerWith(path, root string, opts FileServerOptions) {
+	p := strings.LastIndex(path, "/*")
+	if p < 0 || strings.IndexByte(path[p+1:], '/') >= 0 {
+		panic("path must end with a catch-all like /*filepath in path '" + path + "'")
+	}
+	prefix := path[:p]
+
+	fs := &fasthttp.FS{
+		Root:               root,
+		IndexNames:         []string{"index.html"},
+		GenerateIndexPages: true,
+		AcceptByteRange:    true,
+		Compress:           opts.Compress,
+		CacheDuration:      opts.CacheDuration,
+	}
+	if n := strings.Count(prefix, "/"); n > 0 {
+		fs.PathRewrite = fasthttp.NewPathSlashesStripper(n)
+	}
+	fileHandler := fs.NewRequestHandler()
+
+	var cacheControl string
+	if opts.MaxAge > 0 {
+		cacheControl = fmt.Sprintf("public, max-age=%d", int(opts.MaxAge/time.Second))
+	}
+
+	tr := mx.router
+	mx.Get(path, func(fctx *fasthttp.RequestCtx) {
+		// If-None-Match takes precedence over If-Modified-Since
+		ifNoneMatch := fctx.Request.Header.Peek("If-None-Match")
+		if !opts.NoETag && len(ifNoneMatch) > 0 {
+			ifNoneMatch = append([]byte(nil), ifNoneMatch...)
+			fctx.Request.Header.Del("If-Modified-Since")
+		}
+
+		fileHandler(fctx)
+
+		switch fctx.Response.StatusCode() {
+		case fasthttp.StatusNotFound:
+			if tr.notFoundFile != "" {
+				fctx.Response.Reset()
+				serveNotFoundFile(fctx, tr.notFoundFile)
+			}
+			return
+		case fasthttp.StatusOK, fasthttp.StatusNotModified, fasthttp.StatusPartialContent:
+		default:
+			return
+		}
+
+		if cacheControl != "" {
+			fctx.Response.Header.Set("Cache-Control", cacheControl)
+		}
+		if opts.NoETag || fctx.Response.StatusCode() != fasthttp.StatusOK {
+			return
+		}
+
+		etag := fileETag(&fctx.Response)
+		if etag == "" {
+			return
+		}
+		fctx.Response.Header.Set("ETag", etag)
+		if etagMatches(ifNoneMatch, etag) {
+			lastModified := append([]byte(nil), fctx.Response.Header.Peek("Last-Modified")...)
+			fctx.NotModified()
+			fctx.Response.Header.Set("ETag", etag)
+			fctx.Response.Header.SetBytesV("Last-Modified", lastModified)
+			if cacheControl != "" {
+				fctx.Response.Header.Set("Cache-Control", cacheControl)
+			}
+		}
+	})
+}
+
+// fileETag returns a weak ETag of a served file from its modification time
+// and length, or "" without a Last-Modified header.
+func fileETag(resp *fasthttp.Response) string {
+	lastModified, err := fasthttp.ParseHTTPDate(resp.Header.Peek("Last-Modified"))
+	if err != nil {
+		return ""
+	}
+	n := resp.Header.ContentLength()
+	if n <= 0 {
+		n = len(resp.Body())
+	}
+	return fmt.Sprintf(`W/"%x-%x"`, lastModified.Unix(), n)
+}
+
+// etagMatches reports whether the If-None-Match header value lists the
+// ETag, comparing them weakly.
+func etagMatches(ifNoneMatch []byte, etag string) bool {
+	if len(ifNoneMatch) == 0 {
+		return false
+	}
+	if string(bytes.TrimSpace(ifNoneMatch)) == "*" {
+		return true
+	}
+	weak := strings.TrimPrefix(etag, "W/")
+	for _, tag := range bytes.Split(ifNoneMatch, []byte(",")) {
+		tag = bytes.TrimPrefix(bytes.TrimSpace(tag), []byte("W/"))
+		if string(tag) == weak {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package chi
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestMuxFileServerCaching(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/favicon.ico", []byte("fake ico"), 0644)
+
+	r := NewRouter()
+	r.FileServerWith("/static/*", dir, FileServerOptions{MaxAge: time.Hour})
+
+	// The files are served as body streams, read by Body, which CopyTo
+	// leaves behind
+	get := func(headers map[string]string) *fasthttp.Response {
+		fctx := &fasthttp.RequestCtx{}
+		fctx.Request.SetRequestURI("/static/favicon.ico")
+		for k, v := range headers {
+			fctx.Request.Header.Set(k, v)
+		}
+		r.ServeHTTP(fctx)
+		return &fctx.Response
+	}
+
+	resp := get(nil)
+	lastModified := string(resp.Header.Peek("Last-Modified"))
+	etag := string(resp.Header.Peek("ETag"))
+	if resp.StatusCode() != 200 || string(resp.Body()) != "fake ico" {
+		t.Fatalf("got %d '%s'", resp.StatusCode(), resp.Body())
+	}
+	if lastModified == "" || etag == "" {
+		t.Fatalf("expecting Last-Modified and ETag headers, got '%s' and '%s'", lastModified, etag)
+	}
+	if cc := string(resp.Header.Peek("Cache-Control")); cc != "public, max-age=3600" {
+		t.Fatalf("got Cache-Control '%s'", cc)
+	}
+
+	tests := []struct {
+		headers map[string]string
+		status  int
+	}{
+		{map[string]string{"If-Modified-Since": lastModified}, 304},
+		{map[string]string{"If-None-Match": etag}, 304},
+		{map[string]string{"If-None-Match": `"other", ` + etag}, 304},
+		{map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified}, 200},
+	}
+	for _, tt := range tests {
+		resp := get(tt.headers)
+		if resp.StatusCode() != tt.status {
+			t.Fatalf("%v: got %d", tt.headers, resp.StatusCode())
+		}
+		if tt.status == 304 && (len(resp.Body()) != 0 || string(resp.Header.Peek("Cache-Control")) == "") {
+			t.Fatalf("%v: expecting an empty 304 with Cache-Control, got '%s'", tt.headers, resp.Body())
+		}
+	}
+}
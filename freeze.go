@@ -0,0 +1,18 @@
+package chi
+
+// Freeze seals the routes of the Mux and of its subrouters like a Builder
+// does, adding or removing routes panicking from then on, so the trees are
+// read without locking. Call it once the routes are defined, before
+// serving. Muxes built by a Builder are frozen already.
+//
+// Freeze doesn't index the routes any further: the routes of static
+// patterns, ie. `/health`, are looked up by path in the index of each tree
+// whether frozen or not.
+func (mx *Mux) Freeze() {
+	mx.buildHandler()
+	mx.seal()
+	if live := mx.current(); live != mx {
+		// Routes swapped in by Reload
+		live.seal()
+	}
+}
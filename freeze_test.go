@@ -0,0 +1,90 @@
+package chi
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestMuxFreeze(t *testing.T) {
+	reply := func(s string) HandlerFunc {
+		return func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.WriteString(s)
+		}
+	}
+
+	sr := NewRouter()
+	sr.Get("/search", reply("search"))
+	sr.Get("/:id", reply("user"))
+
+	r := NewRouter()
+	r.Get("/health", reply("health"))
+	r.Get("/:page", reply("page"))
+	r.Head("/:page", reply("head page"))
+	r.Mount("/users", sr)
+	r.Freeze()
+
+	if !r.router.sealed || !sr.router.sealed {
+		t.Fatal("expecting the routers to be sealed")
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"GET", "/health", 200, "health"},
+		{"GET", "/about", 200, "page"},
+		{"HEAD", "/health", 200, "head page"},
+		{"POST", "/health", 405, "Method Not Allowed"},
+		{"GET", "/users/search", 200, "search"},
+		{"GET", "/users/1", 200, "user"},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.Header.SetMethod(tt.method)
+		fctx.Request.SetRequestURI(tt.path)
+		r.ServeHTTP(&fctx)
+		if fctx.Response.StatusCode() != tt.status || string(fctx.Response.Body()) != tt.body {
+			t.Fatalf("%s %s: got %d '%s'", tt.method, tt.path, fctx.Response.StatusCode(), fctx.Response.Body())
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expecting a route added once frozen to panic")
+			}
+		}()
+		sr.Get("/new", reply("new"))
+	}()
+}
+
+func BenchmarkMuxStatic(b *testing.B) {
+	h := func(ctx context.Context, fctx *fasthttp.RequestCtx) {}
+
+	for _, frozen := range []bool{false, true} {
+		r := NewRouter()
+		for i := 0; i < 800; i++ {
+			r.Get(fmt.Sprintf("/api/v1/resource%d/list", i), h)
+		}
+		r.Get("/api/v1/:resource/:id", h)
+		if frozen {
+			r.Freeze()
+		}
+
+		b.Run(fmt.Sprintf("frozen=%v", frozen), func(b *testing.B) {
+			var fctx fasthttp.RequestCtx
+			fctx.Request.SetRequestURI("/api/v1/resource400/list")
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				r.ServeHTTP(&fctx)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package chi
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// PeekAll returns the values of every request header line named `key`,
+// matched case-insensitively. Unlike fasthttp's RequestHeader.Peek, which
+// only returns the first occurrence, repeated header lines are all kept.
+// The returned slices point into the request buffers and are only valid
+// until the handler returns.
+func PeekAll(fctx *fasthttp.RequestCtx, key string) [][]byte {
+	return appendPeekAll(nil, &fctx.Request.Header, key)
+}
+
+func appendPeekAll(dst [][]byte, h *fasthttp.RequestHeader, key string) [][]byte {
+	k := []byte(key)
+	h.VisitAll(func(hk, hv []byte) {
+		if bytes.EqualFold(hk, k) {
+			dst = append(dst, hv)
+		}
+	})
+	return dst
+}
+
+// HeaderList returns the comma-separated elements of the request header
+// `key` across all of its header lines, with surrounding whitespace and
+// empty elements removed, ie. "gzip, deflate" => ["gzip" "deflate"].
+func HeaderList(fctx *fasthttp.RequestCtx, key string) [][]byte {
+	var list [][]byte
+	for _, v := range PeekAll(fctx, key) {
+		list = appendList(list, v)
+	}
+	return list
+}
+
+// appendList splits a comma-separated header value onto dst.
+func appendList(dst [][]byte, v []byte) [][]byte {
+	for len(v) > 0 {
+		var elem []byte
+		if i := bytes.IndexByte(v, ','); i >= 0 {
+			elem, v = v[:i], v[i+1:]
+		} else {
+			elem, v = v, nil
+		}
+		if elem = bytes.TrimSpace(elem); len(elem) > 0 {
+			dst = append(dst, elem)
+		}
+	}
+	return dst
+}
+
+// A QValue is a single element of a weighted header list such as Accept,
+// Accept-Encoding or Accept-Language.
+type QValue struct {
+	// Value of the element without its parameters, ie. "text/html"
+	Value string
+
+	// Quality factor in the range 0..1, defaults to 1
+	Q float64
+}
+
+// QValues parses the weighted list of the request header `key` and returns
+// its elements ordered by descending quality. Elements with equal quality
+// keep the order in which the client sent them. Elements with q=0 are
+// explicitly refused by the client and are left out.
+func QValues(fctx *fasthttp.RequestCtx, key string) []QValue {
+	list := HeaderList(fctx, key)
+	if len(list) == 0 {
+		return nil
+	}
+
+	qvs := make([]QValue, 0, len(list))
+	for _, elem := range list {
+		qv := parseQValue(elem)
+		if qv.Q <= 0 {
+			continue
+		}
+		qvs = append(qvs, qv)
+	}
+	sort.Stable(qValues(qvs))
+	return qvs
+}
+
+// parseQValue parses a single list element like "text/html;level=1;q=0.8".
+func parseQValue(elem []byte) QValue {
+	qv := QValue{Q: 1}
+
+	params := elem
+	if i := bytes.IndexByte(elem, ';'); i >= 0 {
+		elem, params = elem[:i], elem[i+1:]
+	} else {
+		params = nil
+	}
+	qv.Value = string(bytes.TrimSpace(elem))
+
+	for len(params) > 0 {
+		var p []byte
+		if i := bytes.IndexByte(params, ';'); i >= 0 {
+			p, params = params[:i], params[i+1:]
+		} else {
+			p, params = params, nil
+		}
+		p = bytes.TrimSpace(p)
+		if len(p) < 2 || (p[0] != 'q' && p[0] != 'Q') || p[1] != '=' {
+			continue
+		}
+		q, err := strconv.ParseFloat(string(bytes.TrimSpace(p[2:])), 64)
+		if err != nil || q < 0 {
+			q = 0
+		} else if q > 1 {
+			q = 1
+		}
+		qv.Q = q
+	}
+	return qv
+}
+
+type qValues []QValue
+
+// Sort the list of values by descending quality
+func (q qValues) Len() int           { return len(q) }
+func (q qValues) Less(i, j int) bool { return q[i].Q > q[j].Q }
+func (q qValues) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
@@ -0,0 +1,47 @@
+package chi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestHeaderList(t *testing.T) {
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.Add("Accept-Encoding", "gzip, deflate")
+	fctx.Request.Header.Add("accept-encoding", " br ,, identity")
+
+	if n := len(PeekAll(&fctx, "ACCEPT-ENCODING")); n != 2 {
+		t.Fatalf("expecting 2 header lines, got %d", n)
+	}
+
+	var got []string
+	for _, v := range HeaderList(&fctx, "Accept-Encoding") {
+		got = append(got, string(v))
+	}
+	expected := []string{"gzip", "deflate", "br", "identity"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expecting %v, got %v", expected, got)
+	}
+
+	if list := HeaderList(&fctx, "X-Missing"); len(list) != 0 {
+		t.Fatalf("expecting empty list, got %v", list)
+	}
+}
+
+func TestQValues(t *testing.T) {
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.Set("Accept", "text/html;level=1;q=0.5, application/json, text/plain;q=0, text/xml; q=0.8, */*;q=0.5")
+
+	got := QValues(&fctx, "Accept")
+	expected := []QValue{
+		{Value: "application/json", Q: 1},
+		{Value: "text/xml", Q: 0.8},
+		{Value: "text/html", Q: 0.5},
+		{Value: "*/*", Q: 0.5},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expecting %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,137 @@
+package chi
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// hooks are the lifecycle callbacks of a Mux, shared with its Groups.
+type hooks struct {
+	routeRegistered []func(method, pattern string)
+	requestStart    []func(ctx context.Context, fctx *fasthttp.RequestCtx)
+	requestEnd      []func(ctx context.Context, fctx *fasthttp.RequestCtx, status int, duration time.Duration)
+	notFound        []func(ctx context.Context, fctx *fasthttp.RequestCtx)
+	panicked        []func(ctx context.Context, fctx *fasthttp.RequestCtx, err interface{})
+	errored         []func(ctx context.Context, fctx *fasthttp.RequestCtx, err error)
+}
+
+// serving reports whether any request hook is registered.
+func (h *hooks) serving() bool {
+	return len(h.requestStart) > 0 || len(h.requestEnd) > 0 ||
+		len(h.notFound) > 0 || len(h.panicked) > 0 || len(h.errored) > 0
+}
+
+// OnRouteRegistered registers `fn` to be called for every route added to
+// the Mux and its Groups, with the method "*" for routes of all methods.
+// Mounted subrouters are reported by their mount patterns.
+func (mx *Mux) OnRouteRegistered(fn func(method, pattern string)) {
+	mx.router.hooks.routeRegistered = append(mx.router.hooks.routeRegistered, fn)
+}
+
+// OnRequestStart registers `fn` to be called when the Mux starts serving a
+// request, before its middleware stack.
+//
+// Request hooks are called in a fixed order: OnRequestStart, the handler,
+// OnNotFound if no route matched, OnError for the errors reported by the
+// handler, OnPanic if the handler panicked, and OnRequestEnd. Requests of
+// mounted subrouters are reported too.
+func (mx *Mux) OnRequestStart(fn func(ctx context.Context, fctx *fasthttp.RequestCtx)) {
+	mx.router.hooks.requestStart = append(mx.router.hooks.requestStart, fn)
+}
+
+// OnRequestEnd registers `fn` to be called with the response status and the
+// time spent serving the request, once the Mux is done with it.
+func (mx *Mux) OnRequestEnd(fn func(ctx context.Context, fctx *fasthttp.RequestCtx, status int, duration time.Duration)) {
+	mx.router.hooks.requestEnd = append(mx.router.hooks.requestEnd, fn)
+}
+
+// OnNotFound registers `fn` to be called after a request was answered by
+// a NotFound handler of the Mux or of its subrouters.
+func (mx *Mux) OnNotFound(fn func(ctx context.Context, fctx *fasthttp.RequestCtx)) {
+	mx.router.hooks.notFound = append(mx.router.hooks.notFound, fn)
+}
+
+// OnPanic registers `fn` to be called with the value of a panic raised while
+// serving a request. The panic isn't recovered: it goes on once the hooks
+// ran, and panics recovered by a middleware, ie. middleware.Recoverer, are
+// never seen by the hook.
+func (mx *Mux) OnPanic(fn func(ctx context.Context, fctx *fasthttp.RequestCtx, err interface{})) {
+	mx.router.hooks.panicked = append(mx.router.hooks.panicked, fn)
+}
+
+// OnError registers `fn` to be called with the errors reported with
+// ReportError while serving a request, ie. by the render package when a
+// response can't be marshalled.
+func (mx *Mux) OnError(fn func(ctx context.Context, fctx *fasthttp.RequestCtx, err error)) {
+	mx.router.hooks.errored = append(mx.router.hooks.errored, fn)
+}
+
+// ReportError reports an error of the request to the OnError hooks of the
+// routers serving it, once the handler returned. Errors rendered to the
+// client with a generic message are reported with their details this way.
+func ReportError(ctx context.Context, err error) {
+	if rctx := RouteContext(ctx); rctx != nil {
+		rctx.mu.Lock()
+		rctx.errs = append(rctx.errs, err)
+		rctx.mu.Unlock()
+	}
+}
+
+// serveHooked serves the request through the Mux handler, calling the
+// request hooks in their documented order.
+func (mx *Mux) serveHooked(ctx context.Context, fctx *fasthttp.RequestCtx) {
+	h := &mx.router.hooks
+	start := time.Now()
+
+	for _, fn := range h.requestStart {
+		fn(ctx, fctx)
+	}
+
+	defer func() {
+		err := recover()
+
+		if rctx := RouteContext(ctx); rctx != nil {
+			if rctx.notFound {
+				for _, fn := range h.notFound {
+					fn(ctx, fctx)
+				}
+			}
+			rctx.mu.Lock()
+			errs := rctx.errs
+			rctx.mu.Unlock()
+			for _, e := range errs {
+				for _, fn := range h.errored {
+					fn(ctx, fctx, e)
+				}
+			}
+		}
+		if err != nil {
+			for _, fn := range h.panicked {
+				fn(ctx, fctx, err)
+			}
+		}
+		status := fctx.Response.StatusCode()
+		for _, fn := range h.requestEnd {
+			fn(ctx, fctx, status, time.Since(start))
+		}
+
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	mx.handler.ServeHTTPC(ctx, fctx)
+}
+
+// routeAdded calls the route hooks for a new route.
+func (h *hooks) routeAdded(method methodTyp, pattern string) {
+	if len(h.routeRegistered) == 0 {
+		return
+	}
+	name := methodName(method)
+	for _, fn := range h.routeRegistered {
+		fn(name, pattern)
+	}
+}
@@ -0,0 +1,130 @@
+package chi
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// A hostRoute routes the requests of the hosts matching its pattern with
+// the routes of a Mux.
+type hostRoute struct {
+	pattern string
+
+	// Pattern labels, ie. ["*", "cdn", "example", "com"]
+	labels []string
+
+	mux *Mux
+}
+
+// Host returns the router of the requests whose Host header matches the
+// `pattern`, ie. `cdn.example.com`. A `:name` label matches any label, and
+// a leading `*` label matches one or more labels, whose values are set as
+// the URL params `name` and `subdomain`:
+//
+//	r.Host("*.cdn.example.com").Get("/assets/*", serveAsset)
+//	r.Host(":tenant.example.com").Get("/", tenantIndex)
+//
+// Hosts are matched in the order they were added, ignoring the case and
+// the port, before the routes of the Mux. Their routers get the NotFound
+// and MethodNotAllowed handlers of the Mux, and serve the requests of
+// their hosts even if none of their routes match.
+func (mx *Mux) Host(pattern string) Router {
+	labels := strings.Split(pattern, ".")
+	for i, l := range labels {
+		if l == "" || l == ":" || (strings.Contains(l, "*") && (l != "*" || i > 0)) {
+			panic(fmt.Sprintf("chi: invalid host pattern '%s'", pattern))
+		}
+		if l[0] != ':' {
+			labels[i] = strings.ToLower(l)
+		}
+	}
+
+	tr := mx.router
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.checkSealed()
+
+	for _, h := range tr.hosts {
+		if h.pattern == pattern {
+			return h.mux
+		}
+	}
+
+	sr := NewRouter()
+	sr.disabled = mx.disabled
+	sr.router.syntax = tr.syntax
+	sr.router.maxParams = tr.maxParams
+	sr.router.unknownMethods = tr.unknownMethods
+	sr.router.notFoundHandler = tr.notFoundHandler
+	sr.router.notFoundMethods = tr.notFoundMethods
+	sr.router.notFoundFile = tr.notFoundFile
+	sr.router.rawParams = tr.rawParams
+	sr.router.methodNotAllowedHandler = tr.methodNotAllowedHandler
+	tr.hosts = append(tr.hosts, &hostRoute{pattern: pattern, labels: labels, mux: sr})
+	return sr
+}
+
+// matchHost returns the router of the request's host, adding the host
+// params to the routing context, or nil.
+func (tr *treeRouter) matchHost(rctx *Context, fctx *fasthttp.RequestCtx) *Mux {
+	tr.mu.RLock()
+	hosts := tr.hosts
+	tr.mu.RUnlock()
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	host := fctx.Host()
+	if i := bytes.LastIndexByte(host, ':'); i >= 0 && bytes.IndexByte(host[i:], ']') < 0 {
+		host = host[:i]
+	}
+	labels := strings.Split(strings.ToLower(string(host)), ".")
+
+	for _, h := range hosts {
+		h.mux.router.mu.RLock()
+		routed := h.mux.handler != nil
+		h.mux.router.mu.RUnlock()
+		if !routed {
+			// No routes yet
+			continue
+		}
+		if params, ok := h.match(labels); ok {
+			for _, p := range params {
+				rctx.Params.Add(p.Key, p.Value)
+			}
+			return h.mux
+		}
+	}
+	return nil
+}
+
+// match returns the params of the host labels if they match the pattern.
+func (h *hostRoute) match(labels []string) ([]param, bool) {
+	var params []param
+	pl := h.labels
+
+	if pl[0] == "*" {
+		n := len(labels) - len(pl) + 1
+		if n < 1 {
+			return nil, false
+		}
+		params = append(params, param{"subdomain", strings.Join(labels[:n], ".")})
+		labels, pl = labels[n:], pl[1:]
+	}
+	if len(labels) != len(pl) {
+		return nil, false
+	}
+
+	for i, l := range pl {
+		switch {
+		case l[0] == ':':
+			params = append(params, param{l[1:], labels[i]})
+		case l != labels[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
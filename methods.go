@@ -0,0 +1,62 @@
+package chi
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// An UnknownMethodPolicy is the handling of the requests whose method is
+// neither a standard one nor a custom one routed with Method. The routes of
+// Any serve them whatever the policy.
+type UnknownMethodPolicy int
+
+const (
+	// UnknownMethodNotAllowed answers a 405 with the methods of the path in
+	// the Allow header, or the NotFound handler if it has no routes, which
+	// is the default.
+	UnknownMethodNotAllowed UnknownMethodPolicy = iota
+
+	// UnknownMethodNotImplemented answers a 501, the server not supporting
+	// the method, whatever the path.
+	UnknownMethodNotImplemented
+
+	// UnknownMethodDispatch serves the requests with the routes of all
+	// methods of their path, the ones of Handle, Any and Mount, answering a
+	// 405 if it only has routes of given methods.
+	UnknownMethodDispatch
+)
+
+// UnknownMethods sets how the router answers the requests of unknown
+// methods, ie. "BREW", see UnknownMethodPolicy. As these requests are
+// answered before reaching the subrouters, it's set on the root router,
+// and inherited by its Host routers.
+func (mx *Mux) UnknownMethods(policy UnknownMethodPolicy) {
+	mx.router.unknownMethods = policy
+}
+
+var errUnknownMethod = errors.New("chi: unknown http method")
+
+// rejectUnknownMethod answers the request of an unknown method according to
+// the policy of the router.
+func (tr *treeRouter) rejectUnknownMethod(ctx context.Context, rctx *Context, fctx *fasthttp.RequestCtx, path []byte) {
+	if tr.unknownMethods == UnknownMethodNotImplemented {
+		rctx.tracef("unknown method, not implemented")
+		rctx.renderError(fctx, fasthttp.StatusNotImplemented, errUnknownMethod)
+		return
+	}
+
+	if methods := tr.allowedMethods(rctx, path); len(methods) > 0 {
+		allow := strings.Join(methods, ", ")
+		if rctx.tracing {
+			rctx.tracef("unknown method, allow %s", allow)
+		}
+		fctx.Response.Header.Set("Allow", allow)
+		tr.MethodNotAllowedHandlerFn().ServeHTTPC(ctx, fctx)
+		return
+	}
+	rctx.tracef("unknown method, not found")
+	tr.NotFoundHandlerFn().ServeHTTPC(ctx, fctx)
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"encoding/json"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// An AllocProfiler samples the heap allocations made while serving the
+// requests of each route, to find the handlers undermining the zero
+// allocation design of fasthttp. It's a debugging aid: reading the memory
+// statistics of the runtime stops the world, and the allocations of
+// concurrent requests are counted too, so profile under light load, ie.
+// replaying requests one at a time in staging:
+//
+//	allocs := middleware.NewAllocProfiler(10)
+//	r.Use(allocs.Handler)
+//	r.Get("/debug/allocs", allocs.Report)
+type AllocProfiler struct {
+	rate  uint64
+	count uint64
+
+	mu     sync.Mutex
+	routes map[string]*AllocStats
+}
+
+// AllocStats are the allocations sampled for a route.
+type AllocStats struct {
+	Route string `json:"route"`
+
+	// Number of requests sampled
+	Samples int `json:"samples"`
+
+	// Heap allocations and bytes allocated, in total and at most per request
+	Allocs    uint64 `json:"allocs"`
+	Bytes     uint64 `json:"bytes"`
+	MaxAllocs uint64 `json:"maxAllocs"`
+	MaxBytes  uint64 `json:"maxBytes"`
+}
+
+// AvgAllocs returns the average number of allocations per request.
+func (s *AllocStats) AvgAllocs() float64 {
+	if s.Samples == 0 {
+		return 0
+	}
+	return float64(s.Allocs) / float64(s.Samples)
+}
+
+// NewAllocProfiler returns an AllocProfiler sampling one request in `rate`,
+// all of them for a rate of 1.
+func NewAllocProfiler(rate int) *AllocProfiler {
+	if rate < 1 {
+		panic("middleware.NewAllocProfiler expects rate > 0")
+	}
+	return &AllocProfiler{rate: uint64(rate), routes: make(map[string]*AllocStats)}
+}
+
+// Handler is the profiler's middleware, recording the allocations of the
+// sampled requests under their method and route pattern.
+func (p *AllocProfiler) Handler(next chi.Handler) chi.Handler {
+	fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		if atomic.AddUint64(&p.count, 1)%p.rate != 0 {
+			next.ServeHTTPC(ctx, fctx)
+			return
+		}
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		next.ServeHTTPC(ctx, fctx)
+		runtime.ReadMemStats(&after)
+
+		route := chi.RoutePattern(ctx)
+		if route == "" {
+			route = "(unmatched)"
+		}
+		p.record(string(fctx.Method())+" "+route, after.Mallocs-before.Mallocs, after.TotalAlloc-before.TotalAlloc)
+	}
+	return chi.HandlerFunc(fn)
+}
+
+func (p *AllocProfiler) record(route string, allocs, bytes uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.routes[route]
+	if s == nil {
+		s = &AllocStats{Route: route}
+		p.routes[route] = s
+	}
+	s.Samples++
+	s.Allocs += allocs
+	s.Bytes += bytes
+	if allocs > s.MaxAllocs {
+		s.MaxAllocs = allocs
+	}
+	if bytes > s.MaxBytes {
+		s.MaxBytes = bytes
+	}
+}
+
+// Stats returns the allocations sampled for each route, the routes with the
+// most allocations per request first.
+func (p *AllocProfiler) Stats() []AllocStats {
+	p.mu.Lock()
+	stats := make([]AllocStats, 0, len(p.routes))
+	for _, s := range p.routes {
+		stats = append(stats, *s)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if ai, aj := stats[i].AvgAllocs(), stats[j].AvgAllocs(); ai != aj {
+			return ai > aj
+		}
+		return stats[i].Route < stats[j].Route
+	})
+	return stats
+}
+
+// Reset drops the allocations sampled so far.
+func (p *AllocProfiler) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.routes = make(map[string]*AllocStats)
+}
+
+// Report is a handler serving the Stats as JSON, for an ops endpoint.
+func (p *AllocProfiler) Report(ctx context.Context, fctx *fasthttp.RequestCtx) {
+	b, err := json.MarshalIndent(p.Stats(), "", "  ")
+	if err != nil {
+		fctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+		return
+	}
+	fctx.SetContentType("application/json; charset=utf-8")
+	fctx.Write(b)
+}
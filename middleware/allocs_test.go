@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+var sink [][]byte
+
+func TestAllocProfiler(t *testing.T) {
+	allocs := NewAllocProfiler(1)
+
+	r := chi.NewRouter()
+	r.Use(allocs.Handler)
+	r.Get("/lean", func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	r.Get("/greedy/:n", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		for i := 0; i < 100; i++ {
+			sink = append(sink, make([]byte, 1024))
+		}
+	})
+	r.Get("/debug/allocs", allocs.Report)
+
+	for _, path := range []string{"/lean", "/greedy/1", "/greedy/2"} {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(path)
+		r.ServeHTTP(&fctx)
+	}
+	sink = nil
+
+	stats := allocs.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("expecting 2 routes, got %+v", stats)
+	}
+	if s := stats[0]; s.Route != "GET /greedy/:n" || s.Samples != 2 || s.MaxAllocs < 100 || s.MaxBytes < 100*1024 {
+		t.Fatalf("got %+v", s)
+	}
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/debug/allocs")
+	r.ServeHTTP(&fctx)
+	var report []AllocStats
+	if err := json.Unmarshal(fctx.Response.Body(), &report); err != nil || len(report) != 2 {
+		t.Fatalf("got '%s' (%v)", fctx.Response.Body(), err)
+	}
+}
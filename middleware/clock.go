@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Clock is the time source of the middlewares waiting on timers, ie. Timeout
+// and Throttle, and of the expiry of render.Cache. Tests set a ManualClock to advance time deterministically,
+// rather than sleeping, and embedded environments their own monotonic
+// source.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer of a Clock, like time.Timer.
+type Timer interface {
+	// C returns the channel receiving the time once the timer fires.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing, returning false if it already
+	// fired or was stopped.
+	Stop() bool
+}
+
+// DefaultClock is the Clock of the middlewares, the system clock by default.
+// It's read when the middlewares are created, so set it before, ie. at the
+// start of a test:
+//
+//	clock := middleware.NewManualClock(time.Now())
+//	middleware.DefaultClock = clock
+//	defer func() { middleware.DefaultClock = middleware.SystemClock }()
+var DefaultClock Clock = SystemClock
+
+// SystemClock is the Clock of the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	t *time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t systemTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// ManualClock is a Clock whose time only moves with Advance.
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+// NewManualClock returns a ManualClock set to `now`.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the time of the clock.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a Timer firing once the clock advanced by `d`.
+func (c *ManualClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &manualTimer{clock: c, at: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by `d`, firing the timers due by then.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = pending
+}
+
+// Waiting returns the number of timers neither fired nor stopped, for tests
+// to wait until the requests under test set theirs before advancing the
+// clock.
+func (c *ManualClock) Waiting() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+type manualTimer struct {
+	clock *ManualClock
+	at    time.Time
+	c     chan time.Time
+}
+
+func (t *manualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, ct := range c.timers {
+		if ct == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// withClockTimeout is context.WithTimeout on the time of `clock`.
+func withClockTimeout(parent context.Context, clock Clock, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if clock == SystemClock {
+		return context.WithTimeout(parent, timeout)
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	cctx := &clockCtx{Context: ctx, deadline: clock.Now().Add(timeout)}
+	timer := clock.NewTimer(timeout)
+	go func() {
+		select {
+		case <-timer.C():
+			cctx.mu.Lock()
+			cctx.expired = true
+			cctx.mu.Unlock()
+			cancel()
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}()
+	return cctx, cancel
+}
+
+// clockCtx is a context cancelled once the deadline passed on its Clock.
+type clockCtx struct {
+	context.Context
+	deadline time.Time
+
+	mu      sync.Mutex
+	expired bool
+}
+
+func (c *clockCtx) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *clockCtx) Err() error {
+	c.mu.Lock()
+	expired := c.expired
+	c.mu.Unlock()
+	if expired {
+		return context.DeadlineExceeded
+	}
+	return c.Context.Err()
+}
@@ -1,3 +1,14 @@
 package middleware
 
-// TODO
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// Compress is a middleware compressing the responses of the clients
+// accepting it with gzip or deflate, at the compression `level`, ie.
+// fasthttp.CompressBestSpeed, with fasthttp.CompressHandlerLevel.
+func Compress(level int) func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return fasthttp.CompressHandlerLevel(next, level)
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// CORSOptions are the cross-origin requests allowed by the CORS middleware.
+type CORSOptions struct {
+	// Origins allowed to make requests, "*" allowing any origin
+	AllowedOrigins []string `toml:"allowed_origins" ini:"allowed_origins"`
+
+	// Methods allowed in preflight requests, defaults to GET, POST and HEAD
+	AllowedMethods []string `toml:"allowed_methods" ini:"allowed_methods"`
+
+	// Headers allowed in preflight requests
+	AllowedHeaders []string `toml:"allowed_headers" ini:"allowed_headers"`
+
+	// Allow requests with credentials, ie. cookies
+	AllowCredentials bool `toml:"allow_credentials" ini:"allow_credentials"`
+
+	// Seconds clients cache the results of a preflight request, 0 leaving
+	// it to the client
+	MaxAge int `toml:"max_age" ini:"max_age"`
+}
+
+// CORS is a middleware setting the Access-Control headers of the requests
+// from allowed origins, and answering their preflight requests with a 204.
+// Requests from other origins are served without the headers, so browsers
+// block their responses.
+func CORS(opts CORSOptions) func(chi.Handler) chi.Handler {
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{"GET", "POST", "HEAD"}
+	}
+	allowMethods := strings.ToUpper(strings.Join(methods, ", "))
+	allowHeaders := strings.Join(opts.AllowedHeaders, ", ")
+
+	return func(next chi.Handler) chi.Handler {
+		fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			origin := string(fctx.Request.Header.Peek("Origin"))
+			if origin == "" || !opts.allowsOrigin(origin) {
+				next.ServeHTTPC(ctx, fctx)
+				return
+			}
+
+			h := &fctx.Response.Header
+			h.Add("Vary", "Origin")
+			h.Set("Access-Control-Allow-Origin", origin)
+			if opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if string(fctx.Method()) != "OPTIONS" || len(fctx.Request.Header.Peek("Access-Control-Request-Method")) == 0 {
+				next.ServeHTTPC(ctx, fctx)
+				return
+			}
+
+			// Preflight request
+			h.Set("Access-Control-Allow-Methods", allowMethods)
+			if allowHeaders != "" {
+				h.Set("Access-Control-Allow-Headers", allowHeaders)
+			}
+			if opts.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(opts.MaxAge))
+			}
+			fctx.SetStatusCode(fasthttp.StatusNoContent)
+		}
+		return chi.HandlerFunc(fn)
+	}
+}
+
+// allowsOrigin reports whether requests from the origin are allowed.
+func (opts *CORSOptions) allowsOrigin(origin string) bool {
+	for _, o := range opts.AllowedOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/hmgle/chi"
+	"golang.org/x/net/context"
+)
+
+// DeadlineRemaining is a middleware reporting to clients how much of the
+// request context deadline was left once the handler returned, in
+// milliseconds, in the X-Deadline-Remaining response header. Clients can
+// tune their own timeouts against it, ie. for slow endpoints behind a
+// Timeout middleware:
+//
+//	r.Get("/slow", middleware.Timeout(5*time.Second), middleware.DeadlineRemaining, slow)
+//
+// It must come after the middleware setting the deadline. No header is set
+// for requests without a deadline, and a missed deadline is reported as 0.
+func DeadlineRemaining(next chi.Handler) chi.Handler {
+	fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		next.ServeHTTPC(ctx, fctx)
+
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return
+		}
+		remaining := deadline.Sub(DefaultClock.Now()) / time.Millisecond
+		if remaining < 0 {
+			remaining = 0
+		}
+		fctx.Response.Header.Set("X-Deadline-Remaining", strconv.FormatInt(int64(remaining), 10))
+	}
+	return chi.HandlerFunc(fn)
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestDeadlineRemaining(t *testing.T) {
+	h := Timeout(time.Second)(DeadlineRemaining(chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		time.Sleep(10 * time.Millisecond)
+	})))
+
+	var fctx fasthttp.RequestCtx
+	h.ServeHTTPC(context.Background(), &fctx)
+
+	ms, err := strconv.Atoi(string(fctx.Response.Header.Peek("X-Deadline-Remaining")))
+	if err != nil || ms <= 0 || ms > 990 {
+		t.Fatalf("expecting the remaining milliseconds, got '%s'", fctx.Response.Header.Peek("X-Deadline-Remaining"))
+	}
+
+	// No deadline, no header
+	var nctx fasthttp.RequestCtx
+	DeadlineRemaining(chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})).ServeHTTPC(context.Background(), &nctx)
+	if nctx.Response.Header.Peek("X-Deadline-Remaining") != nil {
+		t.Fatalf("expecting no header without a deadline")
+	}
+}
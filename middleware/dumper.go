@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"log"
+	"strings"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// DumpOptions configures the requests dumped by the Dumper middleware and
+// how they're written out.
+type DumpOptions struct {
+	// Environment whose requests are all dumped, defaults to the
+	// development environment
+	Env string
+
+	// Environment the application runs in, defaults to chi.OSEnv()
+	Current string
+
+	// Requests carrying the SecretHeader set to Secret are dumped in any
+	// environment, ie. to reproduce a bug in production. The secret header
+	// itself is left out of the dumps
+	SecretHeader string
+	Secret       string
+
+	// Headers whose values are replaced by "[REDACTED]", on top of the
+	// Authorization, Cookie and Proxy-Authorization headers
+	Redact []string
+
+	// Body bytes dumped at most, defaults to 4KB, -1 leaves the body out
+	MaxBody int
+
+	// Called with the curl command of every dumped request, defaults to
+	// logging it
+	Output func(ctx context.Context, fctx *fasthttp.RequestCtx, curl string)
+}
+
+// Headers redacted from all dumps
+var dumpRedacted = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+const dumpMaxBody = 4 << 10
+
+// Dumper returns a debugging middleware writing out the requests as curl
+// commands replaying them, with their method, URL, headers and body, ie.
+//
+//	r.Use(middleware.Dumper(middleware.DumpOptions{
+//		SecretHeader: "X-Debug-Dump",
+//		Secret:       os.Getenv("DEBUG_DUMP_SECRET"),
+//	}))
+//
+// Requests are dumped before being served, so a request crashing the
+// handler is dumped too.
+func Dumper(opts DumpOptions) func(chi.Handler) chi.Handler {
+	if opts.Env == "" {
+		opts.Env = chi.EnvDevelopment
+	}
+	if opts.Current == "" {
+		opts.Current = chi.OSEnv()
+	}
+	if opts.MaxBody == 0 {
+		opts.MaxBody = dumpMaxBody
+	}
+	redact := make(map[string]bool)
+	for _, k := range append(dumpRedacted, opts.Redact...) {
+		redact[strings.ToLower(k)] = true
+	}
+
+	return func(next chi.Handler) chi.Handler {
+		fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			if opts.dumped(fctx) {
+				curl := curlCommand(fctx, redact, opts.SecretHeader, opts.MaxBody)
+				if opts.Output != nil {
+					opts.Output(ctx, fctx, curl)
+				} else {
+					log.Printf("dump: %s", curl)
+				}
+			}
+			next.ServeHTTPC(ctx, fctx)
+		}
+		return chi.HandlerFunc(fn)
+	}
+}
+
+// dumped reports whether the request is to be dumped.
+func (opts *DumpOptions) dumped(fctx *fasthttp.RequestCtx) bool {
+	if opts.Current == opts.Env {
+		return true
+	}
+	if opts.SecretHeader == "" || opts.Secret == "" {
+		return false
+	}
+	v := fctx.Request.Header.Peek(opts.SecretHeader)
+	return subtle.ConstantTimeCompare(v, []byte(opts.Secret)) == 1
+}
+
+// curlCommand returns the curl command replaying the request.
+func curlCommand(fctx *fasthttp.RequestCtx, redact map[string]bool, skip string, maxBody int) string {
+	var buf bytes.Buffer
+	buf.WriteString("curl")
+	if m := string(fctx.Method()); m != "GET" {
+		buf.WriteString(" -X " + m)
+	}
+	buf.WriteString(" " + shellQuote(fctx.URI().String()))
+
+	fctx.Request.Header.VisitAll(func(key, value []byte) {
+		k := string(key)
+		switch {
+		case strings.EqualFold(k, skip), strings.EqualFold(k, "Content-Length"):
+			return
+		case redact[strings.ToLower(k)]:
+			value = []byte("[REDACTED]")
+		}
+		buf.WriteString(" -H " + shellQuote(k+": "+string(value)))
+	})
+
+	if body := fctx.Request.Body(); len(body) > 0 && maxBody >= 0 {
+		truncated := len(body) > maxBody
+		if truncated {
+			body = body[:maxBody]
+		}
+		buf.WriteString(" --data-binary " + shellQuote(string(body)))
+		if truncated {
+			buf.WriteString(" # body truncated")
+		}
+	}
+	return buf.String()
+}
+
+// shellQuote quotes `s` as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
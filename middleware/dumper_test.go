@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestDumper(t *testing.T) {
+	var dumps []string
+	dumper := Dumper(DumpOptions{
+		Current:      chi.EnvProduction,
+		SecretHeader: "X-Debug-Dump",
+		Secret:       "s3cr3t",
+		MaxBody:      8,
+		Output: func(ctx context.Context, fctx *fasthttp.RequestCtx, curl string) {
+			dumps = append(dumps, curl)
+		},
+	})
+	h := dumper(chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {}))
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.SetMethod("POST")
+	fctx.Request.Header.SetHost("example.com")
+	fctx.Request.SetRequestURI("/notes?tag=it's")
+	fctx.Request.Header.Set("Authorization", "Bearer token")
+	fctx.Request.SetBodyString("hello world")
+	h.ServeHTTPC(context.Background(), &fctx)
+	if len(dumps) != 0 {
+		t.Fatalf("expecting no dump without the secret, got %q", dumps)
+	}
+
+	fctx.Request.Header.Set("X-Debug-Dump", "s3cr3t")
+	h.ServeHTTPC(context.Background(), &fctx)
+	if len(dumps) != 1 {
+		t.Fatalf("expecting a dump with the secret, got %q", dumps)
+	}
+	expected := `curl -X POST 'http://example.com/notes?tag=it'\''s' -H 'Host: example.com'` +
+		` -H 'Authorization: [REDACTED]' --data-binary 'hello wo' # body truncated`
+	if dumps[0] != expected {
+		t.Fatalf("got %s", dumps[0])
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import "github.com/hmgle/chi"
+
+// DevOnly returns a middleware applying `mw` when `env`, the environment the
+// application runs in, is the development one, see chi.OSEnv, so debugging
+// middlewares can be declared inline at no cost in production:
+//
+//	r.Use(middleware.DevOnly(env, middleware.Logger))
+func DevOnly(env string, mw func(chi.Handler) chi.Handler) func(chi.Handler) chi.Handler {
+	return EnvOnly(env, chi.EnvDevelopment, mw)
+}
+
+// EnvOnly returns a middleware applying `mw` when `env`, the environment the
+// application runs in, is `target`. In other environments the middleware is
+// left out of the chain when the router builds it.
+func EnvOnly(env, target string, mw func(chi.Handler) chi.Handler) func(chi.Handler) chi.Handler {
+	return func(next chi.Handler) chi.Handler {
+		if env != target {
+			return next
+		}
+		return mw(next)
+	}
+}
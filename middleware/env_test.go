@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestDevOnly(t *testing.T) {
+	debug := func(next chi.Handler) chi.Handler {
+		return chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Response.Header.Set("X-Debug", "1")
+			next.ServeHTTPC(ctx, fctx)
+		})
+	}
+	h := chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+
+	for _, env := range []string{chi.EnvDevelopment, chi.EnvProduction} {
+		var fctx fasthttp.RequestCtx
+		DevOnly(env, debug)(h).ServeHTTPC(context.Background(), &fctx)
+
+		debugged := fctx.Response.Header.Peek("X-Debug") != nil
+		if debugged != (env == chi.EnvDevelopment) {
+			t.Fatalf("%s: unexpected X-Debug header '%s'", env, fctx.Response.Header.Peek("X-Debug"))
+		}
+	}
+}
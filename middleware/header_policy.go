@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// A HeaderPolicy lists the headers the responses of a group of routes must
+// and must not include, enforced by the EnforceHeaders middleware, ie.
+//
+//	api := &middleware.HeaderPolicy{
+//		Required:  []string{"Cache-Control", "Access-Control-Allow-Origin"},
+//		Forbidden: []string{"X-Powered-By"},
+//		Defaults:  map[string]string{"Cache-Control": "no-store"},
+//		Fix:       true,
+//	}
+//	r.Group(func(r chi.Router) {
+//		r.Use(middleware.EnforceHeaders(api))
+//		...
+//	})
+type HeaderPolicy struct {
+	// Headers the responses must include
+	Required []string
+
+	// Headers the responses must not include
+	Forbidden []string
+
+	// Values set on responses missing a required header, when fixing them
+	Defaults map[string]string
+
+	// Fix the responses violating the policy, removing the forbidden
+	// headers and setting the defaults of the missing ones
+	Fix bool
+
+	// Called on every violation, defaults to logging it
+	OnViolation func(ctx context.Context, fctx *fasthttp.RequestCtx, v HeaderViolation)
+
+	violations uint64
+}
+
+// A HeaderViolation is a response header breaking a HeaderPolicy.
+type HeaderViolation struct {
+	Header string
+
+	// Whether the header is required and missing, or forbidden and present
+	Missing bool
+}
+
+func (v HeaderViolation) String() string {
+	if v.Missing {
+		return "missing required header " + v.Header
+	}
+	return "forbidden header " + v.Header
+}
+
+// Violations returns the number of violations of the policy so far, fixed
+// or not.
+func (p *HeaderPolicy) Violations() uint64 {
+	return atomic.LoadUint64(&p.violations)
+}
+
+// EnforceHeaders is a middleware checking the response headers against the
+// policy once the handler returned, reporting and optionally fixing the
+// violations.
+func EnforceHeaders(p *HeaderPolicy) func(chi.Handler) chi.Handler {
+	return func(next chi.Handler) chi.Handler {
+		fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			next.ServeHTTPC(ctx, fctx)
+
+			h := &fctx.Response.Header
+			for _, k := range p.Required {
+				if h.Peek(k) != nil {
+					continue
+				}
+				p.violate(ctx, fctx, HeaderViolation{Header: k, Missing: true})
+				if v, ok := p.Defaults[k]; ok && p.Fix {
+					h.Set(k, v)
+				}
+			}
+			for _, k := range p.Forbidden {
+				if h.Peek(k) == nil {
+					continue
+				}
+				p.violate(ctx, fctx, HeaderViolation{Header: k})
+				if p.Fix {
+					h.Del(k)
+				}
+			}
+		}
+		return chi.HandlerFunc(fn)
+	}
+}
+
+// violate counts and reports a violation.
+func (p *HeaderPolicy) violate(ctx context.Context, fctx *fasthttp.RequestCtx, v HeaderViolation) {
+	atomic.AddUint64(&p.violations, 1)
+	if p.OnViolation != nil {
+		p.OnViolation(ctx, fctx, v)
+		return
+	}
+	log.Printf("header policy: %s %s: %s", fctx.Method(), chi.RoutePattern(ctx), v)
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestEnforceHeaders(t *testing.T) {
+	var reported []string
+	p := &HeaderPolicy{
+		Required:  []string{"Cache-Control", "Access-Control-Allow-Origin"},
+		Forbidden: []string{"X-Powered-By"},
+		Defaults:  map[string]string{"Cache-Control": "no-store"},
+		OnViolation: func(ctx context.Context, fctx *fasthttp.RequestCtx, v HeaderViolation) {
+			reported = append(reported, v.String())
+		},
+	}
+	h := EnforceHeaders(p)(chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Response.Header.Set("X-Powered-By", "PHP")
+		fctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
+	}))
+
+	var fctx fasthttp.RequestCtx
+	h.ServeHTTPC(context.Background(), &fctx)
+	if len(reported) != 2 || reported[0] != "missing required header Cache-Control" || reported[1] != "forbidden header X-Powered-By" {
+		t.Fatalf("got violations %v", reported)
+	}
+	if fctx.Response.Header.Peek("X-Powered-By") == nil {
+		t.Fatalf("expecting the response to be left as is")
+	}
+
+	p.Fix = true
+	fctx = fasthttp.RequestCtx{}
+	h.ServeHTTPC(context.Background(), &fctx)
+	if fctx.Response.Header.Peek("X-Powered-By") != nil || string(fctx.Response.Header.Peek("Cache-Control")) != "no-store" {
+		t.Fatalf("expecting the response headers to be fixed")
+	}
+	if p.Violations() != 4 {
+		t.Fatalf("expecting 4 violations, got %d", p.Violations())
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"sync"
+	"time"
+)
+
+// RequestIDGenerator generates the request IDs of the RequestID middleware,
+// the host, process and counter IDs by default. Setting it to NewULID or
+// NewUUID, at startup, makes the request IDs ULIDs or UUIDs, so the IDs
+// handlers mint for their resources with NewID are of the same kind:
+//
+//	middleware.RequestIDGenerator = middleware.NewULID
+//
+//	func createArticle(ctx context.Context, fctx *fasthttp.RequestCtx) {
+//		article := &Article{ID: middleware.NewID()}
+//		...
+//	}
+var RequestIDGenerator = counterID
+
+// NewID returns a new ID from the RequestIDGenerator.
+func NewID() string {
+	return RequestIDGenerator()
+}
+
+// entropy pools buffered readers of crypto/rand, so IDs are generated
+// without a syscall each.
+var entropy = sync.Pool{
+	New: func() interface{} {
+		return bufio.NewReaderSize(rand.Reader, 256)
+	},
+}
+
+func readEntropy(b []byte) {
+	r := entropy.Get().(*bufio.Reader)
+	if _, err := io.ReadFull(r, b); err != nil {
+		panic("middleware: reading random bytes: " + err.Error())
+	}
+	entropy.Put(r)
+}
+
+// NewUUID returns a random (version 4) UUID, ie.
+// "f47ac10b-58cc-4372-a567-0e02b2c3d479".
+func NewUUID() string {
+	var u [16]byte
+	readEntropy(u[:])
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// ulids holds the last ULID generated, for the next ones of the same
+// millisecond to follow it.
+var ulids struct {
+	mu   sync.Mutex
+	ms   uint64
+	rand [10]byte
+}
+
+// crockford is the base32 alphabet of ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a ULID, ie. "01ARZ3NDEKTSV4RRFFQ69G5FAV", a timestamp in
+// milliseconds followed by random bits, so ULIDs sort by creation time.
+// They're monotonic: the ULIDs of the same millisecond increment the
+// random bits of the previous one, sorting in the order they were
+// generated too.
+func NewULID() string {
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+
+	ulids.mu.Lock()
+	if ms <= ulids.ms {
+		// Same millisecond, or the clock went back
+		ms = ulids.ms
+		if incr(ulids.rand[:]) {
+			// Random bits overflowed, borrow the next millisecond
+			ms++
+			readEntropy(ulids.rand[:])
+		}
+	} else {
+		readEntropy(ulids.rand[:])
+	}
+	ulids.ms = ms
+
+	var id [16]byte
+	binary.BigEndian.PutUint16(id[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	copy(id[6:], ulids.rand[:])
+	ulids.mu.Unlock()
+
+	// 128 bits in 26 characters of 5 bits, from the last one
+	hi, lo := binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+	var buf [26]byte
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(buf[:])
+}
+
+// incr increments the big-endian number b, reporting whether it overflowed.
+func incr(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
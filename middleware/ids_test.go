@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestNewUUID(t *testing.T) {
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := NewUUID()
+		if !re.MatchString(id) || seen[id] {
+			t.Fatalf("got '%s'", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestNewULID(t *testing.T) {
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = NewULID()
+		if len(ids[i]) != 26 || ids[i][0] > '7' {
+			t.Fatalf("got '%s'", ids[i])
+		}
+		if i > 0 && ids[i] <= ids[i-1] {
+			t.Fatalf("expecting '%s' to sort after '%s'", ids[i], ids[i-1])
+		}
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Fatal("expecting the ULIDs to be sorted")
+	}
+
+	var b [3]byte
+	b[2] = 0xff
+	if incr(b[:]) || b != [3]byte{0, 1, 0} {
+		t.Fatalf("got %v", b)
+	}
+	b = [3]byte{0xff, 0xff, 0xff}
+	if !incr(b[:]) {
+		t.Fatal("expecting an overflow")
+	}
+}
+
+func TestRequestIDGenerator(t *testing.T) {
+	defer func(gen func() string) { RequestIDGenerator = gen }(RequestIDGenerator)
+	RequestIDGenerator = func() string { return "id-1" }
+
+	var reqID string
+	h := RequestID(chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		reqID = GetReqID(ctx)
+	}))
+	h.ServeHTTPC(context.Background(), &fasthttp.RequestCtx{})
+	if reqID != "id-1" || NewID() != "id-1" {
+		t.Fatalf("got '%s'", reqID)
+	}
+}
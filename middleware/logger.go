@@ -1,3 +1,67 @@
 package middleware
 
-// TODO
+// Ported from Goji's middleware, source:
+// https://github.com/zenazn/goji/tree/master/web/middleware
+
+import (
+	"bytes"
+	"log"
+	"time"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// Logger is a middleware that logs the start and end of each request, along
+// with some useful data about what was requested, what the response status
+// was, and how long it took to return. When standard output is a TTY,
+// Logger will print in color, otherwise it will print in black and white.
+//
+// Logger prints a request ID if one is provided.
+func Logger(next chi.Handler) chi.Handler {
+	fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		start := time.Now()
+		next.ServeHTTPC(ctx, fctx)
+		printRequest(ctx, fctx, time.Since(start))
+	}
+	return chi.HandlerFunc(fn)
+}
+
+func printRequest(ctx context.Context, fctx *fasthttp.RequestCtx, elapsed time.Duration) {
+	var buf bytes.Buffer
+
+	if reqID := GetReqID(ctx); reqID != "" {
+		cW(&buf, nYellow, "[%s] ", reqID)
+	}
+	cW(&buf, bMagenta, "%s ", fctx.Method())
+	cW(&buf, nBlue, "%q ", fctx.RequestURI())
+	buf.WriteString("from ")
+	buf.WriteString(GetRealIP(ctx, fctx))
+	buf.WriteString(" - ")
+
+	status := fctx.Response.StatusCode()
+	switch {
+	case status < 200:
+		cW(&buf, bBlue, "%03d", status)
+	case status < 300:
+		cW(&buf, bGreen, "%03d", status)
+	case status < 400:
+		cW(&buf, bCyan, "%03d", status)
+	case status < 500:
+		cW(&buf, bYellow, "%03d", status)
+	default:
+		cW(&buf, bRed, "%03d", status)
+	}
+	buf.WriteString(" in ")
+	switch {
+	case elapsed < 500*time.Millisecond:
+		cW(&buf, nGreen, "%s", elapsed)
+	case elapsed < 5*time.Second:
+		cW(&buf, nYellow, "%s", elapsed)
+	default:
+		cW(&buf, nRed, "%s", elapsed)
+	}
+
+	log.Print(buf.String())
+}
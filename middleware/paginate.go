@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// Query params of the page number and size read by Paginate.
+var (
+	PageParam     = "page"
+	PageSizeParam = "per_page"
+)
+
+// DefaultPageSize is the size of the pages of requests without one, and
+// MaxPageSize the size larger requested ones are capped to.
+var (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+type ctxKeyPage int
+
+// PageKey is the key that holds the Page of the request in a request context.
+const PageKey ctxKeyPage = 0
+
+// A Page is the page of a collection requested, set by Paginate. List
+// handlers read the items of the page from its Offset and Size, and set
+// the Total number of items for SetPageHeaders.
+type Page struct {
+	// Page number, from 1
+	Number int
+
+	Size  int
+	Total int
+}
+
+// Offset returns the number of items before the page.
+func (p *Page) Offset() int {
+	return (p.Number - 1) * p.Size
+}
+
+// Pages returns the number of pages of the Total items, at least 1.
+func (p *Page) Pages() int {
+	if p.Total <= p.Size {
+		return 1
+	}
+	return (p.Total + p.Size - 1) / p.Size
+}
+
+// Paginate is a middleware reading the page number and size requested in
+// the `page` and `per_page` query params into the Page of the request,
+// read with GetPage. Requests with an invalid page get a 400.
+//
+//	r.With(middleware.Paginate).Get("/articles", listArticles).Name("articles")
+//
+//	func listArticles(ctx context.Context, fctx *fasthttp.RequestCtx) {
+//		page := middleware.GetPage(ctx)
+//		articles, total := dbListArticles(page.Offset(), page.Size)
+//		page.Total = total
+//		middleware.SetPageHeaders(ctx, fctx, page, "articles")
+//		render.JSON(fctx, 200, articles)
+//	}
+func Paginate(next chi.Handler) chi.Handler {
+	fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		page := &Page{Number: 1, Size: DefaultPageSize}
+		args := fctx.QueryArgs()
+		for _, p := range []struct {
+			key string
+			v   *int
+		}{{PageParam, &page.Number}, {PageSizeParam, &page.Size}} {
+			if v := args.Peek(p.key); len(v) > 0 {
+				n, err := strconv.Atoi(string(v))
+				if err != nil || n < 1 {
+					fctx.Error(fmt.Sprintf("invalid %s '%s'", p.key, v), fasthttp.StatusBadRequest)
+					return
+				}
+				*p.v = n
+			}
+		}
+		if page.Size > MaxPageSize {
+			page.Size = MaxPageSize
+		}
+
+		ctx = context.WithValue(ctx, PageKey, page)
+		next.ServeHTTPC(ctx, fctx)
+	}
+	return chi.HandlerFunc(fn)
+}
+
+// GetPage returns the Page of the request set by Paginate, or nil.
+func GetPage(ctx context.Context) *Page {
+	page, _ := ctx.Value(PageKey).(*Page)
+	return page
+}
+
+// SetPageHeaders sets the X-Total-Count header to the Total of the page and
+// the Link header, see RFC 5988, to the first, previous, next and last
+// pages of the collection, ie.
+//
+//	Link: </articles?page=1>; rel="first", </articles?page=2>; rel="prev", </articles?page=4>; rel="next", </articles?page=9>; rel="last"
+//
+// The links are the path of the route named `route`, see chi.URLFor, with
+// the URL params of the request, or the path of the request if `route` is
+// empty, followed by the query of the request with the page number set.
+func SetPageHeaders(ctx context.Context, fctx *fasthttp.RequestCtx, page *Page, route string) error {
+	path := string(fctx.Path())
+	if route != "" {
+		var err error
+		if path, err = chi.URLFor(ctx, route, chi.URLParams(ctx)); err != nil {
+			return err
+		}
+	}
+
+	var args fasthttp.Args
+	link := func(number int, rel string) string {
+		fctx.QueryArgs().CopyTo(&args)
+		args.SetUint(PageParam, number)
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, path, args.QueryString(), rel)
+	}
+
+	last := page.Pages()
+	links := []string{link(1, "first")}
+	if page.Number > 1 {
+		links = append(links, link(page.Number-1, "prev"))
+	}
+	if page.Number < last {
+		links = append(links, link(page.Number+1, "next"))
+	}
+	links = append(links, link(last, "last"))
+
+	fctx.Response.Header.Set("Link", strings.Join(links, ", "))
+	fctx.Response.Header.Set("X-Total-Count", strconv.Itoa(page.Total))
+	return nil
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestPaginate(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/hubs/:hubID", func(r chi.Router) {
+		r.With(Paginate).Get("/articles", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			page := GetPage(ctx)
+			page.Total = 95
+			if err := SetPageHeaders(ctx, fctx, page, "articles"); err != nil {
+				t.Fatal(err)
+			}
+		}).Name("articles")
+	})
+
+	tests := []struct {
+		uri   string
+		link  string
+		total string
+	}{
+		{"/hubs/1/articles", `</hubs/1/articles?page=1>; rel="first", </hubs/1/articles?page=2>; rel="next", </hubs/1/articles?page=5>; rel="last"`, "95"},
+		{"/hubs/1/articles?sort=title&page=3", `</hubs/1/articles?sort=title&page=1>; rel="first", </hubs/1/articles?sort=title&page=2>; rel="prev", </hubs/1/articles?sort=title&page=4>; rel="next", </hubs/1/articles?sort=title&page=5>; rel="last"`, "95"},
+		{"/hubs/1/articles?per_page=500&page=1", `</hubs/1/articles?per_page=500&page=1>; rel="first", </hubs/1/articles?per_page=500&page=1>; rel="last"`, "95"},
+		{"/hubs/1/articles?page=0", "", ""},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.uri)
+		r.ServeHTTP(&fctx)
+		if link := string(fctx.Response.Header.Peek("Link")); link != tt.link {
+			t.Fatalf("%s: got Link '%s'", tt.uri, link)
+		}
+		if total := string(fctx.Response.Header.Peek("X-Total-Count")); total != tt.total {
+			t.Fatalf("%s: got X-Total-Count '%s'", tt.uri, total)
+		}
+	}
+
+	page := &Page{Number: 3, Size: 20, Total: 41}
+	if page.Offset() != 40 || page.Pages() != 3 {
+		t.Fatalf("got offset %d and %d pages", page.Offset(), page.Pages())
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"time"
+)
+
+// A PresetConfig selects the middlewares of the standard stack assembled by
+// PresetFromConfig. Its fields are tagged to be loaded from the TOML or ini
+// configuration of a service, ie.
+//
+//	[middleware]
+//	request_id = true
+//	real_ip = true
+//	logger = true
+//	recoverer = true
+//	timeout = "30s"
+//	compress = 5
+//
+//	[middleware.cors]
+//	allowed_origins = ["https://example.com"]
+type PresetConfig struct {
+	RequestID bool `toml:"request_id" ini:"request_id"`
+	RealIP    bool `toml:"real_ip" ini:"real_ip"`
+	Logger    bool `toml:"logger" ini:"logger"`
+	Recoverer bool `toml:"recoverer" ini:"recoverer"`
+
+	// Timeout of the requests, 0 disabling it
+	Timeout Duration `toml:"timeout" ini:"timeout"`
+
+	// Compression level of the responses, ie. 5, 0 disabling compression
+	Compress int `toml:"compress" ini:"compress"`
+
+	// Cross-origin requests allowed, nil disabling CORS
+	CORS *CORSOptions `toml:"cors" ini:"cors"`
+}
+
+// A Duration is a time.Duration read from a configuration as text, ie.
+// "30s" or "1m30s".
+type Duration time.Duration
+
+// UnmarshalText parses the duration with time.ParseDuration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText formats the duration like time.Duration does.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// PresetFromConfig returns the middlewares of the standard stack enabled by
+// the config, in the order they must run: RequestID, RealIP, Logger,
+// Recoverer, Timeout, Compress and CORS, so services share one stack shape
+// tuned by their configuration:
+//
+//	r.Use(middleware.PresetFromConfig(cfg.Middleware)...)
+func PresetFromConfig(cfg PresetConfig) []interface{} {
+	var mws []interface{}
+	if cfg.RequestID {
+		mws = append(mws, RequestID)
+	}
+	if cfg.RealIP {
+		mws = append(mws, RealIP)
+	}
+	if cfg.Logger {
+		mws = append(mws, Logger)
+	}
+	if cfg.Recoverer {
+		mws = append(mws, Recoverer)
+	}
+	if cfg.Timeout > 0 {
+		mws = append(mws, Timeout(time.Duration(cfg.Timeout)))
+	}
+	if cfg.Compress > 0 {
+		mws = append(mws, Compress(cfg.Compress))
+	}
+	if cfg.CORS != nil {
+		mws = append(mws, CORS(*cfg.CORS))
+	}
+	return mws
+}
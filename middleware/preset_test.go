@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestPresetFromConfig(t *testing.T) {
+	var cfg PresetConfig
+	if err := cfg.Timeout.UnmarshalText([]byte("1m30s")); err != nil || time.Duration(cfg.Timeout) != 90*time.Second {
+		t.Fatalf("got %v (%v)", time.Duration(cfg.Timeout), err)
+	}
+	cfg.RequestID = true
+	cfg.RealIP = true
+	cfg.Recoverer = true
+	cfg.CORS = &CORSOptions{AllowedOrigins: []string{"https://example.com"}, MaxAge: 600}
+
+	if mws := PresetFromConfig(cfg); len(mws) != 5 {
+		t.Fatalf("expecting 5 middlewares, got %d", len(mws))
+	}
+
+	r := chi.NewRouter()
+	r.Use(PresetFromConfig(cfg)...)
+	r.Get("/", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte(GetReqID(ctx) + " " + GetRealIP(ctx, fctx)))
+	})
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.Set("Origin", "https://example.com")
+	fctx.Request.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	fctx.Request.SetRequestURI("/")
+	r.ServeHTTP(&fctx)
+	if body := string(fctx.Response.Body()); len(body) < 20 || body[len(body)-12:] != " 203.0.113.7" {
+		t.Fatalf("got '%s'", body)
+	}
+	if origin := string(fctx.Response.Header.Peek("Access-Control-Allow-Origin")); origin != "https://example.com" {
+		t.Fatalf("got Access-Control-Allow-Origin '%s'", origin)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	fctx.Request.Header.SetMethod("OPTIONS")
+	fctx.Request.Header.Set("Origin", "https://example.com")
+	fctx.Request.Header.Set("Access-Control-Request-Method", "POST")
+	fctx.Request.SetRequestURI("/")
+	r.ServeHTTP(&fctx)
+	if fctx.Response.StatusCode() != 204 || string(fctx.Response.Header.Peek("Access-Control-Max-Age")) != "600" {
+		t.Fatalf("got %d", fctx.Response.StatusCode())
+	}
+}
+
+func basicAuth(next chi.Handler) chi.Handler { return next }
+
+func TestMiddlewareWarnings(t *testing.T) {
+	h := func(ctx context.Context, fctx *fasthttp.RequestCtx) {}
+
+	r := chi.NewRouter()
+	r.Use(PresetFromConfig(PresetConfig{RequestID: true, RealIP: true, Logger: true, Recoverer: true, Compress: 5})...)
+	r.Get("/", h)
+	if warnings := r.MiddlewareWarnings(); len(warnings) != 0 {
+		t.Fatalf("expecting no warnings for the preset, got %v", warnings)
+	}
+
+	admin := chi.NewRouter()
+	admin.Use(Throttle(10), Recoverer, basicAuth, CORS(CORSOptions{}))
+	admin.Get("/", h)
+	r = chi.NewRouter()
+	r.Use(Compress(5), Logger, RequestID)
+	r.Mount("/admin", admin)
+
+	expected := []string{
+		"/: middleware.Logger runs inside middleware.Compress and sees the responses before compression, use it before middleware.Compress",
+		"/: middleware.Logger runs before middleware.RequestID and prints no request IDs, use it after middleware.RequestID",
+		"/admin: middleware.Recoverer runs inside middleware.Compress, move it up so panics in middleware.Compress are recovered",
+		"/admin: middleware.CORS runs inside middleware.basicAuth, which rejects preflight requests without credentials, use it before middleware.basicAuth",
+	}
+	warnings := r.MiddlewareWarnings()
+	if strings.Join(warnings, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("got %q", warnings)
+	}
+}
@@ -1,3 +1,56 @@
 package middleware
 
-// TODO
+import (
+	"net"
+	"strings"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// Key to use when setting the client IP.
+type ctxKeyRealIP int
+
+// RealIPKey is the key that holds the client IP in a request context.
+const RealIPKey ctxKeyRealIP = 0
+
+// RealIP is a middleware that sets the client IP of the request, read back
+// with GetRealIP, from the X-Forwarded-For or X-Real-IP headers set by a
+// reverse proxy, falling back to the remote address of the connection.
+//
+// Only use it behind a proxy setting the headers, as clients could
+// otherwise spoof them.
+func RealIP(next chi.Handler) chi.Handler {
+	fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		if ip := realIP(fctx); ip != "" {
+			ctx = context.WithValue(ctx, RealIPKey, ip)
+		}
+		next.ServeHTTPC(ctx, fctx)
+	}
+	return chi.HandlerFunc(fn)
+}
+
+// GetRealIP returns the client IP set by the RealIP middleware, or the
+// remote IP of the connection.
+func GetRealIP(ctx context.Context, fctx *fasthttp.RequestCtx) string {
+	if ip, ok := ctx.Value(RealIPKey).(string); ok {
+		return ip
+	}
+	return fctx.RemoteIP().String()
+}
+
+// realIP returns the first valid IP of the X-Forwarded-For header, or the
+// IP of the X-Real-IP header.
+func realIP(fctx *fasthttp.RequestCtx) string {
+	if xff := string(fctx.Request.Header.Peek("X-Forwarded-For")); xff != "" {
+		ip := strings.TrimSpace(strings.SplitN(xff, ",", 2)[0])
+		if net.ParseIP(ip) != nil {
+			return ip
+		}
+	}
+	if ip := strings.TrimSpace(string(fctx.Request.Header.Peek("X-Real-IP"))); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return ""
+}
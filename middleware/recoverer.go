@@ -4,37 +4,190 @@ package middleware
 // https://github.com/zenazn/goji/tree/master/web/middleware
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"html"
 	"log"
 	"runtime/debug"
+	"time"
 
 	"github.com/valyala/fasthttp"
 
-	"bitbucket.org/gle/chi"
+	"github.com/hmgle/chi"
 	"golang.org/x/net/context"
 )
 
+// An Incident describes a panic recovered by the Recoverer middleware.
+// Its ID is handed out to the client so that support teams can look the
+// incident up later from the logs or from the store of a RecovererHook.
+type Incident struct {
+	ID        string
+	RequestID string
+	Time      time.Time
+	Method    string
+	Path      string
+	Route     string
+	Panic     interface{}
+	Stack     []byte
+}
+
 // Recoverer is a middleware that recovers from panics, logs the panic (and a
 // backtrace), and returns a HTTP 500 (Internal Server Error) status if
 // possible.
 //
+// Each panic is assigned an incident ID which is logged and included in the
+// response, as JSON or as an HTML page depending on the request's Accept
+// header, and in the X-Incident-ID response header.
+//
 // Recoverer prints a request ID if one is provided.
 func Recoverer(next chi.Handler) chi.Handler {
-	fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+	return RecovererHook(nil)(next)
+}
+
+// RecovererHook returns a Recoverer middleware that also passes every
+// incident to `fn`, ie. to store it in an error tracker where it can be
+// looked up by ID. The incidents of body stream writers, see
+// SetBodyStreamWriter, are passed along a context detached from the
+// request, which is over by then, carrying its request ID alone.
+func RecovererHook(fn func(ctx context.Context, incident *Incident)) func(chi.Handler) chi.Handler {
+	report := func(ctx context.Context, incident *Incident, err interface{}) *Incident {
+		incident.ID = newIncidentID()
+		incident.Time = time.Now()
+		incident.Panic = err
+		incident.Stack = debug.Stack()
+		printPanic(&bytes.Buffer{}, incident)
+		if fn != nil {
+			fn(ctx, incident)
+		}
+		return incident
+	}
+
+	return func(next chi.Handler) chi.Handler {
+		hfn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			defer func() {
+				if err := recover(); err != nil {
+					writeIncident(fctx, report(ctx, requestIncident(ctx, fctx), err))
+				}
+			}()
+
+			ctx = context.WithValue(ctx, recovererKey, report)
+			next.ServeHTTPC(ctx, fctx)
+		}
+
+		return chi.HandlerFunc(hfn)
+	}
+}
+
+// Key of the incident reporter of the Recoverer serving the request.
+type ctxKeyRecoverer int
+
+const recovererKey ctxKeyRecoverer = 0
+
+// requestIncident returns an incident with the details of the request.
+func requestIncident(ctx context.Context, fctx *fasthttp.RequestCtx) *Incident {
+	return &Incident{
+		RequestID: GetReqID(ctx),
+		Method:    string(fctx.Method()),
+		Path:      string(fctx.Path()),
+		Route:     chi.RoutePattern(ctx),
+	}
+}
+
+// SetBodyStreamWriter sets the body stream writer of the response like
+// fasthttp's RequestCtx.SetBodyStreamWriter. On routes registered with
+// chi.WithStreamRecover, the panics of `sw` are recovered by the Recoverer
+// serving the request, if any, and reported as incidents, the stream being
+// ended with the terminal frame of the route and the connection closed:
+//
+//	r.Get("/events", streamEvents, chi.WithStreamRecover([]byte("event: error\ndata: internal error\n\n")))
+//
+//	func streamEvents(ctx context.Context, fctx *fasthttp.RequestCtx) {
+//		fctx.SetContentType("text/event-stream")
+//		middleware.SetBodyStreamWriter(ctx, fctx, func(w *bufio.Writer) {
+//			...
+//		})
+//	}
+func SetBodyStreamWriter(ctx context.Context, fctx *fasthttp.RequestCtx, sw func(w *bufio.Writer)) {
+	report, _ := ctx.Value(recovererKey).(func(ctx context.Context, incident *Incident, err interface{}) *Incident)
+	terminal, ok := chi.StreamTerminal(ctx)
+	if report == nil || !ok {
+		fctx.SetBodyStreamWriter(sw)
+		return
+	}
+
+	// The writer runs once the handler returned, the request and its
+	// routing context being recycled by then, so the incident is read from
+	// them now and reported along a context detached from them
+	incident := requestIncident(ctx, fctx)
+	detached := context.WithValue(context.Background(), RequestIDKey, incident.RequestID)
+	fctx.SetConnectionClose()
+	fctx.SetBodyStreamWriter(func(w *bufio.Writer) {
 		defer func() {
 			if err := recover(); err != nil {
-				debug.PrintStack()
-				fctx.Error("Internal Server Error", fasthttp.StatusInternalServerError)
+				report(detached, incident, err)
+				w.Write(terminal)
+				w.Flush()
 			}
 		}()
+		sw(w)
+	})
+}
 
-		next.ServeHTTPC(ctx, fctx)
+// newIncidentID returns a short random ID that is easy to read out loud.
+func newIncidentID() string {
+	var buf [10]byte
+	rand.Read(buf[:])
+	return base32.StdEncoding.EncodeToString(buf[:])
+}
+
+// writeIncident responds with a 500 error carrying the incident ID in the
+// format preferred by the client.
+func writeIncident(fctx *fasthttp.RequestCtx, incident *Incident) {
+	fctx.Response.Reset()
+	fctx.Response.Header.Set("X-Incident-ID", incident.ID)
+	fctx.SetStatusCode(fasthttp.StatusInternalServerError)
+
+	status := fasthttp.StatusMessage(fasthttp.StatusInternalServerError)
+	if prefersHTML(fctx) {
+		fctx.SetContentType("text/html; charset=utf-8")
+		fctx.WriteString("<!DOCTYPE html>\n<html><head><title>" + status + "</title></head><body>\n" +
+			"<h1>" + status + "</h1>\n" +
+			"<p>Incident ID: <code>" + html.EscapeString(incident.ID) + "</code></p>\n" +
+			"</body></html>\n")
+		return
 	}
 
-	return chi.HandlerFunc(fn)
+	b, _ := json.Marshal(map[string]string{
+		"error":       status,
+		"incident_id": incident.ID,
+	})
+	fctx.SetContentType("application/json; charset=utf-8")
+	fctx.Write(b)
 }
 
-func printPanic(buf *bytes.Buffer, reqID string, err interface{}) {
-	cW(buf, bRed, "panic: %+v", err)
+// prefersHTML reports whether the client prefers an HTML page over JSON.
+func prefersHTML(fctx *fasthttp.RequestCtx) bool {
+	for _, qv := range chi.QValues(fctx, "Accept") {
+		switch qv.Value {
+		case "text/html", "application/xhtml+xml":
+			return true
+		case "application/json", "*/*":
+			return false
+		}
+	}
+	return false
+}
+
+func printPanic(buf *bytes.Buffer, incident *Incident) {
+	cW(buf, bRed, "panic: %+v", incident.Panic)
+	cW(buf, nYellow, " incident:%s", incident.ID)
+	if incident.RequestID != "" {
+		cW(buf, nYellow, " reqid:%s", incident.RequestID)
+	}
+	cW(buf, nBlue, " %s %s\n", incident.Method, incident.Path)
+	buf.Write(incident.Stack)
 	log.Print(buf.String())
 }
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestRecovererIncident(t *testing.T) {
+	var incidents []*Incident
+	h := RecovererHook(func(ctx context.Context, incident *Incident) {
+		incidents = append(incidents, incident)
+	})(chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.WriteString("partial output")
+		panic("oops")
+	}))
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/articles/1")
+	h.ServeHTTPC(context.Background(), &fctx)
+
+	if len(incidents) != 1 {
+		t.Fatalf("expecting 1 incident, got %d", len(incidents))
+	}
+	incident := incidents[0]
+	if incident.ID == "" || incident.Panic != "oops" || incident.Path != "/articles/1" {
+		t.Fatalf("unexpected incident %+v", incident)
+	}
+	if fctx.Response.StatusCode() != 500 {
+		t.Fatalf("expecting status 500, got %d", fctx.Response.StatusCode())
+	}
+	if string(fctx.Response.Header.Peek("X-Incident-ID")) != incident.ID {
+		t.Fatalf("expecting X-Incident-ID header %s", incident.ID)
+	}
+
+	var body map[string]string
+	if err := json.Unmarshal(fctx.Response.Body(), &body); err != nil {
+		t.Fatalf("expecting a JSON body, got %q", fctx.Response.Body())
+	}
+	if body["incident_id"] != incident.ID {
+		t.Fatalf("expecting incident_id %s, got %v", incident.ID, body)
+	}
+
+	// Browsers get an HTML page
+	var hctx fasthttp.RequestCtx
+	hctx.Request.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	h.ServeHTTPC(context.Background(), &hctx)
+
+	if !bytes.Contains(hctx.Response.Body(), []byte(incidents[1].ID)) {
+		t.Fatalf("expecting incident ID in HTML body, got %q", hctx.Response.Body())
+	}
+	if !bytes.HasPrefix(hctx.Response.Header.ContentType(), []byte("text/html")) {
+		t.Fatalf("expecting an HTML response, got %s", hctx.Response.Header.ContentType())
+	}
+}
+
+func TestRecovererStream(t *testing.T) {
+	var incidents []*Incident
+	var routed bool
+	r := chi.NewRouter()
+	r.Use(RequestID)
+	r.Use(RecovererHook(func(ctx context.Context, incident *Incident) {
+		incidents = append(incidents, incident)
+		routed = chi.RouteContext(ctx) != nil
+	}))
+	r.Get("/events", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.SetContentType("text/event-stream")
+		SetBodyStreamWriter(ctx, fctx, func(w *bufio.Writer) {
+			w.WriteString("data: 1\n\n")
+			panic("oops")
+		})
+	}, chi.WithStreamRecover([]byte("event: error\ndata: internal error\n\n")))
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/events")
+	r.ServeHTTP(&fctx)
+	if fctx.Response.StatusCode() != 200 || !fctx.Response.Header.ConnectionClose() {
+		t.Fatalf("expecting a 200 closing the connection, got %d", fctx.Response.StatusCode())
+	}
+	if body := string(fctx.Response.Body()); body != "data: 1\n\nevent: error\ndata: internal error\n\n" {
+		t.Fatalf("got '%s'", body)
+	}
+	if len(incidents) != 1 || incidents[0].Path != "/events" || incidents[0].Route != "/events" || incidents[0].RequestID == "" {
+		t.Fatalf("expecting 1 incident of the request, got %+v", incidents)
+	}
+	if routed {
+		t.Fatal("expecting the hook to get a context detached from the request")
+	}
+}
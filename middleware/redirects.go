@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"log"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// SafeRedirects is a middleware rejecting the responses whose Location
+// header fails chi.ValidRedirect, ie. redirects built by hand from request
+// data smuggling CR/LF into the header. They're logged and replaced with a
+// 500, so a header-splitting Location never reaches the client.
+func SafeRedirects(next chi.Handler) chi.Handler {
+	fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		next.ServeHTTPC(ctx, fctx)
+
+		location := fctx.Response.Header.Peek("Location")
+		if len(location) == 0 {
+			return
+		}
+		if err := chi.ValidRedirect(string(location)); err != nil {
+			log.Printf("chi: %s %s: %v %q", fctx.Method(), fctx.Path(), err, location)
+			fctx.Response.Reset()
+			fctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+		}
+	}
+	return chi.HandlerFunc(fn)
+}
@@ -11,7 +11,7 @@ import (
 	"strings"
 	"sync/atomic"
 
-	"bitbucket.org/gle/chi"
+	"github.com/hmgle/chi"
 	"github.com/valyala/fasthttp"
 	"golang.org/x/net/context"
 )
@@ -61,16 +61,22 @@ func init() {
 // request. A request ID is a string of the form "host.example.com/random-0001",
 // where "random" is a base62 random string that uniquely identifies this go
 // process, and where the last number is an atomically incremented request
-// counter.
+// counter, unless another RequestIDGenerator is set.
 func RequestID(next chi.Handler) chi.Handler {
 	fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
-		myid := atomic.AddUint64(&reqid, 1)
-		ctx = context.WithValue(ctx, RequestIDKey, fmt.Sprintf("%s-%06d", prefix, myid))
+		ctx = context.WithValue(ctx, RequestIDKey, RequestIDGenerator())
 		next.ServeHTTPC(ctx, fctx)
 	}
 	return chi.HandlerFunc(fn)
 }
 
+// counterID returns the next ID of the form "host.example.com/random-0001",
+// see RequestID.
+func counterID() string {
+	myid := atomic.AddUint64(&reqid, 1)
+	return fmt.Sprintf("%s-%06d", prefix, myid)
+}
+
 // GetReqID returns a request ID from the given context if one is present.
 // Returns the empty string if a request ID cannot be found.
 func GetReqID(ctx context.Context) string {
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// A ResponseLimit caps the size of the responses of a group of routes,
+// enforced by the LimitResponses middleware, to catch unbounded responses,
+// ie. a listing without pagination, before they reach the proxies:
+//
+//	r.Group(func(r chi.Router) {
+//		r.Use(middleware.LimitResponses(&middleware.ResponseLimit{MaxBody: 1 << 20}))
+//		r.Get("/articles", listArticles)
+//	})
+type ResponseLimit struct {
+	// Max size of the response body in bytes, 0 for no limit
+	MaxBody int
+
+	// Max size of the response headers in bytes, counted as sent, 0 for no
+	// limit
+	MaxHeader int
+
+	// Truncate the bodies over MaxBody rather than answering a 500. The
+	// truncated body, ie. of JSON, may not parse
+	Truncate bool
+
+	// Called on every response over a limit, defaults to logging it
+	OnExceeded func(ctx context.Context, fctx *fasthttp.RequestCtx, v ResponseSizeViolation)
+
+	exceeded uint64
+}
+
+// A ResponseSizeViolation is a response over a ResponseLimit.
+type ResponseSizeViolation struct {
+	// Whether the headers or the body are over the limit
+	Header bool
+
+	Size  int
+	Limit int
+}
+
+func (v ResponseSizeViolation) String() string {
+	part := "body"
+	if v.Header {
+		part = "headers"
+	}
+	return fmt.Sprintf("response %s of %d bytes over the limit of %d", part, v.Size, v.Limit)
+}
+
+// Exceeded returns the number of responses over the limit so far.
+func (l *ResponseLimit) Exceeded() uint64 {
+	return atomic.LoadUint64(&l.exceeded)
+}
+
+// LimitResponses is a middleware checking the size of the responses against
+// the limit once the handler returned. Responses over it are reported, and
+// answered with a 500 instead, or truncated. Streamed bodies are checked
+// against their Content-Length, ie. those of render.StreamXML, and read
+// whole when truncated. Those streamed without a length, ie. with
+// fctx.SetBodyStreamWriter, aren't checked, their size being unknown by
+// then.
+func LimitResponses(l *ResponseLimit) func(chi.Handler) chi.Handler {
+	return func(next chi.Handler) chi.Handler {
+		fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			next.ServeHTTPC(ctx, fctx)
+
+			if l.MaxHeader > 0 {
+				if n := responseHeaderSize(fctx); n > l.MaxHeader {
+					l.exceed(ctx, fctx, ResponseSizeViolation{Header: true, Size: n, Limit: l.MaxHeader})
+					l.reject(fctx)
+					return
+				}
+			}
+			if l.MaxBody == 0 {
+				return
+			}
+			size := fctx.Response.Header.ContentLength()
+			if !fctx.Response.IsBodyStream() {
+				size = len(fctx.Response.Body())
+			}
+			if size <= l.MaxBody {
+				return
+			}
+			l.exceed(ctx, fctx, ResponseSizeViolation{Size: size, Limit: l.MaxBody})
+			if l.Truncate {
+				fctx.Response.SetBody(fctx.Response.Body()[:l.MaxBody])
+				return
+			}
+			l.reject(fctx)
+		}
+		return chi.HandlerFunc(fn)
+	}
+}
+
+// exceed counts and reports a response over the limit.
+func (l *ResponseLimit) exceed(ctx context.Context, fctx *fasthttp.RequestCtx, v ResponseSizeViolation) {
+	atomic.AddUint64(&l.exceeded, 1)
+	if l.OnExceeded != nil {
+		l.OnExceeded(ctx, fctx, v)
+		return
+	}
+	log.Printf("response limit: %s %s: %s", fctx.Method(), chi.RoutePattern(ctx), v)
+}
+
+// reject replaces the response with a bare 500.
+func (l *ResponseLimit) reject(fctx *fasthttp.RequestCtx) {
+	fctx.Response.Reset()
+	fctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+}
+
+// responseHeaderSize returns the size of the response headers as sent, one
+// `Key: value\r\n` line each.
+func responseHeaderSize(fctx *fasthttp.RequestCtx) int {
+	n := 0
+	fctx.Response.Header.VisitAll(func(k, v []byte) {
+		n += len(k) + len(v) + 4
+	})
+	return n
+}
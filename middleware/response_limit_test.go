@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestLimitResponses(t *testing.T) {
+	var violations []string
+	limit := &ResponseLimit{
+		MaxBody:   8,
+		MaxHeader: 256,
+		OnExceeded: func(ctx context.Context, fctx *fasthttp.RequestCtx, v ResponseSizeViolation) {
+			violations = append(violations, chi.RoutePattern(ctx)+": "+v.String())
+		},
+	}
+	truncated := &ResponseLimit{MaxBody: 8, Truncate: true, OnExceeded: limit.OnExceeded}
+
+	r := chi.NewRouter()
+	r.With(LimitResponses(limit)).Get("/small", func(fctx *fasthttp.RequestCtx) {
+		fctx.WriteString("ok")
+	})
+	r.With(LimitResponses(limit)).Get("/articles", func(fctx *fasthttp.RequestCtx) {
+		fctx.WriteString("[1,2,3,4,5,6]")
+	})
+	r.With(LimitResponses(limit)).Get("/cookies", func(fctx *fasthttp.RequestCtx) {
+		fctx.Response.Header.Set("X-Padding", strings.Repeat("x", 300))
+	})
+	r.With(LimitResponses(truncated)).Get("/log", func(fctx *fasthttp.RequestCtx) {
+		fctx.WriteString("0123456789")
+	})
+	r.With(LimitResponses(limit)).Get("/export", func(fctx *fasthttp.RequestCtx) {
+		fctx.SetBodyStream(strings.NewReader("[1,2,3,4,5]"), 11)
+	})
+	r.With(LimitResponses(truncated)).Get("/export.log", func(fctx *fasthttp.RequestCtx) {
+		fctx.SetBodyStream(strings.NewReader("0123456789"), 10)
+	})
+	r.With(LimitResponses(limit)).Get("/events", func(fctx *fasthttp.RequestCtx) {
+		fctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+			w.WriteString("data: 0123456789\n\n")
+		})
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/small", 200, "ok"},
+		{"/articles", 500, "Internal Server Error"},
+		{"/cookies", 500, "Internal Server Error"},
+		{"/log", 200, "01234567"},
+		{"/export", 500, "Internal Server Error"},
+		{"/export.log", 200, "01234567"},
+		{"/events", 200, "data: 0123456789\n\n"},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.path)
+		r.ServeHTTP(&fctx)
+		if fctx.Response.StatusCode() != tt.status || string(fctx.Response.Body()) != tt.body {
+			t.Fatalf("%s: got %d '%s'", tt.path, fctx.Response.StatusCode(), fctx.Response.Body())
+		}
+	}
+
+	expected := []string{
+		"/articles: response body of 13 bytes over the limit of 8",
+		"/cookies: response headers of ",
+		"/log: response body of 10 bytes over the limit of 8",
+		"/export: response body of 11 bytes over the limit of 8",
+		"/export.log: response body of 10 bytes over the limit of 8",
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("got violations %q", violations)
+	}
+	for i, v := range violations {
+		if !strings.HasPrefix(v, expected[i]) {
+			t.Fatalf("got violations %q", violations)
+		}
+	}
+	if limit.Exceeded() != 3 || truncated.Exceeded() != 2 {
+		t.Fatalf("expecting 3 and 2 responses over the limits, got %d and %d", limit.Exceeded(), truncated.Exceeded())
+	}
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/textproto"
+	"sync"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// DefaultRedactedHeaders are the headers whose values are redacted from the
+// samples of a SampleCapture without Redact headers of its own.
+var DefaultRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// A Sample is a request and its response captured by CaptureSamples, to
+// generate contract tests from the shapes of real traffic.
+type Sample struct {
+	Method string            `json:"method"`
+	Route  string            `json:"route"`
+	Params map[string]string `json:"params,omitempty"`
+	Query  string            `json:"query,omitempty"`
+
+	RequestHeaders map[string]string `json:"requestHeaders,omitempty"`
+	RequestBody    []byte            `json:"requestBody,omitempty"`
+
+	Status          int               `json:"status"`
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"`
+	ResponseBody    []byte            `json:"responseBody,omitempty"`
+}
+
+// A SampleSink receives the samples captured by CaptureSamples. Capture is
+// called from the requests' goroutines, so it must be safe for concurrent
+// use.
+type SampleSink interface {
+	Capture(s Sample)
+}
+
+// SampleSinkFunc is a SampleSink func.
+type SampleSinkFunc func(s Sample)
+
+// Capture calls f(s).
+func (f SampleSinkFunc) Capture(s Sample) {
+	f(s)
+}
+
+// JSONSampleSink returns a SampleSink writing the samples to `w` as JSON,
+// one per line.
+func JSONSampleSink(w io.Writer) SampleSink {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return SampleSinkFunc(func(s Sample) {
+		mu.Lock()
+		enc.Encode(s)
+		mu.Unlock()
+	})
+}
+
+// A SampleCapture captures the first requests of each route, and their
+// responses, into a sink, ie. in staging, with the CaptureSamples
+// middleware:
+//
+//	f, _ := os.Create("samples.jsonl")
+//	r.Use(middleware.CaptureSamples(&middleware.SampleCapture{
+//		PerRoute: 5,
+//		Sink:     middleware.JSONSampleSink(f),
+//	}))
+//
+// Samples are anonymized: the values of the Redact headers are replaced,
+// then the Anonymize func masks the rest, ie. fields of the bodies.
+type SampleCapture struct {
+	// Samples captured per method and route pattern
+	PerRoute int
+
+	// Receives the samples
+	Sink SampleSink
+
+	// Headers whose values are redacted, DefaultRedactedHeaders if nil
+	Redact []string
+
+	// Called on each sample before the sink, to anonymize it further
+	Anonymize func(s *Sample)
+
+	mu       sync.Mutex
+	captured map[string]int
+}
+
+// CaptureSamples is a middleware capturing the requests and responses of
+// the routes into the sink of `c`, until it holds PerRoute samples of each
+// route. The route pattern is known once the handler returned, so it goes
+// in front of the routes, ie. on the root router. Requests matching no
+// route and streamed responses aren't captured.
+func CaptureSamples(c *SampleCapture) func(chi.Handler) chi.Handler {
+	if c.PerRoute < 1 {
+		panic("middleware.CaptureSamples expects PerRoute > 0")
+	}
+	redact := c.Redact
+	if redact == nil {
+		redact = DefaultRedactedHeaders
+	}
+	redacted := make(map[string]bool, len(redact))
+	for _, k := range redact {
+		redacted[textproto.CanonicalMIMEHeaderKey(k)] = true
+	}
+
+	return func(next chi.Handler) chi.Handler {
+		fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			next.ServeHTTPC(ctx, fctx)
+
+			route := chi.RoutePattern(ctx)
+			if route == "" || fctx.Response.IsBodyStream() || !c.reserve(string(fctx.Method())+" "+route) {
+				return
+			}
+			s := Sample{
+				Method:          string(fctx.Method()),
+				Route:           route,
+				Params:          chi.URLParams(ctx),
+				Query:           string(fctx.URI().QueryString()),
+				RequestHeaders:  make(map[string]string),
+				RequestBody:     append([]byte(nil), fctx.PostBody()...),
+				Status:          fctx.Response.StatusCode(),
+				ResponseHeaders: make(map[string]string),
+				ResponseBody:    append([]byte(nil), fctx.Response.Body()...),
+			}
+			copySampleHeaders(s.RequestHeaders, fctx.Request.Header.VisitAll, redacted)
+			copySampleHeaders(s.ResponseHeaders, fctx.Response.Header.VisitAll, redacted)
+			if c.Anonymize != nil {
+				c.Anonymize(&s)
+			}
+			c.Sink.Capture(s)
+		}
+		return chi.HandlerFunc(fn)
+	}
+}
+
+// reserve reports whether the route still takes a sample, counting it.
+func (c *SampleCapture) reserve(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.captured == nil {
+		c.captured = make(map[string]int)
+	}
+	if c.captured[key] >= c.PerRoute {
+		return false
+	}
+	c.captured[key]++
+	return true
+}
+
+// copySampleHeaders copies the headers visited by `visit` into `m`,
+// redacting the values of the `redacted` ones.
+func copySampleHeaders(m map[string]string, visit func(func(k, v []byte)), redacted map[string]bool) {
+	visit(func(k, v []byte) {
+		key := textproto.CanonicalMIMEHeaderKey(string(k))
+		if redacted[key] {
+			m[key] = "REDACTED"
+			return
+		}
+		if prev, ok := m[key]; ok {
+			m[key] = prev + ", " + string(v)
+			return
+		}
+		m[key] = string(v)
+	})
+}
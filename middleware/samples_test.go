@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestCaptureSamples(t *testing.T) {
+	var buf bytes.Buffer
+	r := chi.NewRouter()
+	r.Use(CaptureSamples(&SampleCapture{
+		PerRoute: 2,
+		Sink:     JSONSampleSink(&buf),
+		Anonymize: func(s *Sample) {
+			s.Params["email"] = "user@example.com"
+		},
+	}))
+	r.Post("/users/:email", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Response.Header.Set("Set-Cookie", "session=secret")
+		fctx.SetStatusCode(201)
+		fctx.Write(fctx.PostBody())
+	})
+
+	for _, path := range []string{"/users/a@b.c", "/users/d@e.f", "/users/g@h.i", "/nothing"} {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.Header.SetMethod("POST")
+		fctx.Request.SetRequestURI(path + "?v=1")
+		fctx.Request.Header.Set("Authorization", "Bearer secret")
+		fctx.Request.SetBodyString(`{"name":"x"}`)
+		r.ServeHTTP(&fctx)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expecting 2 samples, got %d: %s", len(lines), buf.String())
+	}
+	var s Sample
+	if err := json.Unmarshal([]byte(lines[0]), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Method != "POST" || s.Route != "/users/:email" || s.Status != 201 || s.Query != "v=1" {
+		t.Fatalf("unexpected sample %+v", s)
+	}
+	if s.Params["email"] != "user@example.com" || string(s.RequestBody) != `{"name":"x"}` || string(s.ResponseBody) != `{"name":"x"}` {
+		t.Fatalf("unexpected sample %+v", s)
+	}
+	if s.RequestHeaders["Authorization"] != "REDACTED" || s.ResponseHeaders["Set-Cookie"] != "REDACTED" {
+		t.Fatalf("expecting the credentials to be redacted, got %v and %v", s.RequestHeaders, s.ResponseHeaders)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Fatalf("expecting no secret in the samples: %s", buf.String())
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// ServerTiming is a middleware that reports the spans recorded with
+// chi.Timing, and the total time spent down the chain, to the client in a
+// Server-Timing response header, ie.
+//
+//	Server-Timing: db;dur=12.5, render;dur=0.8, total;dur=14.1
+//
+// Browsers display these in their developer tools next to the request.
+// Span names should be valid HTTP tokens, without spaces or separators.
+func ServerTiming(next chi.Handler) chi.Handler {
+	fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		start := time.Now()
+		next.ServeHTTPC(ctx, fctx)
+		total := time.Since(start)
+
+		var buf []byte
+		if rctx := chi.RouteContext(ctx); rctx != nil {
+			for _, span := range rctx.Spans() {
+				buf = appendServerTiming(buf, span.Name, span.Duration)
+			}
+		}
+		buf = appendServerTiming(buf, "total", total)
+		fctx.Response.Header.SetBytesV("Server-Timing", buf)
+	}
+	return chi.HandlerFunc(fn)
+}
+
+// appendServerTiming appends a `name;dur=millis` metric to the header value.
+func appendServerTiming(buf []byte, name string, d time.Duration) []byte {
+	if len(buf) > 0 {
+		buf = append(buf, ", "...)
+	}
+	buf = append(buf, name...)
+	buf = append(buf, ";dur="...)
+	return strconv.AppendFloat(buf, float64(d)/float64(time.Millisecond), 'f', 1, 64)
+}
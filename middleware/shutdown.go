@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+const errShuttingDown = "Server is shutting down."
+
+// A ShutdownGate stops admitting requests once the router's parent context
+// is cancelled, answering new requests with a 503 and Connection: close so
+// clients move on to another instance, while requests already past the
+// gate are allowed to finish.
+//
+// The gate should be the first middleware on the root router, served by
+// chi.Serve, which waits for the requests past the gate to finish before
+// running the OnStop hooks once the parent context is cancelled:
+//
+//	ctx, shutdown := context.WithCancel(context.Background())
+//	gate := middleware.NewShutdownGate()
+//	r := chi.NewRouterContext(ctx)
+//	r.Use(gate.Handler)
+//	r.Get("/ready", gate.Ready)
+//
+//	// shutdown() on SIGTERM
+//	chi.Serve(":3333", r)
+//
+// Services running their own fasthttp.Server wait for them with Wait
+// instead.
+type ShutdownGate struct {
+	inflight int64
+	closed   int32
+}
+
+// NewShutdownGate returns a new open ShutdownGate.
+func NewShutdownGate() *ShutdownGate {
+	return &ShutdownGate{}
+}
+
+// Handler is the gate's middleware.
+func (g *ShutdownGate) Handler(next chi.Handler) chi.Handler {
+	fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		select {
+		case <-ctx.Done():
+			g.Close()
+		default:
+		}
+		if g.Closed() {
+			fctx.Error(errShuttingDown, fasthttp.StatusServiceUnavailable)
+			fctx.SetConnectionClose()
+			return
+		}
+
+		atomic.AddInt64(&g.inflight, 1)
+		defer atomic.AddInt64(&g.inflight, -1)
+
+		next.ServeHTTPC(ctx, fctx)
+	}
+	return chi.HandlerFunc(fn)
+}
+
+// Close closes the gate ahead of the parent context being cancelled, ie.
+// to fail readiness checks while load balancers drain the instance.
+func (g *ShutdownGate) Close() {
+	atomic.StoreInt32(&g.closed, 1)
+}
+
+// Closed reports whether the gate stopped admitting requests.
+func (g *ShutdownGate) Closed() bool {
+	return atomic.LoadInt32(&g.closed) == 1
+}
+
+// Inflight returns the number of requests admitted by the gate which
+// haven't finished yet.
+func (g *ShutdownGate) Inflight() int {
+	return int(atomic.LoadInt64(&g.inflight))
+}
+
+// Wait blocks until all admitted requests have finished or the timeout
+// elapses, and reports whether they all finished.
+func (g *ShutdownGate) Wait(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for g.Inflight() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+// Ready is chi.ReadyCheck, the readiness check of chi.Serve, responding
+// with a 503 too once the gate is closed, so load balancers drain the
+// instance as soon as Close is called.
+func (g *ShutdownGate) Ready(ctx context.Context, fctx *fasthttp.RequestCtx) {
+	select {
+	case <-ctx.Done():
+		g.Close()
+	default:
+	}
+	if g.Closed() {
+		fctx.Error(errShuttingDown, fasthttp.StatusServiceUnavailable)
+		return
+	}
+	chi.ReadyCheck(ctx, fctx)
+}
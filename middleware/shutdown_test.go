@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestShutdownGate(t *testing.T) {
+	gate := NewShutdownGate()
+	entered, release := make(chan struct{}), make(chan struct{})
+	h := gate.Handler(chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		entered <- struct{}{}
+		<-release
+		fctx.WriteString("done")
+	}))
+
+	// Admitted before the shutdown, the request is let through to its end
+	ctx, shutdown := context.WithCancel(context.Background())
+	var admitted fasthttp.RequestCtx
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTPC(context.Background(), &admitted)
+		close(done)
+	}()
+	<-entered
+	if gate.Inflight() != 1 {
+		t.Fatalf("expecting 1 request in flight, got %d", gate.Inflight())
+	}
+
+	shutdown()
+	var rejected fasthttp.RequestCtx
+	h.ServeHTTPC(ctx, &rejected)
+	if rejected.Response.StatusCode() != fasthttp.StatusServiceUnavailable || !rejected.Response.Header.ConnectionClose() {
+		t.Fatalf("expecting a 503 closing the connection, got %d", rejected.Response.StatusCode())
+	}
+	if !gate.Closed() {
+		t.Fatal("expecting the gate to be closed")
+	}
+	if gate.Wait(20 * time.Millisecond) {
+		t.Fatal("expecting Wait to time out with a request in flight")
+	}
+
+	close(release)
+	if !gate.Wait(time.Second) {
+		t.Fatal("expecting Wait to return once the request finished")
+	}
+	<-done
+	if admitted.Response.StatusCode() != fasthttp.StatusOK || string(admitted.Response.Body()) != "done" {
+		t.Fatalf("expecting the admitted request to be served, got %d '%s'", admitted.Response.StatusCode(), admitted.Response.Body())
+	}
+}
+
+func TestShutdownGateReady(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	gate := NewShutdownGate()
+	ctx, shutdown := context.WithCancel(context.Background())
+	r := chi.NewRouterContext(ctx)
+	r.Use(gate.Handler)
+	r.Get("/ready", gate.Ready)
+
+	ready := func() int {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI("/ready")
+		r.ServeHTTP(&fctx)
+		return fctx.Response.StatusCode()
+	}
+
+	// Unready until Serve started
+	if status := ready(); status != fasthttp.StatusServiceUnavailable {
+		t.Fatalf("expecting 503 before serving, got %d", status)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- chi.Serve(addr, r)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for !chi.Ready() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if status := ready(); status != fasthttp.StatusOK {
+		t.Fatalf("expecting 200 while serving, got %d", status)
+	}
+
+	// Closed ahead of the shutdown, to drain the instance
+	gate.Close()
+	if status := ready(); status != fasthttp.StatusServiceUnavailable {
+		t.Fatalf("expecting 503 once the gate is closed, got %d", status)
+	}
+	if !chi.Ready() {
+		t.Fatal("expecting Serve to still serve")
+	}
+
+	shutdown()
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,9 +1,10 @@
 package middleware
 
 import (
+	"sync/atomic"
 	"time"
 
-	"bitbucket.org/gle/chi"
+	"github.com/hmgle/chi"
 	"github.com/valyala/fasthttp"
 	"golang.org/x/net/context"
 )
@@ -28,6 +29,13 @@ func Throttle(limit int) func(chi.Handler) chi.Handler {
 // requests at a time and provides a backlog for holding a finite number of
 // pending requests.
 func ThrottleBacklog(limit int, backlogLimit int, backlogTimeout time.Duration) func(chi.Handler) chi.Handler {
+	return ThrottleCounted(limit, backlogLimit, backlogTimeout, &ThrottleCounters{})
+}
+
+// ThrottleCounted is a ThrottleBacklog middleware counting the requests it
+// accepts, rejects, and the ones cancelled while waiting in the backlog,
+// into `counters`. The backlog timeout is measured on the DefaultClock.
+func ThrottleCounted(limit int, backlogLimit int, backlogTimeout time.Duration, counters *ThrottleCounters) func(chi.Handler) chi.Handler {
 	if limit < 1 {
 		panic("middleware.Throttle expects limit > 0")
 	}
@@ -40,6 +48,8 @@ func ThrottleBacklog(limit int, backlogLimit int, backlogTimeout time.Duration)
 		tokens:         make(chan token, limit),
 		backlogTokens:  make(chan token, limit+backlogLimit),
 		backlogTimeout: backlogTimeout,
+		counters:       counters,
+		clock:          DefaultClock,
 	}
 
 	// Filling tokens.
@@ -58,6 +68,31 @@ func ThrottleBacklog(limit int, backlogLimit int, backlogTimeout time.Duration)
 	return fn
 }
 
+// ThrottleCounters counts the requests seen by a Throttle middleware, to
+// size its limits on the requests actually served.
+type ThrottleCounters struct {
+	accepted  uint64
+	rejected  uint64
+	cancelled uint64
+}
+
+// Accepted returns the number of requests passed on to the handler.
+func (c *ThrottleCounters) Accepted() uint64 {
+	return atomic.LoadUint64(&c.accepted)
+}
+
+// Rejected returns the number of requests rejected as the backlog was full
+// or timed out.
+func (c *ThrottleCounters) Rejected() uint64 {
+	return atomic.LoadUint64(&c.rejected)
+}
+
+// Cancelled returns the number of requests whose context was cancelled
+// before reaching the handler, ie. on client disconnects.
+func (c *ThrottleCounters) Cancelled() uint64 {
+	return atomic.LoadUint64(&c.cancelled)
+}
+
 // token represents a request that is being processed.
 type token struct{}
 
@@ -67,38 +102,58 @@ type throttler struct {
 	tokens         chan token
 	backlogTokens  chan token
 	backlogTimeout time.Duration
+	counters       *ThrottleCounters
+	clock          Clock
 }
 
 // ServeHTTPC implements chi.Handler interface.
 func (t *throttler) ServeHTTPC(ctx context.Context, fctx *fasthttp.RequestCtx) {
 	select {
 	case <-ctx.Done():
-		fctx.Error(errContextCanceled, fasthttp.StatusServiceUnavailable)
+		t.cancel(fctx)
 		return
 	case btok := <-t.backlogTokens:
-		timer := time.NewTimer(t.backlogTimeout)
+		timer := t.clock.NewTimer(t.backlogTimeout)
 
 		defer func() {
 			t.backlogTokens <- btok
 		}()
 
 		select {
-		case <-timer.C:
+		case <-timer.C():
+			atomic.AddUint64(&t.counters.rejected, 1)
 			fctx.Error(errTimedOut, fasthttp.StatusServiceUnavailable)
 			return
 		case <-ctx.Done():
-			fctx.Error(errContextCanceled, fasthttp.StatusServiceUnavailable)
+			timer.Stop()
+			t.cancel(fctx)
 			return
 		case tok := <-t.tokens:
+			timer.Stop()
 			defer func() {
 				t.tokens <- tok
 			}()
-			// t.h.ServeHTTPC(ctx, w, r)
+
+			// The token may win the race against a cancellation, give it
+			// back without serving the request
+			if ctx.Err() != nil {
+				t.cancel(fctx)
+				return
+			}
+
+			atomic.AddUint64(&t.counters.accepted, 1)
 			t.h.ServeHTTPC(ctx, fctx)
 		}
 		return
 	default:
+		atomic.AddUint64(&t.counters.rejected, 1)
 		fctx.Error(errCapacityExceeded, fasthttp.StatusServiceUnavailable)
 		return
 	}
 }
+
+// cancel answers a request cancelled before reaching the handler.
+func (t *throttler) cancel(fctx *fasthttp.RequestCtx) {
+	atomic.AddUint64(&t.counters.cancelled, 1)
+	fctx.Error(errContextCanceled, fasthttp.StatusServiceUnavailable)
+}
@@ -1,3 +1,109 @@
 package middleware
 
-// TODO
+import (
+	"testing"
+	"time"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestThrottleCounted(t *testing.T) {
+	var counters ThrottleCounters
+	clock := NewManualClock(time.Now())
+	DefaultClock = clock
+	defer func() { DefaultClock = SystemClock }()
+
+	release := make(chan struct{})
+	served := make(chan struct{}, 2)
+
+	h := ThrottleCounted(1, 1, time.Minute, &counters)(chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		served <- struct{}{}
+		<-release
+	}))
+
+	// Holds the only token
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTPC(context.Background(), &fasthttp.RequestCtx{})
+		close(done)
+	}()
+	<-served
+
+	// Waits in the backlog until cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting := make(chan struct{})
+	go func() {
+		h.ServeHTTPC(ctx, &fasthttp.RequestCtx{})
+		close(waiting)
+	}()
+	for clock.Waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The backlog is full
+	var fctx fasthttp.RequestCtx
+	h.ServeHTTPC(context.Background(), &fctx)
+	if fctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Fatalf("expecting 503, got %d", fctx.Response.StatusCode())
+	}
+
+	cancel()
+	<-waiting
+	close(release)
+	<-done
+
+	// Cancelled requests never get the token, even when it's free
+	h.ServeHTTPC(ctx, &fasthttp.RequestCtx{})
+	select {
+	case <-served:
+		t.Fatalf("expecting the cancelled request not to be served")
+	default:
+	}
+
+	if counters.Accepted() != 1 || counters.Rejected() != 1 || counters.Cancelled() != 2 {
+		t.Fatalf("expecting 1 accepted, 1 rejected and 2 cancelled, got %d, %d and %d",
+			counters.Accepted(), counters.Rejected(), counters.Cancelled())
+	}
+}
+
+func TestThrottleBacklogTimeout(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	DefaultClock = clock
+	defer func() { DefaultClock = SystemClock }()
+
+	release := make(chan struct{})
+	served := make(chan struct{}, 1)
+	h := ThrottleBacklog(1, 1, time.Minute)(chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		served <- struct{}{}
+		<-release
+	}))
+	defer close(release)
+
+	go h.ServeHTTPC(context.Background(), &fasthttp.RequestCtx{})
+	<-served
+
+	var fctx fasthttp.RequestCtx
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTPC(context.Background(), &fctx)
+		close(done)
+	}()
+	for clock.Waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(59 * time.Second)
+	select {
+	case <-done:
+		t.Fatalf("expecting the request to wait for a minute")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	<-done
+	if fctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable || string(fctx.Response.Body()) != errTimedOut {
+		t.Fatalf("expecting a 503 timeout, got %d '%s'", fctx.Response.StatusCode(), fctx.Response.Body())
+	}
+}
@@ -5,24 +5,46 @@ import (
 
 	"github.com/valyala/fasthttp"
 
-	"bitbucket.org/gle/chi"
+	"github.com/hmgle/chi"
 	"golang.org/x/net/context"
 )
 
 // Timeout is a middleware that cancels ctx after a given timeout.
 func Timeout(timeout time.Duration) func(next chi.Handler) chi.Handler {
+	return TimeoutWarn(timeout, 0, nil)
+}
+
+// TimeoutWarn is a Timeout middleware also calling `fn` for requests taking
+// longer than the `threshold` fraction of the timeout, ie. 0.8 for 80%, so
+// latency regressions surface before they turn into 504s:
+//
+//	r.Use(middleware.TimeoutWarn(time.Second, 0.8, func(ctx context.Context, fctx *fasthttp.RequestCtx, elapsed time.Duration) {
+//		log.Printf("slow route %s: %s", chi.RoutePattern(ctx), elapsed)
+//	}))
+//
+// `fn` is called once the handler returned, with its elapsed time. Requests
+// that reached the timeout are reported too, with ctx.Err() set. Time is
+// measured on the DefaultClock.
+func TimeoutWarn(timeout time.Duration, threshold float64, fn func(ctx context.Context, fctx *fasthttp.RequestCtx, elapsed time.Duration)) func(next chi.Handler) chi.Handler {
+	soft := time.Duration(float64(timeout) * threshold)
+	clock := DefaultClock
+
 	return func(next chi.Handler) chi.Handler {
-		fn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
-			ctx, cancel := context.WithTimeout(ctx, timeout)
+		hfn := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			start := clock.Now()
+			ctx, cancel := withClockTimeout(ctx, clock, timeout)
 			defer func() {
 				cancel()
 				if ctx.Err() == context.DeadlineExceeded {
 					fctx.SetStatusCode(fasthttp.StatusGatewayTimeout)
 				}
+				if elapsed := clock.Now().Sub(start); fn != nil && elapsed >= soft {
+					fn(ctx, fctx, elapsed)
+				}
 			}()
 
 			next.ServeHTTPC(ctx, fctx)
 		}
-		return chi.HandlerFunc(fn)
+		return chi.HandlerFunc(hfn)
 	}
 }
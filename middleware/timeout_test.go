@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestTimeoutWarn(t *testing.T) {
+	var warnings []string
+
+	clock := NewManualClock(time.Now())
+	DefaultClock = clock
+	defer func() { DefaultClock = SystemClock }()
+
+	r := chi.NewRouter()
+	r.Use(TimeoutWarn(100*time.Millisecond, 0.2, func(ctx context.Context, fctx *fasthttp.RequestCtx, elapsed time.Duration) {
+		warnings = append(warnings, chi.RouteContext(ctx).RoutePattern())
+	}))
+	r.Get("/fast", func(fctx *fasthttp.RequestCtx) {})
+	r.Get("/slow/:id", func(fctx *fasthttp.RequestCtx) {
+		clock.Advance(30 * time.Millisecond)
+	})
+
+	for _, path := range []string{"/fast", "/slow/1"} {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(path)
+		r.ServeHTTP(&fctx)
+		if fctx.Response.StatusCode() != 200 {
+			t.Fatalf("%s: expecting status 200, got %d", path, fctx.Response.StatusCode())
+		}
+	}
+
+	if len(warnings) != 1 || warnings[0] != "/slow/:id" {
+		t.Fatalf("expecting a warning for /slow/:id, got %v", warnings)
+	}
+}
+
+func TestTimeoutClock(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	DefaultClock = clock
+	defer func() { DefaultClock = SystemClock }()
+
+	r := chi.NewRouter()
+	r.Use(Timeout(time.Minute))
+	r.Get("/wait", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		if deadline, ok := ctx.Deadline(); !ok || deadline.Sub(clock.Now()) != time.Minute {
+			t.Errorf("expecting a deadline in a minute, got %v", deadline)
+		}
+		clock.Advance(time.Minute)
+		<-ctx.Done()
+	})
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/wait")
+	r.ServeHTTP(&fctx)
+	if fctx.Response.StatusCode() != fasthttp.StatusGatewayTimeout {
+		t.Fatalf("expecting status 504, got %d", fctx.Response.StatusCode())
+	}
+}
@@ -2,8 +2,10 @@ package chi
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/valyala/fasthttp"
 
@@ -20,12 +22,17 @@ var _ Router = &Mux{}
 // modular HTTP services with a large set of handlers. It's particularly useful
 // for writing large REST API services that break a handler into many smaller
 // parts composed of middlewares and end handlers.
+//
+// Routes can be registered and removed while the Mux serves requests, as
+// the routing trees are locked during updates. Middlewares, hooks and
+// settings must be set up before serving.
 type Mux struct {
 	// A parent root context for any request that is usually a server context
 	parentCtx context.Context
 
 	// The middleware stack, supporting..
-	// func(http.Handler) http.Handler and func(chi.Handler) chi.Handler
+	// func(chi.Handler) chi.Handler and
+	// func(fasthttp.RequestHandler) fasthttp.RequestHandler
 	middlewares []interface{}
 
 	// The radix trie router
@@ -38,8 +45,20 @@ type Mux struct {
 	// is registered as an inline group inside another mux.
 	inline bool
 
+	// Path prefixed to the route patterns of an inline group, see Prefix
+	prefix string
+
+	// Request conditions of the routes of an inline group, see Header
+	conds routeConds
+
 	// Routing context pool
 	pool sync.Pool
+
+	// Names of middlewares left out of the stack, see DisableMiddleware
+	disabled []string
+
+	// The Mux serving requests once routes are swapped in by Reload
+	live atomic.Value
 }
 
 type methodTyp int
@@ -71,6 +90,55 @@ var methodMap = map[string]methodTyp{
 	"TRACE":   mTRACE,
 }
 
+// maxCustomMethods bounds the custom http methods, whose types follow the
+// standard ones in methodTyp bits.
+const maxCustomMethods = 16
+
+// mEXT is the type of the extension http methods without routes of their
+// own, served by the routes of all methods.
+const mEXT methodTyp = mTRACE << (maxCustomMethods + 1)
+
+var (
+	// Custom http methods by name, replaced on updates so requests read it
+	// without locking
+	customMethods   atomic.Value
+	customMethodsMu sync.Mutex
+)
+
+// methodType returns the type of a standard or custom http method.
+func methodType(method string) (methodTyp, bool) {
+	if mt, ok := methodMap[method]; ok {
+		return mt, true
+	}
+	methods, _ := customMethods.Load().(map[string]methodTyp)
+	mt, ok := methods[method]
+	return mt, ok
+}
+
+// registerMethod returns the type of the http method, registering it as a
+// custom method if it isn't known yet.
+func registerMethod(method string) methodTyp {
+	customMethodsMu.Lock()
+	defer customMethodsMu.Unlock()
+
+	if mt, ok := methodType(method); ok {
+		return mt
+	}
+	methods, _ := customMethods.Load().(map[string]methodTyp)
+	if len(methods) == maxCustomMethods {
+		panic(fmt.Sprintf("chi: too many custom http methods, can't register '%s'", method))
+	}
+
+	mt := mTRACE << uint(len(methods)+1)
+	update := make(map[string]methodTyp, len(methods)+1)
+	for m, t := range methods {
+		update[m] = t
+	}
+	update[method] = mt
+	customMethods.Store(update)
+	return mt
+}
+
 // NewMux returns a new Mux object with an optional parent context.
 func NewMux(parent ...context.Context) *Mux {
 	pctx := context.Background()
@@ -86,71 +154,360 @@ func NewMux(parent ...context.Context) *Mux {
 	return mux
 }
 
-// Use appends a middleware handler to the Mux middleware stack.
+// Use appends a middleware handler to the Mux middleware stack. The
+// middlewares of a Middlewares stack are appended one by one.
 func (mx *Mux) Use(mws ...interface{}) {
 	for _, mw := range mws {
-		mx.middlewares = append(mx.middlewares, assertMiddleware(mw))
+		if stack, ok := mw.(Middlewares); ok {
+			for _, m := range stack {
+				mx.Use(m)
+			}
+			continue
+		}
+		assertMiddleware(mw)
+		if mx.isDisabled(mw) {
+			continue
+		}
+		mx.middlewares = append(mx.middlewares, mw)
+	}
+}
+
+// A MiddlewareEntry is a middleware on a Mux stack, named after its function
+// as `package.Func`, ie. "middleware.Recoverer".
+type MiddlewareEntry struct {
+	Name       string
+	Middleware interface{}
+}
+
+// Middlewares returns the middleware stack of the Mux in the order of Use.
+func (mx *Mux) Middlewares() []MiddlewareEntry {
+	mws := mx.current().middlewares
+	entries := make([]MiddlewareEntry, len(mws))
+	for i, mw := range mws {
+		entries[i] = MiddlewareEntry{Name: middlewareName(mw), Middleware: mw}
+	}
+	return entries
+}
+
+// DisableMiddleware leaves the named middlewares out of the Mux stack, its
+// inline route middlewares, and the routers created by Group, Route and
+// mounted on it. Names match the MiddlewareEntry name, with or without its
+// package, ie. "middleware.Recoverer" or "Recoverer". It must be called
+// before the routes are defined and is meant for tests, see chitest.
+func (mx *Mux) DisableMiddleware(names ...string) {
+	mx.disabled = append(mx.disabled, names...)
+}
+
+// isDisabled reports whether the middleware was disabled on the Mux.
+func (mx *Mux) isDisabled(mw interface{}) bool {
+	if len(mx.disabled) == 0 {
+		return false
+	}
+	name := middlewareName(mw)
+	for _, d := range mx.disabled {
+		if name == d || strings.HasSuffix(name, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// enabled returns the inline middlewares of a handlers chain without the
+// disabled ones, keeping the end handler.
+func (mx *Mux) enabled(handlers []interface{}) []interface{} {
+	if len(mx.disabled) == 0 || len(handlers) < 2 {
+		return handlers
+	}
+	hs := make([]interface{}, 0, len(handlers))
+	for _, h := range handlers[:len(handlers)-1] {
+		if !mx.isDisabled(h) {
+			hs = append(hs, h)
+		}
 	}
+	return append(hs, handlers[len(handlers)-1])
 }
 
 // Handle adds a route for all http methods that match the `pattern`
 // for the `handlers` chain.
-func (mx *Mux) Handle(pattern string, handlers ...interface{}) {
-	mx.handle(mALL, pattern, handlers...)
+func (mx *Mux) Handle(pattern string, handlers ...interface{}) *Route {
+	return mx.handle(mALL, pattern, handlers...)
+}
+
+// Any adds a route for all http methods, unknown ones included, ie.
+// "PURGE" or "PROPFIND" without routes of their own, so a catch-all proxy
+// sees every verb:
+//
+//	r.Any("/upstream/*", proxy)
+//
+// Unlike the routes of Handle, it serves them whatever the policy set with
+// UnknownMethods, when it's the route matching the path, not another route
+// of all methods on a longer pattern.
+func (mx *Mux) Any(pattern string, handlers ...interface{}) *Route {
+	route := mx.handle(mALL, pattern, handlers...)
+	mx.router.mu.Lock()
+	route.entry.any = true
+	mx.router.mu.Unlock()
+	return route
+}
+
+// Method adds a route that matches the http `method` and the `pattern` for
+// the `handlers` chain. The method may be any verb, ie. "PROPFIND", and
+// routes of all methods, like the ones of Handle and Mount, serve the
+// custom verbs too. The verbs without routes of their own are answered as
+// set with UnknownMethods.
+func (mx *Mux) Method(method, pattern string, handlers ...interface{}) *Route {
+	return mx.handle(registerMethod(strings.ToUpper(method)), pattern, handlers...)
+}
+
+// TryHandle adds a route like Method, or like Handle for the "*" method,
+// returning the error of an invalid method, pattern or handlers instead of
+// panicking, for servers registering routes from runtime data, ie. the
+// routes of plugins:
+//
+//	if _, err := r.TryHandle(p.Method, p.Pattern, p.Handler); err != nil {
+//		log.Printf("plugin %s: %v", p.Name, err)
+//	}
+//
+// The routes of the Mux are left as they were on errors.
+func (mx *Mux) TryHandle(method, pattern string, handlers ...interface{}) (route *Route, err error) {
+	defer recoverRegistration(&err)
+	if method == "*" {
+		return mx.Handle(pattern, handlers...), nil
+	}
+	return mx.Method(method, pattern, handlers...), nil
+}
+
+// TryMount mounts the subrouter like Mount, returning the error of an
+// invalid pattern or handlers instead of panicking, see TryHandle.
+func (mx *Mux) TryMount(pattern string, handlers ...interface{}) (err error) {
+	defer recoverRegistration(&err)
+	mx.Mount(pattern, handlers...)
+	return nil
+}
+
+// recoverRegistration returns the panic of a route registration as an
+// error.
+func recoverRegistration(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if e, ok := r.(error); ok {
+		*err = e
+		return
+	}
+	*err = fmt.Errorf("chi: %s", strings.TrimPrefix(fmt.Sprint(r), "chi: "))
 }
 
 // Connect adds a route that matches a CONNECT http method and the `pattern`
 // for the `handlers` chain.
-func (mx *Mux) Connect(pattern string, handlers ...interface{}) {
-	mx.handle(mCONNECT, pattern, handlers...)
+func (mx *Mux) Connect(pattern string, handlers ...interface{}) *Route {
+	return mx.handle(mCONNECT, pattern, handlers...)
 }
 
 // Head adds a route that matches a HEAD http method and the `pattern`
-// for the `handlers` chain.
-func (mx *Mux) Head(pattern string, handlers ...interface{}) {
-	mx.handle(mHEAD, pattern, handlers...)
+// for the `handlers` chain. HEAD requests without a HEAD route are served
+// by the GET route of the path, without the response body.
+func (mx *Mux) Head(pattern string, handlers ...interface{}) *Route {
+	return mx.handle(mHEAD, pattern, handlers...)
 }
 
 // Get adds a route that matches a GET http method and the `pattern`
 // for the `handlers` chain.
-func (mx *Mux) Get(pattern string, handlers ...interface{}) {
-	mx.handle(mGET, pattern, handlers...)
+func (mx *Mux) Get(pattern string, handlers ...interface{}) *Route {
+	return mx.handle(mGET, pattern, handlers...)
 }
 
 // Post adds a route that matches a POST http method and the `pattern`
 // for the `handlers` chain.
-func (mx *Mux) Post(pattern string, handlers ...interface{}) {
-	mx.handle(mPOST, pattern, handlers...)
+func (mx *Mux) Post(pattern string, handlers ...interface{}) *Route {
+	return mx.handle(mPOST, pattern, handlers...)
 }
 
 // Put adds a route that matches a PUT http method and the `pattern`
 // for the `handlers` chain.
-func (mx *Mux) Put(pattern string, handlers ...interface{}) {
-	mx.handle(mPUT, pattern, handlers...)
+func (mx *Mux) Put(pattern string, handlers ...interface{}) *Route {
+	return mx.handle(mPUT, pattern, handlers...)
 }
 
 // Patch adds a route that matches a PATCH http method and the `pattern`
 // for the `handlers` chain.
-func (mx *Mux) Patch(pattern string, handlers ...interface{}) {
-	mx.handle(mPATCH, pattern, handlers...)
+func (mx *Mux) Patch(pattern string, handlers ...interface{}) *Route {
+	return mx.handle(mPATCH, pattern, handlers...)
 }
 
 // Delete adds a route that matches a DELETE http method and the `pattern`
 // for the `handlers` chain.
-func (mx *Mux) Delete(pattern string, handlers ...interface{}) {
-	mx.handle(mDELETE, pattern, handlers...)
+func (mx *Mux) Delete(pattern string, handlers ...interface{}) *Route {
+	return mx.handle(mDELETE, pattern, handlers...)
 }
 
 // Trace adds a route that matches a TRACE http method and the `pattern`
 // for the `handlers` chain.
-func (mx *Mux) Trace(pattern string, handlers ...interface{}) {
-	mx.handle(mTRACE, pattern, handlers...)
+func (mx *Mux) Trace(pattern string, handlers ...interface{}) *Route {
+	return mx.handle(mTRACE, pattern, handlers...)
 }
 
 // Options adds a route that matches a OPTIONS http method and the `pattern`
 // for the `handlers` chain.
-func (mx *Mux) Options(pattern string, handlers ...interface{}) {
-	mx.handle(mOPTIONS, pattern, handlers...)
+func (mx *Mux) Options(pattern string, handlers ...interface{}) *Route {
+	return mx.handle(mOPTIONS, pattern, handlers...)
+}
+
+// Remove unregisters the route of the http method and `pattern`, or of all
+// methods with the "*" method, and reports whether a route was removed.
+// Param names of the pattern don't matter, so `/users/:id` removes the
+// route registered as `/users/:userID`. Routes can be removed while
+// serving requests.
+//
+// The routes registered with request conditions are removed from the
+// inline router of their conditions, ie.
+//
+//	r.Header("X-Api-Version", "2").Remove("GET", "/articles")
+//
+// while removing a route without conditions leaves the ones with
+// conditions of its path in place.
+func (mx *Mux) Remove(method, pattern string) bool {
+	mt := mALL
+	if method == "*" {
+		methods, _ := customMethods.Load().(map[string]methodTyp)
+		for _, t := range methods {
+			mt |= t
+		}
+	} else {
+		var ok bool
+		if mt, ok = methodType(strings.ToUpper(method)); !ok {
+			return false
+		}
+	}
+
+	tr := mx.current().router
+	pattern = tr.syntax.native(pattern)
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.checkSealed()
+
+	return tr.unregister(mt, pattern, mx.conds) != 0
+}
+
+// Match reports the pattern of the route that would serve a request of the
+// http method and path, including the patterns of mounted subrouters, ie.
+// `/admin/users/:id`, along with its URL params, without serving it. Paths
+// are matched with the trailing slash and case policies of the routers.
+func (mx *Mux) Match(method, path string) (pattern string, params map[string]string, ok bool) {
+	mt, ok := methodType(strings.ToUpper(method))
+	if !ok {
+		mt = mEXT
+	}
+
+	rctx := newContext(mx.parentCtx)
+	m := mx.current()
+	for {
+		route, ok, _ := m.router.match(rctx, nil, mt, []byte(path))
+		if !ok {
+			return "", nil, false
+		}
+		rctx.addRoutePattern(route.spelled)
+
+		if route.mount == nil {
+			break
+		}
+		if strings.HasSuffix(route.pattern, "/") {
+			// The mount path with a trailing slash isn't routed
+			return "", nil, false
+		}
+
+		// Route the rest of the path in the subrouter, like Mount does
+		path = "/" + rctx.Params.Del("*")
+		m = route.mount.current()
+	}
+
+	params = make(map[string]string, len(rctx.Params))
+	for _, p := range rctx.Params {
+		params[p.Key] = p.Value
+	}
+	return rctx.RoutePattern(), params, true
+}
+
+// A TrailingSlashPolicy tells a router how to handle a request path that
+// only matches a route once its trailing slash is added or removed.
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashStrict doesn't match `/folders` to `/folders/` or the
+	// other way around, which is the default.
+	TrailingSlashStrict TrailingSlashPolicy = iota
+
+	// TrailingSlashRedirect redirects the client to the matching path, with
+	// a 301 for GET and HEAD requests and a 308 otherwise.
+	TrailingSlashRedirect
+
+	// TrailingSlashMatch serves the request with the matching route.
+	TrailingSlashMatch
+)
+
+// TrailingSlash sets the trailing slash policy of the router. The policy
+// applies to this router's tree only, not to mounted subrouters.
+func (mx *Mux) TrailingSlash(policy TrailingSlashPolicy) {
+	mx.router.trailingSlash = policy
+}
+
+// DecodeParams sets whether the URL params are percent-decoded, which they
+// are by default: the routes match the decoded request path, and requests
+// with a malformed encoding are answered with a 400. Without decoding, the
+// routes match the path as sent, so an encoded slash doesn't split a param
+// value, ie. "a%2Fb" for `/files/:name`. It applies to the root router.
+func (mx *Mux) DecodeParams(decode bool) {
+	mx.router.rawParams = !decode
+}
+
+// UserValueParams sets whether the URL params are also stored as user values
+// of the fasthttp request, see fasthttp.RequestCtx.SetUserValue, for the
+// handlers reading them without the routing context, ie. plain fasthttp
+// request handlers:
+//
+//	r.UserValueParams(true)
+//	r.Get("/users/:id", func(fctx *fasthttp.RequestCtx) {
+//		id := fctx.UserValue("id").(string)
+//	})
+//
+// Like URLParam, a param set by a parent router and a subrouter alike holds
+// the value of the parent. It applies to the router and its subrouters.
+func (mx *Mux) UserValueParams(enable bool) {
+	mx.router.userValueParams = enable
+}
+
+// ParentParamPrefix renames the params of the parent routers shadowed by a
+// param of the subrouter, so the subrouter's own value wins the key and the
+// parent's one remains under `prefix` + key. For a subrouter mounted on
+// `/orgs/:id/teams` with a route `/:id`:
+//
+//	teams.ParentParamPrefix("org.")
+//	// GET /orgs/1/teams/2
+//	URLParam(ctx, "id")     // "2"
+//	URLParam(ctx, "org.id") // "1"
+//
+// Params of the parents with other keys are left as is, ie. `hubID` of a
+// subrouter mounted on `/hubs/:hubID/users` resolves in its handlers either
+// way. An empty prefix, the default, leaves the parent's value on top.
+func (mx *Mux) ParentParamPrefix(prefix string) {
+	mx.router.parentParamPrefix = prefix
+}
+
+// prefixParentParams renames the params of the parent routers, the first
+// `nparent` ones, whose key is also set by the router.
+func (tr *treeRouter) prefixParentParams(rctx *Context, nparent int) {
+	own := rctx.Params[nparent:]
+	for i := range rctx.Params[:nparent] {
+		p := &rctx.Params[i]
+		for _, q := range own {
+			if q.Key == p.Key {
+				p.Key = tr.parentParamPrefix + p.Key
+				break
+			}
+		}
+	}
 }
 
 // NotFound sets a custom http.HandlerFunc for missing routes on the treeRouter.
@@ -158,59 +515,178 @@ func (mx *Mux) NotFound(h HandlerFunc) {
 	mx.router.notFoundHandler = &h
 }
 
+// NotFoundMethod sets a custom handler for the missing routes of the
+// requests of the `method`, ie. to answer missing GET pages with a HTML
+// page and missing API endpoints with JSON, taking over the NotFound
+// handler for them. HEAD requests get the handler of GET unless they have
+// their own. Subrouters mounted afterwards without NotFound handlers of
+// their own get the ones of the router:
+//
+//	r.NotFound(notFoundJSON)
+//	r.NotFoundMethod("GET", notFoundPage)
+func (mx *Mux) NotFoundMethod(method string, h HandlerFunc) {
+	// The handlers may be shared with the routers they were inherited
+	// from, or by, so they're copied rather than set in place
+	handlers := make(map[string]HandlerFunc, len(mx.router.notFoundMethods)+1)
+	for m, mh := range mx.router.notFoundMethods {
+		handlers[m] = mh
+	}
+	handlers[strings.ToUpper(method)] = h
+	mx.router.notFoundMethods = handlers
+}
+
+// NotFoundFile serves the `file`, ie. a static site's 404.html, with a 404
+// status for missing routes and for the missing files of FileServer,
+// instead of the NotFound handler. Subrouters mounted afterwards without a
+// NotFound handler serve it too.
+func (mx *Mux) NotFoundFile(file string) {
+	mx.router.notFoundFile = file
+	mx.NotFound(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		serveNotFoundFile(fctx, file)
+	})
+}
+
+// serveNotFoundFile serves the file with a 404 status.
+func serveNotFoundFile(fctx *fasthttp.RequestCtx, file string) {
+	// Serve the whole file whatever the request conditions
+	fctx.Request.Header.Del("If-Modified-Since")
+	fctx.Request.Header.Del("Range")
+
+	fasthttp.ServeFile(fctx, file)
+	if fctx.Response.StatusCode() == fasthttp.StatusOK {
+		fctx.SetStatusCode(fasthttp.StatusNotFound)
+	}
+}
+
+// MethodNotAllowed sets a custom http.HandlerFunc for paths routed for
+// other http methods only. The Allow header is set to the methods of the
+// path before the handler runs.
+func (mx *Mux) MethodNotAllowed(h HandlerFunc) {
+	mx.router.methodNotAllowedHandler = &h
+}
+
+// CaseInsensitive makes the router match the static segments of route
+// patterns ignoring case, so `/Ping` is served by `/ping`. With `redirect`
+// the client is instead redirected to the path spelled as registered, with
+// a 301 for GET and HEAD requests and a 308 otherwise. The setting applies
+// to this router's tree only, not to mounted subrouters.
+func (mx *Mux) CaseInsensitive(redirect bool) {
+	mx.router.mu.Lock()
+	defer mx.router.mu.Unlock()
+
+	mx.router.caseRedirect = redirect
+	mx.router.caseInsensitive = true
+	for _, t := range mx.router.routes {
+		t.caseInsensitive = true
+	}
+}
+
 // FileServer serves files from the given file system root.
-// The path must end with "/*filepath", files are then served from the local
-// path /defined/root/dir/*filepath.
+// The path must end with a catch-all, ie. "/*filepath" or "/*", files are
+// then served from the local path /defined/root/dir/*filepath.
 // For example if root is "/etc" and *filepath is "passwd", the local file
 // "/etc/passwd" would be served.
 // Internally a http.FileServer is used, therefore http.NotFound is used instead
-// of the Router's NotFound handler.
+// of the Router's NotFound handler, unless a NotFoundFile is set.
 //     router.FileServer("/src/*filepath", "/var/www")
 func (mx *Mux) FileServer(path, root string) {
-	if len(path) < 10 || path[len(path)-10:] != "/*filepath" {
-		panic("path must end with /*filepath in path '" + path + "'")
-	}
-	prefix := path[:len(path)-10]
-
-	fileHandler := fasthttp.FSHandler(root, strings.Count(prefix, "/"))
-
-	mx.Get(path, func(fctx *fasthttp.RequestCtx) {
-		fileHandler(fctx)
-	})
+	mx.FileServerWith(path, root, FileServerOptions{})
 }
 
 // handle creates a chi.Handler from a chain of middlewares and an end handler,
 // and then registers the route in the router.
-func (mx *Mux) handle(method methodTyp, pattern string, handlers ...interface{}) {
+func (mx *Mux) handle(method methodTyp, pattern string, handlers ...interface{}) *Route {
+	return mx.handleMount(method, pattern, nil, handlers...)
+}
+
+// handleMount registers a route like handle, recording the subrouter
+// mounted along the route if any.
+func (mx *Mux) handleMount(method methodTyp, pattern string, mount *Mux, handlers ...interface{}) *Route {
 	if len(pattern) == 0 || pattern[0] != '/' {
 		panic(fmt.Sprintf("pattern must begin with '/' in '%s'", pattern))
 	}
+	if mx.prefix != "" {
+		if pattern == "/" {
+			pattern = mx.prefix
+		} else {
+			pattern = mx.prefix + pattern
+		}
+	}
+	spelled := pattern
+	pattern = mx.router.syntax.native(pattern)
+	if n := checkParamNames(pattern); mx.router.maxParams > 0 && n > mx.router.maxParams {
+		panic(fmt.Sprintf("chi: pattern '%s' has %d params, more than the max of %d", pattern, n, mx.router.maxParams))
+	}
 
 	// Build the single mux handler that is a chain of the middleware stack, as
 	// defined by calls to Use(), and the tree router (mux) itself. After this point,
 	// no other middlewares can be registered on this mux's stack. But you can still
 	// use inline middlewares via Group()'s and other routes that only execute after
 	// a matched pattern on the treeRouter.
-	if !mx.inline && mx.handler == nil {
-		mx.handler = chain(mx.middlewares, mx.router)
-	}
+	mx.buildHandler()
 
 	// Build endpoint handler with inline middlewares for the route
+	handlers, opts := splitRouteOptions(handlers)
+	handlers = mx.enabled(handlers)
+	middlewares := len(handlers) - 1
 	var endpoint Handler
 	if mx.inline {
-		mx.handler = mx.router
 		endpoint = chain(mx.middlewares, handlers...)
+		middlewares += len(mx.middlewares)
 	} else {
 		endpoint = chain([]interface{}{}, handlers...)
 	}
 
-	// Set the route for the respective HTTP methods
-	for _, mt := range methodMap {
-		m := method & mt
-		if m > 0 {
-			mx.router.routes[m].Insert(pattern, endpoint)
+	route := mx.router.add(method, pattern, mx.conds, opts.wrap(endpoint), middlewares, mount)
+	if opts.timeout > 0 || spelled != pattern {
+		mx.router.mu.Lock()
+		route.entry.timeout = opts.timeout
+		if spelled != pattern {
+			route.entry.spelled = spelled
 		}
+		mx.router.mu.Unlock()
+	}
+	if opts.streamTerminal != nil {
+		route.Meta(streamTerminalKey, opts.streamTerminal)
+	}
+	mx.router.hooks.routeAdded(method, pattern)
+	return route
+}
+
+// buildHandler builds the mux handler once, chaining the middleware stack
+// and the tree router, or the tree router alone for inline-Muxes whose
+// middlewares are chained by route. It's built under the router lock, as
+// requests may be served meanwhile, see treeRouter.matchHost.
+func (mx *Mux) buildHandler() {
+	mx.router.mu.Lock()
+	defer mx.router.mu.Unlock()
+	if mx.handler != nil {
+		return
+	}
+	if mx.inline {
+		mx.handler = mx.router
+	} else {
+		mx.handler = chain(mx.middlewares, mx.router)
+	}
+}
+
+// With returns an inline-Mux adding the `middlewares` to the inline ones of
+// the Mux, if any, for one-off middleware chains without a Group, ie.
+//
+//	r.With(paginate).Get("/articles", listArticles)
+func (mx *Mux) With(middlewares ...interface{}) Router {
+	// Similarly as in handle(), we must build the mux handler once further
+	// middleware registration isn't allowed for this stack, like now.
+	if !mx.inline {
+		mx.buildHandler()
 	}
+
+	w := &Mux{inline: true, router: mx.router, handler: nil, disabled: mx.disabled, prefix: mx.prefix, conds: mx.conds}
+	if mx.inline {
+		w.middlewares = append([]interface{}(nil), mx.middlewares...)
+	}
+	w.Use(middlewares...)
+	return w
 }
 
 // Group creates a new inline-Mux with a fresh middleware stack. It's useful
@@ -219,24 +695,56 @@ func (mx *Mux) handle(method methodTyp, pattern string, handlers ...interface{})
 func (mx *Mux) Group(fn func(r Router)) Router {
 	// Similarly as in handle(), we must build the mux handler once further
 	// middleware registration isn't allowed for this stack, like now.
-	if !mx.inline && mx.handler == nil {
-		mx.handler = chain(mx.middlewares, mx.router)
+	if !mx.inline {
+		mx.buildHandler()
 	}
 
 	// Make a new inline mux and run the router functions over it.
-	g := &Mux{inline: true, router: mx.router, handler: nil}
+	g := &Mux{inline: true, router: mx.router, handler: nil, disabled: mx.disabled, prefix: mx.prefix, conds: mx.conds}
 	if fn != nil {
 		fn(g)
 	}
 	return g
 }
 
+// Prefix creates an inline-Mux like With, whose routes are registered with
+// the `prefix` on the same routing tree, ie. `/` and `/users` are routed
+// as `/v1` and `/v1/users` in:
+//
+//	r.Prefix("/v1", func(r chi.Router) {
+//		r.Use(apiVersion)
+//		r.Get("/", index)
+//		r.Get("/users", listUsers)
+//	})
+//
+// Unlike Route, no subrouter is mounted, so the routes are matched in a
+// single tree lookup.
+func (mx *Mux) Prefix(prefix string, fn func(r Router)) Router {
+	if len(prefix) == 0 || prefix[0] != '/' {
+		panic(fmt.Sprintf("prefix must begin with '/' in '%s'", prefix))
+	}
+
+	p := mx.With().(*Mux)
+	p.prefix += strings.TrimRight(prefix, "/")
+	if fn != nil {
+		fn(p)
+	}
+	return p
+}
+
 // Route creates a new Mux with a fresh middleware stack and mounts it
 // along the `pattern`. This is very simiular to the Group, but attaches
 // the group along a new routing path. See _examples/ for example usage.
-func (mx *Mux) Route(pattern string, fn func(r Router)) Router {
+// The `middlewares` run in front of the subrouter and its own stack, for
+// its routes only, without a Group around them:
+//
+//	r.Route("/admin", adminRoutes, adminOnly, audit)
+func (mx *Mux) Route(pattern string, fn func(r Router), middlewares ...interface{}) Router {
 	subRouter := NewRouter()
-	mx.Mount(pattern, subRouter)
+	subRouter.disabled = mx.disabled
+	subRouter.router.syntax = mx.router.syntax
+	subRouter.router.maxParams = mx.router.maxParams
+	mx.Mount(pattern, append(append([]interface{}{}, middlewares...), subRouter)...)
 	if fn != nil {
 		fn(subRouter)
 	}
@@ -245,16 +753,30 @@ func (mx *Mux) Route(pattern string, fn func(r Router)) Router {
 
 // Mount attaches another mux as a subrouter along a routing path. It's very useful
 // to split up a large API as many independent routers and compose them as a single
-// service using Mount. See _examples/ for example usage.
+// service using Mount. See _examples/ for example usage. Inline middlewares
+// may come before the subrouter, like for the routing methods, ie.
+// `r.Mount("/admin", adminOnly, audit, adminRouter)`.
 func (mx *Mux) Mount(path string, handlers ...interface{}) {
 	// Build chain with any inline middlewares and endpoint handler for the subrouter
+	handlers = mx.enabled(handlers)
 	h := chain([]interface{}{}, handlers...)
 
-	// Assign sub-Router's with the parent not found handler if not specified.
+	// Assign sub-Router's with the parent not found and method not allowed
+	// handlers if not specified.
 	for _, hh := range handlers {
 		if sr, ok := hh.(*Mux); ok {
 			if sr.router.notFoundHandler == nil && mx.router.notFoundHandler != nil {
 				sr.NotFound(*mx.router.notFoundHandler)
+				sr.router.notFoundFile = mx.router.notFoundFile
+			}
+			if sr.router.notFoundMethods == nil {
+				sr.router.notFoundMethods = mx.router.notFoundMethods
+			}
+			if sr.router.methodNotAllowedHandler == nil && mx.router.methodNotAllowedHandler != nil {
+				sr.MethodNotAllowed(*mx.router.methodNotAllowedHandler)
+			}
+			if len(mx.disabled) > 0 {
+				sr.inheritDisabled(mx.disabled)
 			}
 		}
 	}
@@ -262,16 +784,93 @@ func (mx *Mux) Mount(path string, handlers ...interface{}) {
 	// Wrap the sub-router in a handlerFunc to scope the request path for routing.
 	subHandler := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
 		rctx := RouteContext(ctx)
-		rctx.RoutePath = "/" + rctx.Params.Del("*")
+		path := rctx.RoutePath
+		if path == "" {
+			path = string(fctx.Path())
+		}
+		rest := rctx.Params.Del("*")
+		if strings.HasSuffix(path, rest) {
+			rctx.mountPoint += strings.TrimSuffix(path[:len(path)-len(rest)], "/")
+		}
+		rctx.RoutePath = "/" + rest
 		h.ServeHTTPC(ctx, fctx)
 	})
 
+	// Record the subrouter on the mount routes to list its routes
+	var sr *Mux
+	if len(handlers) > 0 {
+		sr, _ = handlers[len(handlers)-1].(*Mux)
+	}
+
 	if path == "" || path[len(path)-1] != '/' {
-		mx.Handle(path, subHandler)
-		mx.Handle(path+"/", mx.router.NotFoundHandlerFn())
+		mx.handleMount(mALL, path, sr, subHandler)
+		mx.handleMount(mALL, path+"/", sr, mx.router.NotFoundHandlerFn())
 		path += "/"
 	}
-	mx.Handle(path+"*", subHandler)
+	mx.handleMount(mALL, path+"*", sr, subHandler)
+}
+
+// Reload builds a new set of routes with `fn` and atomically swaps it in
+// place of the routes served by the Mux, so routes can be reconfigured
+// while serving requests. The new routes get the middleware stack, the
+// handlers, hooks and settings of the Mux, and `fn` may add middlewares
+// of its own. The routes served so far are kept if `fn` panics.
+//
+// Once reloaded, routes are changed with further calls to Reload, as routes
+// defined on the Mux itself are no longer served.
+func (mx *Mux) Reload(fn func(r Router)) {
+	mx.router.checkSealed()
+
+	nm := NewMux(mx.parentCtx)
+	nm.middlewares = append([]interface{}(nil), mx.middlewares...)
+	nm.disabled = mx.disabled
+
+	tr := mx.router
+	nm.router.notFoundHandler = tr.notFoundHandler
+	nm.router.notFoundMethods = tr.notFoundMethods
+	nm.router.notFoundFile = tr.notFoundFile
+	nm.router.methodNotAllowedHandler = tr.methodNotAllowedHandler
+	nm.router.errorRenderer = tr.errorRenderer
+	nm.router.trailingSlash = tr.trailingSlash
+	nm.router.rawParams = tr.rawParams
+	nm.router.userValueParams = tr.userValueParams
+	nm.router.traceHeader = tr.traceHeader
+	nm.router.parentParamPrefix = tr.parentParamPrefix
+	nm.router.syntax = tr.syntax
+	nm.router.maxParams = tr.maxParams
+	nm.router.unknownMethods = tr.unknownMethods
+	nm.router.hooks = tr.hooks
+	if tr.caseInsensitive {
+		nm.CaseInsensitive(tr.caseRedirect)
+	}
+	nm.router.caseRedirect = tr.caseRedirect
+
+	fn(nm)
+	nm.buildHandler()
+	mx.live.Store(nm)
+}
+
+// current returns the Mux serving requests, either the Mux itself or the
+// one swapped in by Reload.
+func (mx *Mux) current() *Mux {
+	if live, _ := mx.live.Load().(*Mux); live != nil {
+		return live
+	}
+	return mx
+}
+
+// inheritDisabled disables middlewares on a mounted subrouter whose stack
+// may already be defined, rebuilding its middleware chain.
+func (mx *Mux) inheritDisabled(names []string) {
+	mx.DisableMiddleware(names...)
+
+	mws := mx.middlewares
+	mx.middlewares = nil
+	mx.Use(mws...)
+
+	if !mx.inline && mx.handler != nil {
+		mx.handler = chain(mx.middlewares, mx.router)
+	}
 }
 
 // ServeHTTP is the single method of the http.Handler interface that makes
@@ -285,19 +884,103 @@ func (mx *Mux) ServeHTTP(fctx *fasthttp.RequestCtx) {
 }
 
 // ServeHTTPC is chi's Handler method that adds a context.Context argument to the
-// standard ServeHTTP handler function.
+// standard ServeHTTP handler function. A Mux served with a context other than
+// a routing one, ie. by a framework passing its own request context, wraps it
+// in a routing context taken from the pool of ServeHTTP.
 func (mx *Mux) ServeHTTPC(ctx context.Context, fctx *fasthttp.RequestCtx) {
+	if RouteContext(ctx) == nil {
+		rctx := mx.pool.Get().(*Context)
+		base := rctx.Context
+		rctx.Context = ctx
+		mx.ServeHTTPC(rctx, fctx)
+		rctx.Context = base
+		rctx.reset()
+		mx.pool.Put(rctx)
+		return
+	}
+	if live, _ := mx.live.Load().(*Mux); live != nil {
+		live.ServeHTTPC(ctx, fctx)
+		return
+	}
+	if mx.router.hooks.serving() {
+		mx.serveHooked(ctx, fctx)
+		return
+	}
 	mx.handler.ServeHTTPC(ctx, fctx)
 }
 
 // A treeRouter manages a radix trie prefix-router for each HTTP method and passes
 // each request via its chi.Handler method.
 type treeRouter struct {
-	// Routing tree by method type
+	// Routing tree by method type, and of the routes of all methods under
+	// mALL, serving the custom methods
 	routes map[methodTyp]*tree
 
 	// Custom route not found handler
 	notFoundHandler *HandlerFunc
+
+	// Custom route not found handlers by method, see Mux.NotFoundMethod
+	notFoundMethods map[string]HandlerFunc
+
+	// File served by the NotFound handler set with NotFoundFile
+	notFoundFile string
+
+	// Custom method not allowed handler
+	methodNotAllowedHandler *HandlerFunc
+
+	// Custom renderer of the errors raised by the fasthttp server
+	errorRenderer func(fctx *fasthttp.RequestCtx, status int, err error)
+
+	// Match paths case-insensitively, and redirect the matches to the
+	// canonical path
+	caseInsensitive bool
+	caseRedirect    bool
+
+	// Handling of paths matching with their trailing slash toggled
+	trailingSlash TrailingSlashPolicy
+
+	// Match the request path as sent, leaving the params percent-encoded
+	rawParams bool
+
+	// Store the params as user values of the request too
+	userValueParams bool
+
+	// Request header asking for the routing trace, see Mux.TraceHeader
+	traceHeader string
+
+	// Max number of named params of the route patterns, see WithMaxParams
+	maxParams int
+
+	// Handling of the requests of unknown methods, see Mux.UnknownMethods
+	unknownMethods UnknownMethodPolicy
+
+	// Prefix renaming the parent params shadowed by the params of the
+	// router, see Mux.ParentParamPrefix
+	parentParamPrefix string
+
+	// Lifecycle hooks of the Mux
+	hooks hooks
+
+	// Param syntax of the route patterns
+	syntax PatternSyntax
+
+	// Guards the trees and registered routes, so routes can be added and
+	// removed while serving requests
+	mu sync.RWMutex
+
+	// Registered routes, by method and normalized pattern
+	sites   map[methodTyp]map[string]*routeEntry
+	entries []*routeEntry
+
+	// Routes named with Route.Name
+	names map[string]*routeEntry
+
+	// Routers of the hosts added with Host
+	hosts []*hostRoute
+
+	// Set once built by a Builder or frozen, forbidding route changes and
+	// reading the trees without locking
+	sealed bool
 }
 
 // newTreeRouter creates a new treeRouter object and initializes the trees for
@@ -310,21 +993,91 @@ func newTreeRouter() *treeRouter {
 	for _, v := range methodMap {
 		tr.routes[v] = &tree{root: &node{}}
 	}
+	tr.routes[mALL] = &tree{root: &node{}}
 	return tr
 }
 
-// NotFoundHandlerFn returns the HandlerFunc setup on the tree.
-func (tr treeRouter) NotFoundHandlerFn() HandlerFunc {
+// tree returns the routing tree of the method, creating the trees of custom
+// methods on their first route.
+func (tr *treeRouter) tree(method methodTyp) *tree {
+	t := tr.routes[method]
+	if t == nil {
+		t = &tree{root: &node{}, caseInsensitive: tr.caseInsensitive}
+		tr.routes[method] = t
+	}
+	return t
+}
+
+// NotFoundHandlerFn returns the HandlerFunc setup on the tree, or the one
+// of the request method, flagging the routing context for the OnNotFound
+// hooks.
+func (tr *treeRouter) NotFoundHandlerFn() HandlerFunc {
+	h := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.NotFound()
+	})
 	if tr.notFoundHandler != nil {
-		return *tr.notFoundHandler
+		h = *tr.notFoundHandler
 	}
 	return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
-		fctx.NotFound()
+		if rctx := RouteContext(ctx); rctx != nil {
+			rctx.notFound = true
+		}
+		if mh := tr.notFoundMethod(fctx.Method()); mh != nil {
+			mh(ctx, fctx)
+			return
+		}
+		h(ctx, fctx)
 	})
 }
 
+// notFoundMethod returns the NotFound handler of the method, if any.
+func (tr *treeRouter) notFoundMethod(method []byte) HandlerFunc {
+	if tr.notFoundMethods == nil {
+		return nil
+	}
+	if h, ok := tr.notFoundMethods[string(method)]; ok {
+		return h
+	}
+	if string(method) == "HEAD" {
+		return tr.notFoundMethods["GET"]
+	}
+	return nil
+}
+
+// MethodNotAllowedHandlerFn returns the method not allowed HandlerFunc
+// setup on the tree.
+func (tr *treeRouter) MethodNotAllowedHandlerFn() HandlerFunc {
+	if tr.methodNotAllowedHandler != nil {
+		return *tr.methodNotAllowedHandler
+	}
+	return HandlerFunc(methodNotAllowedHandler)
+}
+
+// allowedMethods returns the sorted methods with a route for the path.
+func (tr *treeRouter) allowedMethods(rctx *Context, path []byte) []string {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	// The trees are looked up in no particular order, keep them out of the
+	// trace
+	tracing := rctx.tracing
+	rctx.tracing = false
+
+	var methods []string
+	n := len(rctx.Params)
+	for mt := range tr.routes {
+		if mt != mALL && tr.findRoute(rctx, mt, path) != nil {
+			methods = append(methods, methodName(mt))
+		}
+		rctx.Params = rctx.Params[:n]
+	}
+	rctx.tracing = tracing
+	sort.Strings(methods)
+	return methods
+}
+
 // ServeHTTPC is the main routing method for each request.
-func (tr treeRouter) ServeHTTPC(ctx context.Context, fctx *fasthttp.RequestCtx) {
+func (tr *treeRouter) ServeHTTPC(ctx context.Context, fctx *fasthttp.RequestCtx) {
 	// Grab the root context object
 	rctx, _ := ctx.(*Context)
 	if rctx == nil {
@@ -334,27 +1087,236 @@ func (tr treeRouter) ServeHTTPC(ctx context.Context, fctx *fasthttp.RequestCtx)
 		}
 	}
 
-	// The request path
-	routePath := rctx.RoutePath
-	if routePath == "" {
-		routePath = string(fctx.Path())
+	if tr.errorRenderer != nil {
+		rctx.errorRenderer = tr.errorRenderer
+	}
+	if tr.userValueParams {
+		rctx.userValueParams = true
+	}
+	if tr.startTrace(rctx, fctx) {
+		defer rctx.writeTrace(fctx, tr.traceHeader)
+	}
+	// Params set by the parent routers
+	nparent := len(rctx.Params)
+
+	// The request path, matched as bytes
+	var routePath []byte
+	if rctx.RoutePath != "" {
+		rctx.pathBuf = append(rctx.pathBuf[:0], rctx.RoutePath...)
+		routePath = rctx.pathBuf
+	} else {
+		switch raw := fctx.URI().PathOriginal(); {
+		case tr.rawParams && len(raw) > 0:
+			routePath = raw
+		case !tr.rawParams && !validPathEncoding(raw):
+			rctx.renderError(fctx, fasthttp.StatusBadRequest, errMalformedPath)
+			return
+		default:
+			routePath = fctx.Path()
+		}
+	}
+	if rctx.tracing {
+		// Steps are only formatted for the traced requests
+		if rctx.RoutePath != "" {
+			rctx.tracef("mount %s routes %s", rctx.routePattern, routePath)
+		} else {
+			rctx.tracef("route %s %s", fctx.Method(), routePath)
+		}
+	}
+
+	// Hosts with their own routes come first
+	if hr := tr.matchHost(rctx, fctx); hr != nil {
+		if rctx.tracing {
+			rctx.tracef("host %s", fctx.Host())
+		}
+		hr.ServeHTTPC(ctx, fctx)
+		return
 	}
 
-	// Check if method is supported by chi
+	// Extension methods without routes of their own, ie. "PURGE" or
+	// "REPORT", are served by the routes of all methods, like the ones of
+	// Handle, Any and Mount, or by the ones of Any only, see
+	// UnknownMethodPolicy
 	method, ok := methodMap[string(fctx.Method())]
 	if !ok {
-		methodNotAllowedHandler(ctx, fctx)
-		return
+		if method, ok = methodType(string(fctx.Method())); !ok {
+			method = mEXT
+		}
 	}
 
 	// Find the handler in the router
-	cxh := tr.routes[method].Find(rctx, routePath)
+	route, ok, path := tr.match(rctx, fctx, method, routePath)
+
+	if !ok && method == mEXT && tr.unknownMethods != UnknownMethodDispatch {
+		tr.rejectUnknownMethod(ctx, rctx, fctx, routePath)
+		return
+	}
 
-	if cxh == nil {
+	if ok && route.negotiated {
+		fctx.Response.Header.Add("Vary", "Accept")
+		if route.handler == nil {
+			rctx.tracef("not acceptable")
+			rctx.renderError(fctx, fasthttp.StatusNotAcceptable, errNotAcceptable)
+			return
+		}
+	}
+	if ok && route.handler == nil {
+		// The request matches none of the conditions of the routes of the
+		// path
+		if rctx.tracing {
+			rctx.tracef("not found, no route of %s meets its conditions", route.pattern)
+		}
 		tr.NotFoundHandlerFn().ServeHTTPC(ctx, fctx)
 		return
 	}
+	if !ok {
+		if methods := tr.allowedMethods(rctx, routePath); len(methods) > 0 {
+			allow := strings.Join(methods, ", ")
+			if rctx.tracing {
+				rctx.tracef("method not allowed, allow %s", allow)
+			}
+			fctx.Response.Header.Set("Allow", allow)
+			tr.MethodNotAllowedHandlerFn().ServeHTTPC(ctx, fctx)
+			return
+		}
+		rctx.tracef("not found")
+		tr.NotFoundHandlerFn().ServeHTTPC(ctx, fctx)
+		return
+	}
+
+	if len(path) != len(routePath) {
+		// Matched with the trailing slash toggled
+		if tr.trailingSlash == TrailingSlashRedirect {
+			if rctx.tracing {
+				rctx.tracef("redirect to %s", path)
+			}
+			redirectRoutePath(rctx, fctx, string(routePath), string(path), tr.rawParams)
+			return
+		}
+		routePath = path
+	}
+
+	if tr.caseRedirect {
+		if cpath := canonicalPath(route.pattern, string(routePath)); cpath != string(routePath) {
+			if rctx.tracing {
+				rctx.tracef("redirect to %s", cpath)
+			}
+			redirectRoutePath(rctx, fctx, string(routePath), cpath, tr.rawParams)
+			return
+		}
+	}
+
+	rctx.addRoutePattern(route.spelled)
+	rctx.router = tr
+	if tr.parentParamPrefix != "" {
+		tr.prefixParentParams(rctx, nparent)
+	}
+	if rctx.tracing {
+		rctx.tracef("serve %s", rctx.routePattern)
+	}
+
+	// HEAD requests never get a body, even when served by a GET route
+	if method == mHEAD {
+		fctx.Response.SkipBody = true
+	}
+
+	if rctx.userValueParams {
+		// Backwards, so the params of parent routers come out on top
+		for i := len(rctx.Params) - 1; i >= 0; i-- {
+			fctx.SetUserValue(rctx.Params[i].Key, rctx.Params[i].Value)
+		}
+	}
 
 	// Serve it
-	cxh.ServeHTTPC(ctx, fctx)
+	fctx.SetUserValue(requestCtxKey, ctx)
+	route.handler.ServeHTTPC(ctx, fctx)
+}
+
+// A routeMatch is the route matched for a request, copied out of the
+// trees while they're locked, see treeRouter.match.
+type routeMatch struct {
+	// Pattern of the leaf node in the colon syntax, and as registered
+	pattern, spelled string
+
+	// Handler serving the request, see node.handlerFor, nil when the
+	// request meets none of the conditions of the routes of the path
+	handler Handler
+
+	// Subrouter mounted along the route, if any
+	mount *Mux
+
+	// Whether routes of the path are negotiated with the Accept header,
+	// see Mux.Produces
+	negotiated bool
+}
+
+// match returns the route for the method and path, reporting whether one
+// matched, and the path it matched, which is the path with its trailing
+// slash toggled when the policy allows it. The trees are read locked
+// during the lookup only, so handlers may register and remove routes,
+// unless they're sealed: what's needed of the route is copied meanwhile.
+func (tr *treeRouter) match(rctx *Context, fctx *fasthttp.RequestCtx, method methodTyp, routePath []byte) (routeMatch, bool, []byte) {
+	if !tr.sealed {
+		tr.mu.RLock()
+		defer tr.mu.RUnlock()
+	}
+
+	nparams := len(rctx.Params)
+	path := routePath
+	route := tr.findRoute(rctx, method, path)
+
+	if route == nil && tr.trailingSlash != TrailingSlashStrict && len(path) > 1 {
+		// Try again with the trailing slash toggled
+		if path[len(path)-1] == '/' {
+			path = path[:len(path)-1]
+		} else {
+			path = append(path[:len(path):len(path)], '/')
+		}
+		if rctx.tracing {
+			rctx.tracef("retry %s", path)
+		}
+		route = tr.findRoute(rctx, method, path)
+	}
+
+	if route == nil {
+		return routeMatch{}, false, routePath
+	}
+	handler, e := route.handlerFor(rctx, fctx)
+	if method == mEXT && tr.unknownMethods != UnknownMethodDispatch && (e == nil || !e.any) {
+		// The policy leaves the unknown methods to the routes of Any
+		rctx.Params = rctx.Params[:nparams]
+		return routeMatch{}, false, routePath
+	}
+
+	m := routeMatch{
+		pattern:    route.pattern,
+		spelled:    route.pattern,
+		handler:    handler,
+		negotiated: route.negotiated(),
+	}
+	if e != nil {
+		m.spelled, m.mount = e.routePattern(), e.mount
+		if e.meta != nil {
+			rctx.metas = append(rctx.metas, e.meta)
+		}
+	}
+	return m, true, path
+}
+
+// findRoute returns the route for the method and path, falling back to the
+// GET route for HEAD requests without a HEAD route of their own, and to
+// the routes of all methods for custom and unknown methods.
+func (tr *treeRouter) findRoute(rctx *Context, method methodTyp, path []byte) *node {
+	var route *node
+	if tr.routes[method] != nil {
+		route = tr.findTraced(rctx, method, path)
+	}
+	switch {
+	case route != nil:
+	case method == mHEAD:
+		route = tr.findTraced(rctx, mGET, path)
+	case method > mTRACE:
+		route = tr.findTraced(rctx, mALL, path)
+	}
+	return route
 }
@@ -3,10 +3,16 @@ package chi
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -217,7 +223,9 @@ func TestMux(t *testing.T) {
 	m.Head("/ping", headPing)
 	m.Post("/ping", createPing)
 	m.Get("/ping/:id", pingOne)
-	m.Get("/ping/:id", pingOne) // should overwrite.. and just be 1
+	if recv := catchPanic(func() { m.Get("/ping/:key", pingOne) }); recv == nil {
+		t.Fatalf("expecting a panic for the duplicate route")
+	}
 	m.Get("/ping/:id/woop", pingWoop)
 	m.Handle("/admin/*", catchAll)
 	// m.Post("/admin/*", catchAll)
@@ -778,11 +786,6 @@ func TestMuxSubroutes(t *testing.T) {
 		r.Mount("/", sr3)
 	})
 
-	// TODO: support overriding the index method on a mount like:
-	// r.Get("/users", UIndex)
-	// r.Mount("/users", U) // assuming U router doesn't implement index route
-	// .. currently for this to work, the index route must be defined separately
-
 	// log.Println("")
 	// log.Println("~~router:")
 	// debugPrintTree(0, 0, r.router[mGET].root, 0)
@@ -838,96 +841,2141 @@ func TestMuxSubroutes(t *testing.T) {
 	}
 }
 
-func catchPanic(testFunc func()) (recv interface{}) {
-	defer func() {
-		recv = recover()
-	}()
+func TestMuxMountIndex(t *testing.T) {
+	users := NewRouter()
+	users.Get("/:id", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("user " + URLParam(ctx, "id")))
+	})
+	users.Post("/", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("create user"))
+	})
+	articles := NewRouter()
+	articles.Get("/", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("articles"))
+	})
 
-	testFunc()
-	return
+	r := NewRouter()
+	r.Get("/users", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("users index"))
+	})
+	r.Mount("/users", users)
+	r.Mount("/articles", articles)
+	r.Get("/articles", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("articles index"))
+	})
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	tests := []struct {
+		method, path string
+		expected     string
+	}{
+		{"GET", "/users", "users index"},
+		{"POST", "/users", "create user"},
+		{"GET", "/users/7", "user 7"},
+		{"GET", "/articles", "articles index"},
+	}
+	for _, tt := range tests {
+		if resp := testRequest(t, ts, tt.method, tt.path); resp != tt.expected {
+			t.Fatalf("%s %s: got '%s'", tt.method, tt.path, resp)
+		}
+	}
+
+	if recv := catchPanic(func() { r.Get("/users", func(fctx *fasthttp.RequestCtx) {}) }); recv == nil {
+		t.Fatalf("expecting a panic for the duplicate index route")
+	}
 }
 
-func TestMuxFileServer(t *testing.T) {
+func TestMuxTypedParams(t *testing.T) {
 	r := NewRouter()
-
-	recv := catchPanic(func() {
-		r.FileServer("/noFilepath", os.TempDir())
+	r.Get("/orders/:id|int", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		id, err := URLParamInt(ctx, "id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fctx.Write([]byte(fmt.Sprintf("order %d", id*2)))
 	})
-	if recv == nil {
-		t.Fatal("registering path not ending with '*filepath' did not panic")
+
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
 	}
-	body := []byte("fake ico")
-	ioutil.WriteFile(os.TempDir()+"/favicon.ico", body, 0644)
 
-	r.FileServer("/*filepath", os.TempDir())
+	if resp := testRequest(t, ts, "GET", "/orders/21"); resp != "order 42" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/orders/abc"); resp != "404 Page not found" {
+		t.Fatalf("got '%s'", resp)
+	}
+}
+
+func TestMuxTiming(t *testing.T) {
+	var spans []Span
+	r := NewRouter()
+	r.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			next.ServeHTTPC(ctx, fctx)
+			spans = RouteContext(ctx).Spans()
+		})
+	})
+	r.Get("/report", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		stop := Timing(ctx, "db")
+		time.Sleep(2 * time.Millisecond)
+		stop()
+		Timing(ctx, "render")()
+		fctx.Write([]byte("report"))
+	})
 
 	ts := &fasthttp.Server{
 		Handler: r.ServeHTTP,
 	}
 
-	rw := &readWriter{}
-	ch := make(chan error)
+	if resp := testRequest(t, ts, "GET", "/report"); resp != "report" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if len(spans) != 2 || spans[0].Name != "db" || spans[1].Name != "render" {
+		t.Fatalf("unexpected spans %v", spans)
+	}
+	if spans[0].Duration < 2*time.Millisecond {
+		t.Fatalf("expecting db span of at least 2ms, got %v", spans[0].Duration)
+	}
 
-	rw.r.WriteString(string("GET /favicon.ico HTTP/1.1\r\n\r\n"))
-	go func() {
-		ch <- ts.ServeConn(rw)
-	}()
-	select {
-	case err := <-ch:
-		if err != nil {
-			t.Fatalf("return error %s", err)
-		}
-	case <-time.After(500 * time.Millisecond):
-		t.Fatalf("timeout")
+	// Spans don't leak into the next request on a reused routing context
+	testRequest(t, ts, "GET", "/nothing")
+	if len(spans) != 0 {
+		t.Fatalf("expecting no spans, got %v", spans)
 	}
 
-	br := bufio.NewReader(&rw.w)
+	// Timing without a routing context is a no-op
+	Timing(context.Background(), "noop")()
+}
+
+func TestMuxCaseInsensitive(t *testing.T) {
+	r := NewRouter()
+	r.CaseInsensitive(false)
+	r.Get("/ping", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("pong"))
+	})
+	r.Get("/Users/:userID/Profile", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("profile " + URLParam(ctx, "userID")))
+	})
+
+	sr := NewRouter()
+	sr.CaseInsensitive(true)
+	sr.Get("/Docs/*", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("docs " + URLParam(ctx, "*")))
+	})
+	r.Mount("/help", sr)
+
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	if resp := testRequest(t, ts, "GET", "/PING"); resp != "pong" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/users/PeTeR/profile"); resp != "profile PeTeR" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	// Redirect to the canonical case, keeping param values
+	rw := &readWriter{}
+	rw.r.WriteString("GET /help/docs/Intro.md?v=1 HTTP/1.1\r\n\r\n")
+	if err := ts.ServeConn(rw); err != nil {
+		t.Fatal(err)
+	}
 	var resp fasthttp.Response
-	if err := resp.Read(br); err != nil {
-		t.Fatalf("Unexpected error when reading response: %s", err)
+	if err := resp.Read(bufio.NewReader(&rw.w)); err != nil {
+		t.Fatal(err)
 	}
-	if resp.Header.StatusCode() != 200 {
-		t.Fatalf("Unexpected status code %d. Expected %d", resp.Header.StatusCode(), 423)
+	if resp.StatusCode() != 301 {
+		t.Fatalf("expecting 301, got %d", resp.StatusCode())
 	}
-	if !bytes.Equal(resp.Body(), body) {
-		t.Fatalf("Unexpected body %q. Expected %q", resp.Body(), string(body))
+	if loc := string(resp.Header.Peek("Location")); !strings.HasSuffix(loc, "/help/Docs/Intro.md?v=1") {
+		t.Fatalf("unexpected redirect location '%s'", loc)
+	}
+	if resp := testRequest(t, ts, "GET", "/help/Docs/Intro.md"); resp != "docs Intro.md" {
+		t.Fatalf("got '%s'", resp)
 	}
 }
 
-func urlParams(ctx context.Context) map[string]string {
-	if rctx := RouteContext(ctx); rctx != nil {
-		m := make(map[string]string, 0)
-		for _, p := range rctx.Params {
-			m[p.Key] = p.Value
+func denyAll(next Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Error("Forbidden", 403)
+	})
+}
+
+func TestMuxMiddlewares(t *testing.T) {
+	routes := func(r Router) {
+		r.Use(denyAll)
+		r.Use(func(next Handler) Handler {
+			return next
+		})
+		r.Get("/", func(fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte("index"))
+		})
+		r.Route("/admin", func(r Router) {
+			r.Use(denyAll)
+			r.Get("/", func(fctx *fasthttp.RequestCtx) {
+				fctx.Write([]byte("admin"))
+			})
+		})
+		r.Get("/inline", denyAll, func(fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte("inline"))
+		})
+	}
+
+	r := NewRouter()
+	routes(r)
+
+	mws := r.Middlewares()
+	if len(mws) != 2 || mws[0].Name != "chi.denyAll" || mws[1].Name != "chi.TestMuxMiddlewares" {
+		t.Fatalf("unexpected middlewares %v", mws)
+	}
+
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+	if resp := testRequest(t, ts, "GET", "/"); resp != "Forbidden" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	r = NewRouter()
+	r.DisableMiddleware("denyAll")
+	routes(r)
+
+	if mws := r.Middlewares(); len(mws) != 1 {
+		t.Fatalf("unexpected middlewares %v", mws)
+	}
+
+	ts = &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+	for path, expected := range map[string]string{"/": "index", "/admin": "admin", "/inline": "inline"} {
+		if resp := testRequest(t, ts, "GET", path); resp != expected {
+			t.Fatalf("%s: expecting '%s', got '%s'", path, expected, resp)
 		}
-		return m
 	}
-	return nil
 }
 
-func testRequest(t *testing.T, ts *fasthttp.Server, method, path string) string {
-	rw := &readWriter{}
-	ch := make(chan error)
+func TestMuxTrailingSlash(t *testing.T) {
+	routes := func(r *Mux) {
+		r.Get("/accounts", func(fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte("accounts"))
+		})
+		r.Route("/folders/", func(r Router) {
+			r.Get("/", func(fctx *fasthttp.RequestCtx) {
+				fctx.Write([]byte("folders"))
+			})
+		})
+	}
 
-	rw.r.WriteString(method + " " + path + " HTTP/1.1\r\n\r\n")
-	go func() {
-		ch <- ts.ServeConn(rw)
-	}()
-	select {
-	case err := <-ch:
-		if err != nil {
+	r := NewRouter()
+	routes(r)
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+	if resp := testRequest(t, ts, "GET", "/folders"); resp != "404 Page not found" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/accounts/"); resp != "404 Page not found" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	r = NewRouter()
+	r.TrailingSlash(TrailingSlashMatch)
+	routes(r)
+	ts = &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+	if resp := testRequest(t, ts, "GET", "/folders"); resp != "folders" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/accounts/"); resp != "accounts" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	r = NewRouter()
+	r.TrailingSlash(TrailingSlashRedirect)
+	routes(r)
+	ts = &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+	for _, tc := range []struct {
+		method, path, location string
+		status                 int
+	}{
+		{"GET", "/folders", "/folders/", 301},
+		{"POST", "/accounts/?page=2", "/accounts?page=2", 404},
+		{"GET", "/accounts/?page=2", "/accounts?page=2", 301},
+	} {
+		rw := &readWriter{}
+		rw.r.WriteString(tc.method + " " + tc.path + " HTTP/1.1\r\n\r\n")
+		if err := ts.ServeConn(rw); err != nil {
 			t.Fatal(err)
-			return ""
 		}
-	case <-time.After(200 * time.Millisecond):
-		t.Fatalf("timeout")
-		return ""
+		var resp fasthttp.Response
+		if err := resp.Read(bufio.NewReader(&rw.w)); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode() != tc.status {
+			t.Fatalf("%s %s: expecting %d, got %d", tc.method, tc.path, tc.status, resp.StatusCode())
+		}
+		if loc := string(resp.Header.Peek("Location")); tc.status != 404 && !strings.HasSuffix(loc, tc.location) {
+			t.Fatalf("%s %s: unexpected redirect location '%s'", tc.method, tc.path, loc)
+		}
 	}
-	br := bufio.NewReader(&rw.w)
-	var resp fasthttp.Response
-	if err := resp.Read(br); err != nil {
-		t.Fatal(err)
-		return ""
+}
+
+func TestMuxHeadFallback(t *testing.T) {
+	r := NewRouter()
+	r.Get("/articles/:id", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Response.Header.Set("X-Article", URLParam(ctx, "id"))
+		fctx.Write([]byte("article"))
+	})
+	r.Head("/ping", func(fctx *fasthttp.RequestCtx) {
+		fctx.Response.Header.Set("X-Ping", "pong")
+	})
+	r.Get("/ping", func(fctx *fasthttp.RequestCtx) {
+		fctx.Response.Header.Set("X-Ping", "get")
+	})
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.SetMethod("HEAD")
+	fctx.Request.SetRequestURI("/articles/22")
+	r.ServeHTTP(&fctx)
+	if string(fctx.Response.Header.Peek("X-Article")) != "22" || !fctx.Response.SkipBody {
+		t.Fatalf("expecting HEAD to be served by the GET route without a body")
 	}
-	return string(resp.Body())
+
+	var pctx fasthttp.RequestCtx
+	pctx.Request.Header.SetMethod("HEAD")
+	pctx.Request.SetRequestURI("/ping")
+	r.ServeHTTP(&pctx)
+	if string(pctx.Response.Header.Peek("X-Ping")) != "pong" {
+		t.Fatalf("expecting the HEAD route, got '%s'", pctx.Response.Header.Peek("X-Ping"))
+	}
+}
+
+func TestMuxMethodNotAllowed(t *testing.T) {
+	r := NewRouter()
+	r.MethodNotAllowed(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.SetStatusCode(405)
+		fctx.Write([]byte(`{"error":"method not allowed"}`))
+	})
+	r.Get("/articles/:id", func(fctx *fasthttp.RequestCtx) {})
+	r.Put("/articles/:id", func(fctx *fasthttp.RequestCtx) {})
+	r.Route("/users", func(r Router) {
+		r.Post("/", func(fctx *fasthttp.RequestCtx) {})
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		allow  string
+	}{
+		{"DELETE", "/articles/1", 405, "GET, HEAD, PUT"},
+		{"GET", "/users", 405, "POST"},
+		{"PURGE", "/articles/1", 405, "GET, HEAD, PUT"},
+		{"DELETE", "/nothing", 404, ""},
+		{"PUT", "/articles/1", 200, ""},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.Header.SetMethod(tt.method)
+		fctx.Request.SetRequestURI(tt.path)
+		r.ServeHTTP(&fctx)
+
+		if fctx.Response.StatusCode() != tt.status {
+			t.Fatalf("%s %s: expecting status %d, got %d", tt.method, tt.path, tt.status, fctx.Response.StatusCode())
+		}
+		if allow := string(fctx.Response.Header.Peek("Allow")); allow != tt.allow {
+			t.Fatalf("%s %s: expecting Allow '%s', got '%s'", tt.method, tt.path, tt.allow, allow)
+		}
+		if tt.status == 405 && string(fctx.Response.Body()) != `{"error":"method not allowed"}` {
+			t.Fatalf("%s %s: got '%s'", tt.method, tt.path, fctx.Response.Body())
+		}
+	}
+}
+
+func TestMuxHooks(t *testing.T) {
+	var events []string
+
+	r := NewRouter()
+	r.OnRouteRegistered(func(method, pattern string) {
+		events = append(events, "route "+method+" "+pattern)
+	})
+	r.OnRequestStart(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		events = append(events, "start "+string(fctx.Path()))
+	})
+	r.OnNotFound(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		events = append(events, "notfound")
+	})
+	r.OnPanic(func(ctx context.Context, fctx *fasthttp.RequestCtx, err interface{}) {
+		events = append(events, fmt.Sprintf("panic %v", err))
+	})
+	r.OnRequestEnd(func(ctx context.Context, fctx *fasthttp.RequestCtx, status int, duration time.Duration) {
+		events = append(events, fmt.Sprintf("end %d", status))
+	})
+
+	r.Get("/ping", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("pong"))
+	})
+	r.Get("/panic", func(fctx *fasthttp.RequestCtx) {
+		panic("oops")
+	})
+	r.Route("/admin", func(r Router) {
+		r.Get("/", func(fctx *fasthttp.RequestCtx) {})
+	})
+
+	serve := func(path string) {
+		defer func() { recover() }()
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(path)
+		r.ServeHTTP(&fctx)
+	}
+	serve("/ping")
+	serve("/nothing")
+	serve("/admin/nothing")
+	serve("/panic")
+
+	expected := []string{
+		"route GET /ping",
+		"route GET /panic",
+		"route * /admin",
+		"route * /admin/",
+		"route * /admin/*",
+		"start /ping", "end 200",
+		"start /nothing", "notfound", "end 404",
+		"start /admin/nothing", "notfound", "end 404",
+		"start /panic", "panic oops", "end 200",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("expecting events %v, got %v", expected, events)
+	}
+}
+
+func TestMuxRouteConflict(t *testing.T) {
+	r := NewRouter()
+	r.Get("/ping/:id", func(fctx *fasthttp.RequestCtx) {})
+	r.Get("/ping/new", func(fctx *fasthttp.RequestCtx) {})
+	r.Get("/ping/:id|int", func(fctx *fasthttp.RequestCtx) {})
+	r.Post("/ping/:key", func(fctx *fasthttp.RequestCtx) {})
+
+	recv := catchPanic(func() {
+		r.Group(func(r Router) {
+			r.Handle("/ping/:key", func(fctx *fasthttp.RequestCtx) {})
+		})
+	})
+	msg, _ := recv.(string)
+	if !strings.Contains(msg, "'GET /ping/:key'") || !strings.Contains(msg, "'GET /ping/:id'") ||
+		!strings.Contains(msg, "mux_test.go:") {
+		t.Fatalf("expecting a conflict naming both routes and their sites, got '%v'", recv)
+	}
+}
+
+func TestDiffRoutes(t *testing.T) {
+	h := func(fctx *fasthttp.RequestCtx) {}
+	mw := func(next Handler) Handler { return next }
+
+	old := NewRouter()
+	old.Get("/", h)
+	old.Get("/articles/:id", h)
+	old.Delete("/articles/:id", h)
+	old.Header("X-Api-Version", "2").Get("/articles/:id", h)
+	old.Route("/admin", func(r Router) {
+		r.Get("/users", h)
+	})
+
+	new := NewRouter()
+	new.Use(mw)
+	new.Get("/", h)
+	new.Get("/articles/:articleID", h)
+	new.Header("X-Api-Version", "3").Get("/articles/:articleID", h)
+	new.Group(func(r Router) {
+		r.Use(mw)
+		r.Post("/articles", h)
+	})
+	new.Route("/admin", func(r Router) {
+		r.Get("/users", h)
+		r.Get("/stats", h)
+	})
+
+	var changes []string
+	for _, c := range DiffRoutes(old, new) {
+		changes = append(changes, c.String())
+	}
+	expected := []string{
+		"~ GET / (middlewares 0 -> 1)",
+		"+ GET /admin/stats",
+		"~ GET /admin/users (middlewares 0 -> 1)",
+		"+ POST /articles",
+		"~ GET /articles/:articleID (middlewares 0 -> 1)",
+		"+ GET /articles/:articleID [x-api-version=3]",
+		"- DELETE /articles/:id",
+		"- GET /articles/:id [x-api-version=2]",
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expecting changes %v, got %v", expected, changes)
+	}
+}
+
+func TestMuxRemove(t *testing.T) {
+	r := NewRouter()
+	r.Get("/ping", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("pong"))
+	})
+	r.Handle("/plugins/:name", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("plugin"))
+	})
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	if !r.Remove("GET", "/plugins/:id") {
+		t.Fatalf("expecting GET /plugins/:name to be removed")
+	}
+	if resp := testRequest(t, ts, "GET", "/plugins/stats"); resp != "Method Not Allowed" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "POST", "/plugins/stats"); resp != "plugin" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	if !r.Remove("*", "/plugins/:name") || r.Remove("*", "/plugins/:name") {
+		t.Fatalf("expecting the remaining /plugins/:name routes to be removed once")
+	}
+	if resp := testRequest(t, ts, "POST", "/plugins/stats"); resp != "404 Page not found" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	// The route can be registered again
+	r.Get("/plugins/:name", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("plugin v2"))
+	})
+	if resp := testRequest(t, ts, "GET", "/plugins/stats"); resp != "plugin v2" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/ping"); resp != "pong" {
+		t.Fatalf("got '%s'", resp)
+	}
+}
+
+func TestMuxRemoveConditions(t *testing.T) {
+	r := NewRouter()
+	r.Get("/articles", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("v1"))
+	})
+	v2 := r.Header("X-Api-Version", "2")
+	v2.Get("/articles", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("v2"))
+	})
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+	get := func(version string) string {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.Header.SetMethod("GET")
+		fctx.Request.SetRequestURI("/articles")
+		if version != "" {
+			fctx.Request.Header.Set("X-Api-Version", version)
+		}
+		ts.Handler(&fctx)
+		return string(fctx.Response.Body())
+	}
+
+	if !r.Header("X-Api-Version", "2").Remove("GET", "/articles") || v2.Remove("GET", "/articles") {
+		t.Fatalf("expecting the v2 route to be removed once")
+	}
+	if resp := get("2"); resp != "v1" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	// Removing the route without conditions leaves the variant in place
+	v2.Get("/articles", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("v2"))
+	})
+	if !r.Remove("GET", "/articles") {
+		t.Fatalf("expecting the v1 route to be removed")
+	}
+	if resp := get("2"); resp != "v2" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := get(""); resp != "404 Page not found" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if !v2.Remove("GET", "/articles") || len(r.Routes()) != 0 {
+		t.Fatalf("expecting no routes left, got %v", r.Routes())
+	}
+	if resp := get("2"); resp != "404 Page not found" {
+		t.Fatalf("got '%s'", resp)
+	}
+}
+
+func TestMuxConcurrentRegistration(t *testing.T) {
+	r := NewRouter()
+	r.Get("/ping", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("pong"))
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			var fctx fasthttp.RequestCtx
+			fctx.Request.SetRequestURI(fmt.Sprintf("/routes/%d", i))
+			r.ServeHTTP(&fctx)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		r.Get(fmt.Sprintf("/routes/%d", i), func(fctx *fasthttp.RequestCtx) {})
+		if i%2 == 0 {
+			r.Remove("GET", fmt.Sprintf("/routes/%d", i))
+		}
+	}
+	<-done
+
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+	if resp := testRequest(t, ts, "GET", "/ping"); resp != "pong" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/routes/2"); resp != "404 Page not found" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/routes/3"); resp != "" {
+		t.Fatalf("got '%s'", resp)
+	}
+}
+
+func TestMuxConcurrentRemove(t *testing.T) {
+	admin := NewRouter()
+	admin.Get("/users/:id", func(fctx *fasthttp.RequestCtx) {}).Name("user")
+
+	r := NewRouter()
+	r.Get("/links", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		url, _ := URLFor(ctx, "user", map[string]string{"id": "7"})
+		fctx.Write([]byte(url))
+	})
+	for i := 0; i < 3000; i++ {
+		r.Get(fmt.Sprintf("/routes/%d", i), func(fctx *fasthttp.RequestCtx) {})
+	}
+	r.Mount("/admin", admin)
+
+	// Run with -race: serving, building URLs and listing routes read the
+	// routes being removed, each removal shifting the mount
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	read := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					fn()
+				}
+			}
+		}()
+	}
+	read(func() {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI("/links")
+		r.ServeHTTP(&fctx)
+		if url := string(fctx.Response.Body()); url != "/admin/users/7" {
+			t.Errorf("got '%s'", url)
+		}
+	})
+	read(func() { r.Routes() })
+	for i := 0; i < 3000; i++ {
+		r.Remove("GET", fmt.Sprintf("/routes/%d", i))
+	}
+	close(done)
+	wg.Wait()
+
+	if routes := r.Routes(); len(routes) != 2 {
+		t.Fatalf("expecting the /links and /admin/users/:id routes, got %v", routes)
+	}
+}
+
+func TestMuxReload(t *testing.T) {
+	r := NewRouter()
+	r.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Response.Header.Set("X-Version", "1")
+			next.ServeHTTPC(ctx, fctx)
+		})
+	})
+	r.NotFound(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.SetStatusCode(404)
+		fctx.Write([]byte("nothing here"))
+	})
+	r.Get("/v1", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("v1"))
+	})
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	// Serve requests while reloading the routes
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			var fctx fasthttp.RequestCtx
+			fctx.Request.SetRequestURI("/v1")
+			r.ServeHTTP(&fctx)
+		}
+	}()
+	r.Reload(func(r Router) {
+		r.Get("/v2", func(fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte("v2"))
+		})
+	})
+	<-done
+
+	if resp := testRequest(t, ts, "GET", "/v2"); resp != "v2" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/v1"); resp != "nothing here" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if len(r.Middlewares()) != 1 {
+		t.Fatalf("expecting the middleware stack to be kept")
+	}
+
+	// A failed reload keeps the routes
+	recv := catchPanic(func() {
+		r.Reload(func(r Router) {
+			r.Get("/v3", func(fctx *fasthttp.RequestCtx) {})
+			r.Get("/v3", func(fctx *fasthttp.RequestCtx) {})
+		})
+	})
+	if recv == nil {
+		t.Fatalf("expecting the reload to panic")
+	}
+	if resp := testRequest(t, ts, "GET", "/v2"); resp != "v2" {
+		t.Fatalf("got '%s'", resp)
+	}
+}
+
+func TestMuxBraceSyntax(t *testing.T) {
+	r := NewRouter()
+	r.Syntax(BraceSyntax)
+	r.Get("/articles/{id:[0-9]{1,4}}", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("article " + URLParam(ctx, "id")))
+	})
+	r.Get("/articles/{slug}", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("slug " + URLParam(ctx, "slug")))
+	})
+	r.Route("/users/{userID}", func(r Router) {
+		r.Get("/files/*", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte(URLParam(ctx, "userID") + " " + URLParam(ctx, "*")))
+		})
+	})
+	r.Get("/feeds/{format:json|xml}", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte(RoutePattern(ctx)))
+	})
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	// Every branch of an alternation is anchored
+	if resp := testRequest(t, ts, "GET", "/feeds/xml"); resp != "/feeds/{format:json|xml}" {
+		t.Fatalf("got '%s'", resp)
+	}
+	for _, path := range []string{"/feeds/jsonp", "/feeds/myxml"} {
+		if resp := testRequest(t, ts, "GET", path); resp != "404 Page not found" {
+			t.Fatalf("%s: got '%s'", path, resp)
+		}
+	}
+
+	// Patterns are introspected as registered
+	if pattern, _, _ := r.Match("GET", "/users/7/files/a"); pattern != "/users/{userID}/files/*" {
+		t.Fatalf("got pattern '%s'", pattern)
+	}
+	var patterns []string
+	for _, ri := range r.Routes() {
+		patterns = append(patterns, ri.Pattern)
+	}
+	expected := []string{"/articles/{id:[0-9]{1,4}}", "/articles/{slug}", "/feeds/{format:json|xml}", "/users/{userID}/files/*"}
+	if !reflect.DeepEqual(patterns, expected) {
+		t.Fatalf("expecting patterns %v, got %v", expected, patterns)
+	}
+
+	if resp := testRequest(t, ts, "GET", "/articles/2016"); resp != "article 2016" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/articles/20160"); resp != "slug 20160" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/users/7/files/a/b.txt"); resp != "7 a/b.txt" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	if recv := catchPanic(func() { r.Get("/{month}-{day}", func(fctx *fasthttp.RequestCtx) {}) }); recv == nil {
+		t.Fatalf("expecting a panic for a param not ending its segment")
+	}
+	if recv := catchPanic(func() { r.Get("/{id:[0-9]+", func(fctx *fasthttp.RequestCtx) {}) }); recv == nil {
+		t.Fatalf("expecting a panic for a missing closing brace")
+	}
+}
+
+func TestMuxWith(t *testing.T) {
+	tag := func(name string) func(next Handler) Handler {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+				fctx.Write([]byte(name + " "))
+				next.ServeHTTPC(ctx, fctx)
+			})
+		}
+	}
+	h := func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("h"))
+	}
+
+	r := NewRouter()
+	r.Use(tag("root"))
+	r.Get("/plain", h)
+	r.With(tag("a"), tag("b")).Get("/with", h)
+	r.Group(func(r Router) {
+		r.Use(tag("group"))
+		r.With(tag("a")).Get("/group", h)
+	})
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	if resp := testRequest(t, ts, "GET", "/plain"); resp != "root h" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/with"); resp != "root a b h" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/group"); resp != "root group a h" {
+		t.Fatalf("got '%s'", resp)
+	}
+}
+
+func TestMuxPrefix(t *testing.T) {
+	version := func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte("v1 "))
+			next.ServeHTTPC(ctx, fctx)
+		})
+	}
+
+	r := NewRouter()
+	r.Prefix("/v1/", func(r Router) {
+		r.Use(version)
+		r.Get("/", func(fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte("index"))
+		})
+		r.Group(func(r Router) {
+			r.Get("/users/:id", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+				fctx.Write([]byte("user " + URLParam(ctx, "id")))
+			})
+		})
+		r.Prefix("/admin", func(r Router) {
+			r.Get("/stats", func(fctx *fasthttp.RequestCtx) {
+				fctx.Write([]byte("stats"))
+			})
+		})
+	})
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	if resp := testRequest(t, ts, "GET", "/v1"); resp != "v1 index" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/v1/users/7"); resp != "user 7" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/v1/admin/stats"); resp != "v1 stats" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/users/7"); resp != "404 Page not found" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if pattern, _, _ := r.Match("GET", "/v1/admin/stats"); pattern != "/v1/admin/stats" {
+		t.Fatalf("got pattern '%s'", pattern)
+	}
+}
+
+func TestMuxServerErrorHandler(t *testing.T) {
+	r := NewRouter()
+	handleError := r.ServerErrorHandler()
+
+	var fctx fasthttp.RequestCtx
+	handleError(&fctx, &fasthttp.ErrSmallBuffer{})
+	if fctx.Response.StatusCode() != 431 || string(fctx.Response.Body()) != `{"error":"Request Header Fields Too Large"}` {
+		t.Fatalf("got %d '%s'", fctx.Response.StatusCode(), fctx.Response.Body())
+	}
+
+	var rendered error
+	r.ErrorRenderer(func(fctx *fasthttp.RequestCtx, status int, err error) {
+		rendered = err
+		fctx.SetStatusCode(status)
+		fctx.Write([]byte("custom"))
+	})
+	fctx = fasthttp.RequestCtx{}
+	handleError(&fctx, fasthttp.ErrBodyTooLarge)
+	if fctx.Response.StatusCode() != 413 || string(fctx.Response.Body()) != "custom" || rendered != fasthttp.ErrBodyTooLarge {
+		t.Fatalf("got %d '%s'", fctx.Response.StatusCode(), fctx.Response.Body())
+	}
+}
+
+func TestMuxErrorHandlers(t *testing.T) {
+	r := NewRouter()
+	r.Get("/plain", func(fctx *fasthttp.RequestCtx) error {
+		return errors.New("db is down")
+	})
+	r.Get("/ok", func(ctx context.Context, fctx *fasthttp.RequestCtx) error {
+		fctx.Write([]byte("ok"))
+		return nil
+	})
+	r.Route("/articles", func(r Router) {
+		r.Get("/:id", func(ctx context.Context, fctx *fasthttp.RequestCtx) error {
+			return &StatusError{Status: 404, Err: errors.New("no article " + URLParam(ctx, "id"))}
+		})
+	})
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	if resp := testRequest(t, ts, "GET", "/plain"); resp != `{"error":"Internal Server Error"}` {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/ok"); resp != "ok" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	var rendered error
+	r.ErrorRenderer(func(fctx *fasthttp.RequestCtx, status int, err error) {
+		rendered = err
+		fctx.SetStatusCode(status)
+		fctx.Write([]byte(fmt.Sprintf("%d %s", status, err)))
+	})
+	if resp := testRequest(t, ts, "GET", "/articles/7"); resp != "404 no article 7" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if _, ok := rendered.(*StatusError); !ok {
+		t.Fatalf("expecting the handler error to be rendered, got %v", rendered)
+	}
+}
+
+func TestMuxRoutePattern(t *testing.T) {
+	var logged string
+	logger := func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			next.ServeHTTPC(ctx, fctx)
+			logged = RoutePattern(ctx)
+		})
+	}
+	h := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte(RoutePattern(ctx)))
+	}
+
+	r := NewRouter()
+	r.Use(logger)
+	r.Get("/articles/:articleID", h)
+	r.Route("/admin", func(r Router) {
+		r.Get("/", h)
+		r.Get("/users/:id", h)
+	})
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	tests := map[string]string{
+		"/articles/42":   "/articles/:articleID",
+		"/admin":         "/admin",
+		"/admin/users/7": "/admin/users/:id",
+	}
+	for path, pattern := range tests {
+		if resp := testRequest(t, ts, "GET", path); resp != pattern {
+			t.Fatalf("%s: got '%s'", path, resp)
+		}
+		if logged != pattern {
+			t.Fatalf("%s: logged '%s'", path, logged)
+		}
+	}
+
+	testRequest(t, ts, "GET", "/nope")
+	if logged != "" {
+		t.Fatalf("expecting no pattern for unrouted paths, got '%s'", logged)
+	}
+}
+
+func TestMuxMountPoint(t *testing.T) {
+	h := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte(MountPoint(ctx)))
+	}
+
+	r := NewRouter()
+	r.Get("/", h)
+	r.Route("/hubs/:hubID/users", func(r Router) {
+		r.Get("/", h)
+		r.Get("/:userID", h)
+		r.Route("/:userID/posts", func(r Router) {
+			r.Get("/:postID", h)
+		})
+	})
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	tests := map[string]string{
+		"/":                          "",
+		"/hubs/123/users":            "/hubs/123/users",
+		"/hubs/123/users/5":          "/hubs/123/users",
+		"/hubs/123/users/5/posts/42": "/hubs/123/users/5/posts",
+	}
+	for path, mountPoint := range tests {
+		if resp := testRequest(t, ts, "GET", path); resp != mountPoint {
+			t.Fatalf("%s: got '%s'", path, resp)
+		}
+	}
+}
+
+func TestMuxURLFor(t *testing.T) {
+	var url string
+	var err error
+	h := func(name string, params map[string]string) HandlerFunc {
+		return func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			url, err = URLFor(ctx, name, params)
+		}
+	}
+
+	r := NewRouter()
+	r.Get("/files/*", h("files", map[string]string{"*": "a/b c.txt"})).Name("files")
+	r.Get("/me", h("user", map[string]string{"hubID": "1", "userID": "2"}))
+	r.Route("/hubs/:hubID/users", func(r Router) {
+		r.Get("/", h("users", nil)).Name("users")
+		r.Get("/:userID|int", h("user", map[string]string{"userID": "5"})).Name("user")
+		r.Get("/:userID/avatar", h("user", nil))
+		r.Get("/search", h("files", map[string]string{"*": "x"}))
+	})
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	tests := map[string]string{
+		"/files/readme":       "/files/a/b%20c.txt",
+		"/hubs/123/users":     "/hubs/123/users",
+		"/me":                 "/hubs/1/users/2",
+		"/hubs/a%20b/users/7": "/hubs/a%20b/users/5",
+	}
+	for path, expected := range tests {
+		testRequest(t, ts, "GET", path)
+		if err != nil || url != expected {
+			t.Fatalf("%s: got '%s', %v", path, url, err)
+		}
+	}
+
+	testRequest(t, ts, "GET", "/hubs/123/users/7/avatar")
+	if err == nil {
+		t.Fatalf("expecting a missing param error, got '%s'", url)
+	}
+	testRequest(t, ts, "GET", "/hubs/123/users/search")
+	if err == nil {
+		t.Fatalf("expecting names to be looked up from their router down, got '%s'", url)
+	}
+
+	if v := catchPanic(func() { r.Get("/other", h("", nil)).Name("files") }); v == nil {
+		t.Fatal("expecting a duplicate name to panic")
+	}
+}
+
+func TestMuxHost(t *testing.T) {
+	r := NewRouter()
+	r.Host("*.cdn.example.com").Get("/assets/*", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("asset " + URLParam(ctx, "subdomain") + " " + URLParam(ctx, "*")))
+	})
+	r.Host(":tenant.Example.com").Get("/", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("tenant " + URLParam(ctx, "tenant")))
+	})
+	r.Get("/", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("index"))
+	})
+
+	tests := []struct {
+		host, path string
+		expected   string
+	}{
+		{"eu.img.cdn.example.com", "/assets/logo.png", "asset eu.img logo.png"},
+		{"EU.cdn.example.com:8080", "/assets/a/b.css", "asset eu a/b.css"},
+		{"cdn.example.com", "/", "tenant cdn"},
+		{"acme.example.com", "/", "tenant acme"},
+		{"acme.example.com", "/assets/logo.png", "404 Page not found"},
+		{"example.com", "/", "index"},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.path)
+		fctx.Request.Header.SetHost(tt.host)
+		r.ServeHTTP(&fctx)
+		if resp := string(fctx.Response.Body()); resp != tt.expected {
+			t.Fatalf("%s%s: got '%s'", tt.host, tt.path, resp)
+		}
+	}
+
+	if r.Host("*.cdn.example.com") != r.Host("*.cdn.example.com") {
+		t.Fatalf("expecting the same router for the same host pattern")
+	}
+	if recv := catchPanic(func() { r.Host("cdn.*.com") }); recv == nil {
+		t.Fatalf("expecting a panic for a wildcard that isn't leading")
+	}
+}
+
+func TestMuxRequestHandler(t *testing.T) {
+	var legacy fasthttp.RequestHandler = func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("legacy " + string(fctx.Path())))
+	}
+
+	r := NewRouter()
+	r.Get("/legacy", legacy)
+	r.Mount("/mounted", legacy)
+	r.Get("/wrapped", func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte("mw "))
+			next.ServeHTTPC(ctx, fctx)
+		})
+	}, WrapF(legacy))
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	if resp := testRequest(t, ts, "GET", "/legacy"); resp != "legacy /legacy" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "POST", "/mounted/a/b"); resp != "legacy /mounted/a/b" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/wrapped"); resp != "mw legacy /wrapped" {
+		t.Fatalf("got '%s'", resp)
+	}
+}
+
+func TestMuxFasthttpMiddleware(t *testing.T) {
+	poweredBy := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(fctx *fasthttp.RequestCtx) {
+			fctx.Response.Header.Set("X-Powered-By", "fasthttp")
+			next(fctx)
+		}
+	}
+
+	r := NewRouter()
+	r.Use(poweredBy)
+	r.Get("/users/:id", poweredBy, func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("user " + URLParam(ctx, "id")))
+	})
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/users/7")
+	r.ServeHTTP(&fctx)
+	if resp := string(fctx.Response.Body()); resp != "user 7" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if v := string(fctx.Response.Header.Peek("X-Powered-By")); v != "fasthttp" {
+		t.Fatalf("got X-Powered-By '%s'", v)
+	}
+	if name := r.Middlewares()[0].Name; name != "chi.TestMuxFasthttpMiddleware" {
+		t.Fatalf("got middleware name '%s'", name)
+	}
+}
+
+func TestMuxNetHTTPHandler(t *testing.T) {
+	r := NewRouter()
+	r.Get("/legacy", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Legacy", "1")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("legacy " + r.URL.Query().Get("q")))
+	})
+	r.Mount("/debug", http.NotFoundHandler())
+	r.Get("/wrapped", WrapHTTP(http.RedirectHandler("/legacy", http.StatusFound)))
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/legacy?q=ok")
+	r.ServeHTTP(&fctx)
+	if resp := string(fctx.Response.Body()); fctx.Response.StatusCode() != 202 || resp != "legacy ok" {
+		t.Fatalf("got %d '%s'", fctx.Response.StatusCode(), resp)
+	}
+	if v := string(fctx.Response.Header.Peek("X-Legacy")); v != "1" {
+		t.Fatalf("got X-Legacy '%s'", v)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("/debug/pprof")
+	r.ServeHTTP(&fctx)
+	if resp := string(fctx.Response.Body()); resp != "404 page not found\n" {
+		t.Fatalf("expecting the mounted handler's 404, got '%s'", resp)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("/wrapped")
+	r.ServeHTTP(&fctx)
+	if fctx.Response.StatusCode() != 302 {
+		t.Fatalf("got %d", fctx.Response.StatusCode())
+	}
+}
+
+func TestMuxRouteMeta(t *testing.T) {
+	requireRole := func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			if role, _ := RouteMeta(ctx, "auth").(string); role != "" && string(fctx.Request.Header.Peek("X-Role")) != role {
+				fctx.SetStatusCode(403)
+				fctx.Write([]byte("forbidden"))
+				return
+			}
+			next.ServeHTTPC(ctx, fctx)
+		})
+	}
+
+	r := NewRouter()
+	r.Group(func(r Router) {
+		r.Use(requireRole)
+		r.Get("/reports", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte("reports"))
+		}).Meta("auth", "admin").Meta("metrics", "reports")
+		r.Get("/public", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte("public"))
+		})
+	})
+	r.Route("/api", func(r Router) {
+		r.Get("/ping", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte(fmt.Sprintf("%v", RouteMeta(ctx, "metrics"))))
+		}).Meta("metrics", "ping")
+	})
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/reports")
+	r.ServeHTTP(&fctx)
+	if resp := string(fctx.Response.Body()); resp != "forbidden" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("/reports")
+	fctx.Request.Header.Set("X-Role", "admin")
+	r.ServeHTTP(&fctx)
+	if resp := string(fctx.Response.Body()); resp != "reports" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("/public")
+	r.ServeHTTP(&fctx)
+	if resp := string(fctx.Response.Body()); resp != "public" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("/api/ping")
+	r.ServeHTTP(&fctx)
+	if resp := string(fctx.Response.Body()); resp != "ping" {
+		t.Fatalf("got '%s'", resp)
+	}
+}
+
+func TestMuxMethod(t *testing.T) {
+	sr := NewRouter()
+	sr.Method("MKCOL", "/:name", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("mkcol " + URLParam(ctx, "name")))
+	})
+
+	r := NewRouter()
+	r.Method("propfind", "/dav/*", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("propfind " + URLParam(ctx, "*")))
+	})
+	r.Handle("/any", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("any " + string(fctx.Method())))
+	})
+	r.Any("/proxy/*", func(fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte("proxy " + string(fctx.Method())))
+	})
+	r.Get("/proxy/health", func(fctx *fasthttp.RequestCtx) {})
+	r.Mount("/col", sr)
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	if resp := testRequest(t, ts, "PROPFIND", "/dav/a/b"); resp != "propfind a/b" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "GET", "/dav/a/b"); resp != "Method Not Allowed" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "PROPFIND", "/any"); resp != "any PROPFIND" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "MKCOL", "/col/docs"); resp != "mkcol docs" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "PROPFIND", "/col/docs"); resp != "Method Not Allowed" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "BREW", "/any"); resp != "Method Not Allowed" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "BREW", "/dav/a/b"); resp != "Method Not Allowed" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "PURGE", "/proxy/a/b"); resp != "proxy PURGE" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "BREW", "/proxy/health"); resp != "proxy BREW" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if resp := testRequest(t, ts, "BREW", "/nothing"); resp != "404 Page not found" {
+		t.Fatalf("got '%s'", resp)
+	}
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.SetMethod("GET")
+	fctx.Request.SetRequestURI("/dav/x")
+	r.ServeHTTP(&fctx)
+	if allow := string(fctx.Response.Header.Peek("Allow")); allow != "PROPFIND" {
+		t.Fatalf("got Allow '%s'", allow)
+	}
+
+	if !r.Remove("PROPFIND", "/dav/*") {
+		t.Fatalf("expecting the PROPFIND route to be removed")
+	}
+	if resp := testRequest(t, ts, "PROPFIND", "/dav/a/b"); resp != "404 Page not found" {
+		t.Fatalf("got '%s'", resp)
+	}
+	if !r.Remove("*", "/any") {
+		t.Fatalf("expecting the route of all methods to be removed")
+	}
+	if resp := testRequest(t, ts, "PROPFIND", "/any"); resp != "404 Page not found" {
+		t.Fatalf("got '%s'", resp)
+	}
+}
+
+func TestMuxUnknownMethods(t *testing.T) {
+	routes := func(r Router) {
+		r.Get("/tea", func(fctx *fasthttp.RequestCtx) {})
+		r.Handle("/any", func(fctx *fasthttp.RequestCtx) {
+			fctx.WriteString("any " + string(fctx.Method()))
+		})
+		r.Any("/proxy/*", func(fctx *fasthttp.RequestCtx) {})
+		r.Get("/proxy/health", func(fctx *fasthttp.RequestCtx) {})
+	}
+
+	tests := []struct {
+		policy UnknownMethodPolicy
+		path   string
+		status int
+		allow  string
+	}{
+		{UnknownMethodDispatch, "/any", 200, ""},
+		{UnknownMethodDispatch, "/tea", 405, "GET, HEAD"},
+		{UnknownMethodNotAllowed, "/tea", 405, "GET, HEAD"},
+		{UnknownMethodNotAllowed, "/any", 405, "CONNECT, DELETE, GET, HEAD, OPTIONS, PATCH, POST, PUT, TRACE"},
+		{UnknownMethodNotAllowed, "/nothing", 404, ""},
+		{UnknownMethodNotImplemented, "/any", 501, ""},
+		{UnknownMethodNotImplemented, "/nothing", 501, ""},
+		{UnknownMethodNotAllowed, "/proxy/a/b", 200, ""},
+		{UnknownMethodNotImplemented, "/proxy/a/b", 200, ""},
+		{UnknownMethodNotAllowed, "/proxy/health", 200, ""},
+	}
+	for _, tt := range tests {
+		var opts []Option
+		if tt.policy != UnknownMethodNotAllowed {
+			// Answering a 405 is the default
+			opts = append(opts, WithUnknownMethods(tt.policy))
+		}
+		r := NewRouter(opts...)
+		routes(r)
+
+		var fctx fasthttp.RequestCtx
+		fctx.Request.Header.SetMethod("BREW")
+		fctx.Request.SetRequestURI(tt.path)
+		r.ServeHTTP(&fctx)
+		allow := string(fctx.Response.Header.Peek("Allow"))
+		if fctx.Response.StatusCode() != tt.status || allow != tt.allow {
+			t.Fatalf("policy %d, %s: got %d, Allow '%s'", tt.policy, tt.path, fctx.Response.StatusCode(), allow)
+		}
+	}
+}
+
+func TestMuxMatch(t *testing.T) {
+	h := func(fctx *fasthttp.RequestCtx) {}
+
+	r := NewRouter()
+	r.Get("/", h)
+	r.Get("/articles/:id|int", h)
+	r.Route("/admin", func(r Router) {
+		r.Get("/", h)
+		r.Post("/users/:userID/roles/:role", h)
+		r.Route("/files", func(r Router) {
+			r.Get("/*", h)
+		})
+	})
+
+	tests := []struct {
+		method, path string
+		pattern      string
+		params       map[string]string
+	}{
+		{"GET", "/", "/", map[string]string{}},
+		{"get", "/articles/42", "/articles/:id|int", map[string]string{"id": "42"}},
+		{"GET", "/admin", "/admin", map[string]string{}},
+		{"POST", "/admin/users/7/roles/editor", "/admin/users/:userID/roles/:role", map[string]string{"userID": "7", "role": "editor"}},
+		{"GET", "/admin/files/a/b.txt", "/admin/files/*", map[string]string{"*": "a/b.txt"}},
+	}
+	for _, tt := range tests {
+		pattern, params, ok := r.Match(tt.method, tt.path)
+		if !ok || pattern != tt.pattern || !reflect.DeepEqual(params, tt.params) {
+			t.Fatalf("%s %s: got '%s' %v %v", tt.method, tt.path, pattern, params, ok)
+		}
+	}
+
+	for _, path := range []string{"/articles/new", "/admin/users/7/roles/editor", "/nope"} {
+		if pattern, _, ok := r.Match("GET", path); ok {
+			t.Fatalf("GET %s: expecting no match, got '%s'", path, pattern)
+		}
+	}
+	if _, _, ok := r.Match("BREW", "/"); ok {
+		t.Fatalf("expecting no match for an unknown method")
+	}
+}
+
+func catchPanic(testFunc func()) (recv interface{}) {
+	defer func() {
+		recv = recover()
+	}()
+
+	testFunc()
+	return
+}
+
+func TestMuxRepeatedParams(t *testing.T) {
+	h := func(ctx context.Context, fctx *fasthttp.RequestCtx) {}
+
+	r := NewRouter()
+	patterns := []string{"/a/:id/b/:id", "/a/:id|int/b/:id", "/files/:path/*path"}
+	for _, pattern := range patterns {
+		if recv := catchPanic(func() { r.Get(pattern, h) }); recv == nil {
+			t.Fatalf("%s: expecting a repeated param to panic", pattern)
+		}
+	}
+
+	r.Syntax(BraceSyntax)
+	if recv := catchPanic(func() { r.Get("/a/{id}/b/{id:[0-9]+}", h) }); recv == nil {
+		t.Fatal("expecting a repeated brace param to panic")
+	}
+	r.Get("/a/{id}/b/{bid}", h)
+}
+
+func TestMuxHeader(t *testing.T) {
+	reply := func(body string) HandlerFunc {
+		return func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte(body + " " + RoutePattern(ctx)))
+		}
+	}
+
+	r := NewRouter()
+	r.Header("X-API-Version", "2").Get("/items/:id", reply("v2"))
+	r.Get("/items/:id", reply("v1"))
+	r.Header("Content-Type", "multipart/*").Post("/uploads", reply("multipart"))
+	r.Header("Content-Type", "application/json").Group(func(r Router) {
+		r.Header("X-API-Version", "2").Post("/uploads", reply("json v2"))
+		r.Post("/uploads", reply("json"))
+	})
+
+	tests := []struct {
+		method, path string
+		headers      map[string]string
+		status       int
+		body         string
+	}{
+		{"GET", "/items/1", nil, 200, "v1 /items/:id"},
+		{"GET", "/items/1", map[string]string{"X-API-Version": "2"}, 200, "v2 /items/:id"},
+		{"GET", "/items/1", map[string]string{"X-API-Version": "3"}, 200, "v1 /items/:id"},
+		{"POST", "/uploads", map[string]string{"Content-Type": "Multipart/Form-Data; boundary=x"}, 200, "multipart /uploads"},
+		{"POST", "/uploads", map[string]string{"Content-Type": "application/json"}, 200, "json /uploads"},
+		{"POST", "/uploads", map[string]string{"Content-Type": "application/json", "X-API-Version": "2"}, 200, "json v2 /uploads"},
+		{"POST", "/uploads", map[string]string{"Content-Type": "text/plain"}, 404, "404 Page not found"},
+		{"PUT", "/uploads", map[string]string{"Content-Type": "application/json"}, 405, "Method Not Allowed"},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.Header.SetMethod(tt.method)
+		fctx.Request.SetRequestURI(tt.path)
+		for k, v := range tt.headers {
+			fctx.Request.Header.Set(k, v)
+		}
+		r.ServeHTTP(&fctx)
+		if fctx.Response.StatusCode() != tt.status || string(fctx.Response.Body()) != tt.body {
+			t.Fatalf("%s %s %v: got %d '%s'", tt.method, tt.path, tt.headers, fctx.Response.StatusCode(), fctx.Response.Body())
+		}
+	}
+
+	if pattern, _, ok := r.Match("POST", "/uploads"); !ok || pattern != "/uploads" {
+		t.Fatalf("got '%s' %v", pattern, ok)
+	}
+
+	h := func(ctx context.Context, fctx *fasthttp.RequestCtx) {}
+	if recv := catchPanic(func() { r.Header("x-api-version", "2").Get("/items/:key", h) }); recv == nil {
+		t.Fatal("expecting a route with the same conditions to conflict")
+	}
+}
+
+func TestMuxProduces(t *testing.T) {
+	reply := func(body string) HandlerFunc {
+		return func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte(body))
+		}
+	}
+
+	r := NewRouter()
+	r.Produces("application/json").Get("/reports", reply("json"))
+	r.Produces("text/csv").Get("/reports", reply("csv"))
+	r.Produces("text/csv").Get("/exports", reply("csv"))
+	r.Get("/exports", reply("default"))
+
+	tests := []struct {
+		path, accept string
+		status       int
+		body         string
+	}{
+		{"/reports", "", 200, "json"},
+		{"/reports", "text/csv", 200, "csv"},
+		{"/reports", "Application/JSON", 200, "json"},
+		{"/reports", "application/json;q=0.5, text/csv", 200, "csv"},
+		{"/reports", "text/*, application/json;q=0.2", 200, "csv"},
+		{"/reports", "*/*, text/csv;q=0", 200, "json"},
+		{"/reports", "image/png", 406, `{"error":"Not Acceptable"}`},
+		{"/exports", "image/png", 200, "default"},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.path)
+		if tt.accept != "" {
+			fctx.Request.Header.Set("Accept", tt.accept)
+		}
+		r.ServeHTTP(&fctx)
+		if fctx.Response.StatusCode() != tt.status || string(fctx.Response.Body()) != tt.body {
+			t.Fatalf("%s %s: got %d '%s'", tt.path, tt.accept, fctx.Response.StatusCode(), fctx.Response.Body())
+		}
+		if vary := string(fctx.Response.Header.Peek("Vary")); vary != "Accept" {
+			t.Fatalf("%s %s: got Vary '%s'", tt.path, tt.accept, vary)
+		}
+	}
+}
+
+func TestMuxCanary(t *testing.T) {
+	reply := func(body string) HandlerFunc {
+		return func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte(body))
+		}
+	}
+	serve := func(r *Mux, path, cookie string) string {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(path)
+		if cookie != "" {
+			fctx.Request.Header.Set("Cookie", cookie)
+		}
+		r.ServeHTTP(&fctx)
+		return string(fctx.Response.Body())
+	}
+
+	r := NewRouter()
+	r.Get("/search", reply("stable"))
+	r.Cookie("beta", "1").Get("/search", reply("beta"))
+	r.Canary(30, "session").Get("/search", reply("canary"))
+	r.Get("/none", reply("stable"))
+	r.Canary(0, "").Get("/none", reply("canary"))
+	r.Get("/both", reply("stable"))
+	r.Canary(50, "").Canary(50, "").Get("/both", reply("canary"))
+
+	canaries, both := 0, 0
+	for i := 0; i < 1000; i++ {
+		if serve(r, "/search", "") == "canary" {
+			canaries++
+		}
+		if body := serve(r, "/none", ""); body != "stable" {
+			t.Fatalf("got '%s'", body)
+		}
+		// The share is drawn once per request
+		if serve(r, "/both", "") == "canary" {
+			both++
+		}
+	}
+	if canaries < 200 || canaries > 400 {
+		t.Fatalf("expecting about 300 canaries, got %d", canaries)
+	}
+	if both < 400 || both > 600 {
+		t.Fatalf("expecting about 500 canaries of both shares, got %d", both)
+	}
+
+	if body := serve(r, "/search", "beta=1"); body != "beta" {
+		t.Fatalf("got '%s'", body)
+	}
+	// Canaries sticking on other cookies are told apart
+	r.Canary(30, "session").Get("/suggest", reply("canary"))
+	r.Canary(30, "user").Get("/suggest", reply("user canary"))
+
+	r.Cookie("plan", "Pro").Get("/plans", reply("pro"))
+	r.Get("/plans", reply("free"))
+	if body := serve(r, "/plans", "plan=pro"); body != "free" {
+		t.Fatalf("expecting cookie values to be case-sensitive, got '%s'", body)
+	}
+	if body := serve(r, "/plans", "plan=Pro"); body != "pro" {
+		t.Fatalf("got '%s'", body)
+	}
+	for _, session := range []string{"a", "b", "c", "d"} {
+		body := serve(r, "/search", "session="+session)
+		for i := 0; i < 10; i++ {
+			if b := serve(r, "/search", "session="+session); b != body {
+				t.Fatalf("session %s: got '%s' then '%s'", session, body, b)
+			}
+		}
+	}
+
+	if recv := catchPanic(func() { r.Canary(120, "") }); recv == nil {
+		t.Fatal("expecting a share over 100% to panic")
+	}
+}
+
+func TestMuxRouteTimeout(t *testing.T) {
+	slow := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			fctx.Write([]byte("done"))
+		}
+	}
+
+	r := NewRouter()
+	r.Get("/slow", slow, WithTimeout(10*time.Millisecond))
+	r.Get("/fast", slow, WithTimeout(2*time.Second))
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/slow")
+	r.ServeHTTP(&fctx)
+	if fctx.Response.StatusCode() != 504 {
+		t.Fatalf("expecting a 504, got %d", fctx.Response.StatusCode())
+	}
+
+	old := NewRouter()
+	old.Get("/slow", slow)
+	old.Get("/fast", slow, WithTimeout(2*time.Second))
+	changes := DiffRoutes(old, r)
+	if len(changes) != 1 || changes[0].String() != "~ GET /slow (middlewares 0 -> 0, timeout 0s -> 10ms)" {
+		t.Fatalf("got %v", changes)
+	}
+}
+
+// slowReader sends a byte of body at each read, after a delay.
+type slowReader struct {
+	delay time.Duration
+	n     int
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(r.delay)
+	r.n--
+	p[0] = 'x'
+	return 1, nil
+}
+
+func TestMuxBodyReadTimeout(t *testing.T) {
+	var cancelled bool
+	upload := func(ctx context.Context, fctx *fasthttp.RequestCtx) error {
+		body, err := ioutil.ReadAll(fctx.Request.BodyStream())
+		cancelled = ctx.Err() != nil
+		if err != nil {
+			return err
+		}
+		fctx.Write(body)
+		return nil
+	}
+
+	r := NewRouter()
+	r.Post("/upload", upload, WithBodyReadTimeout(50*time.Millisecond))
+
+	tests := []struct {
+		delay     time.Duration
+		status    int
+		cancelled bool
+	}{
+		{0, 200, false},
+		{20 * time.Millisecond, 408, true},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.Header.SetMethod("POST")
+		fctx.Request.SetRequestURI("/upload")
+		fctx.Request.SetBodyStream(&slowReader{delay: tt.delay, n: 5}, 5)
+		r.ServeHTTP(&fctx)
+		if fctx.Response.StatusCode() != tt.status || cancelled != tt.cancelled {
+			t.Fatalf("delay %s: got %d, cancelled %v", tt.delay, fctx.Response.StatusCode(), cancelled)
+		}
+		if tt.status == 408 && !fctx.Response.Header.ConnectionClose() {
+			t.Fatalf("expecting the connection to be closed on a 408")
+		}
+	}
+}
+
+func TestMuxTryHandle(t *testing.T) {
+	h := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.WriteString("ok")
+	}
+
+	r := NewRouter()
+	if _, err := r.TryHandle("GET", "/plugins/:id", h); err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if _, err := r.TryHandle("*", "/hooks", h); err != nil {
+		t.Fatalf("got %v", err)
+	}
+
+	tests := []struct {
+		method  string
+		pattern string
+		err     string
+	}{
+		{"GET", "plugins", "chi: pattern must begin with '/' in 'plugins'"},
+		{"GET", "/plugins/:key", "chi: route 'GET /plugins/:key'"},
+		{"GET", "/a/:id/b/:id", "chi: "},
+	}
+	for _, tt := range tests {
+		route, err := r.TryHandle(tt.method, tt.pattern, h)
+		if route != nil || err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Fatalf("%s %s: got %v", tt.method, tt.pattern, err)
+		}
+	}
+	if err := r.TryMount("plugins", NewRouter()); err == nil {
+		t.Fatalf("expecting an invalid mount pattern to fail")
+	}
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/plugins/1")
+	r.ServeHTTP(&fctx)
+	if string(fctx.Response.Body()) != "ok" {
+		t.Fatalf("expecting the routes to be served, got %d", fctx.Response.StatusCode())
+	}
+}
+
+func TestMuxRoutes(t *testing.T) {
+	h := func(ctx context.Context, fctx *fasthttp.RequestCtx) {}
+	mw := func(next Handler) Handler { return next }
+
+	r := NewRouter()
+	r.Use(mw)
+	r.Get("/", h)
+	r.Route("/articles", func(r Router) {
+		r.With(mw).Get("/:id", h, WithTimeout(time.Second)).Name("article")
+		r.Header("X-API-Version", "2").Get("/:id", h)
+	})
+
+	b, err := r.RoutesJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[
+  {
+    "method": "GET",
+    "pattern": "/",
+    "middlewares": 1
+  },
+  {
+    "method": "GET",
+    "pattern": "/articles/:id",
+    "name": "article",
+    "middlewares": 2,
+    "timeout": 1000000000
+  },
+  {
+    "method": "GET",
+    "pattern": "/articles/:id",
+    "conditions": "[x-api-version=2]",
+    "middlewares": 1
+  }
+]`
+	if string(b) != expected {
+		t.Fatalf("got %s", b)
+	}
+
+	if b, _ := NewRouter().RoutesJSON(); string(b) != "[]" {
+		t.Fatalf("got %s", b)
+	}
+}
+
+func TestMuxServeHTTPC(t *testing.T) {
+	r := NewRouter()
+	r.Get("/users/:id", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.WriteString(URLParam(ctx, "id") + " " + ctx.Value("tenant").(string))
+	})
+
+	ctx := context.WithValue(context.Background(), "tenant", "acme")
+	for _, id := range []string{"1", "2"} {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI("/users/" + id)
+		r.ServeHTTPC(ctx, &fctx)
+		if body := string(fctx.Response.Body()); body != id+" acme" {
+			t.Fatalf("got '%s'", body)
+		}
+	}
+}
+
+func TestMuxUserValueParams(t *testing.T) {
+	r := NewRouter()
+	r.UserValueParams(true)
+	r.Route("/hubs/:hubID", func(r Router) {
+		r.Get("/users/:userID", func(fctx *fasthttp.RequestCtx) {
+			fctx.WriteString(fctx.UserValue("hubID").(string) + " " + fctx.UserValue("userID").(string))
+		})
+	})
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/hubs/123/users/5")
+	r.ServeHTTP(&fctx)
+	if body := string(fctx.Response.Body()); body != "123 5" {
+		t.Fatalf("got '%s'", body)
+	}
+}
+
+func TestMuxParentParams(t *testing.T) {
+	users := NewRouter()
+	users.Get("/:userID", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.WriteString(URLParam(ctx, "hubID") + " " + URLParam(ctx, "userID"))
+	})
+
+	teams := NewRouter()
+	teams.ParentParamPrefix("org.")
+	teams.Get("/:id", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.WriteString(URLParam(ctx, "id") + " " + URLParam(ctx, "org.id"))
+	})
+
+	shadowed := NewRouter()
+	shadowed.Get("/:id", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.WriteString(URLParam(ctx, "id"))
+	})
+
+	r := NewRouter()
+	r.Mount("/hubs/:hubID/users", users)
+	r.Mount("/orgs/:id/teams", teams)
+	r.Mount("/groups/:id/members", shadowed)
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/hubs/123/users/5", "123 5"},
+		{"/orgs/1/teams/2", "2 1"},
+		{"/orgs/3/teams/4", "4 3"},
+		{"/groups/1/members/2", "1"},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.path)
+		r.ServeHTTP(&fctx)
+		if body := string(fctx.Response.Body()); body != tt.body {
+			t.Fatalf("%s: got '%s', want '%s'", tt.path, body, tt.body)
+		}
+	}
+}
+
+func TestMuxDecodeParams(t *testing.T) {
+	h := func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.Write([]byte(URLParam(ctx, "name")))
+	}
+
+	r := NewRouter()
+	r.Get("/files/:name", h)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/files/my%20notes", 200, "my notes"},
+		{"/files/100%", 400, `{"error":"Bad Request"}`},
+		{"/files/a%zz", 400, `{"error":"Bad Request"}`},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.path)
+		r.ServeHTTP(&fctx)
+		if fctx.Response.StatusCode() != tt.status || string(fctx.Response.Body()) != tt.body {
+			t.Fatalf("%s: got %d '%s'", tt.path, fctx.Response.StatusCode(), fctx.Response.Body())
+		}
+	}
+
+	r = NewRouter()
+	r.DecodeParams(false)
+	r.Get("/files/:name", h)
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/files/a%2Fb")
+	r.ServeHTTP(&fctx)
+	if resp := string(fctx.Response.Body()); resp != "a%2Fb" {
+		t.Fatalf("got '%s'", resp)
+	}
+}
+
+func TestMuxNotFoundFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/404.html", []byte("<h1>lost</h1>"), 0644)
+	ioutil.WriteFile(dir+"/about.html", []byte("<h1>about</h1>"), 0644)
+
+	r := NewRouter()
+	r.NotFoundFile(dir + "/404.html")
+	r.FileServer("/site/*filepath", dir)
+	r.Route("/api", func(r Router) {
+		r.Get("/ping", func(fctx *fasthttp.RequestCtx) {
+			fctx.Write([]byte("pong"))
+		})
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/site/about.html", 200, "<h1>about</h1>"},
+		{"/site/missing.html", 404, "<h1>lost</h1>"},
+		{"/missing", 404, "<h1>lost</h1>"},
+		{"/api/missing", 404, "<h1>lost</h1>"},
+		{"/api/ping", 200, "pong"},
+	}
+	for _, tt := range tests {
+		// Initialized like the requests of a server, as fasthttp logs the
+		// files it fails to open through the logger of the server
+		var req fasthttp.Request
+		req.SetRequestURI(tt.path)
+		req.Header.Set("If-Modified-Since", "Mon, 02 Jan 2006 15:04:05 GMT")
+		var fctx fasthttp.RequestCtx
+		fctx.Init(&req, nil, nil)
+		r.ServeHTTP(&fctx)
+		if fctx.Response.StatusCode() != tt.status || string(fctx.Response.Body()) != tt.body {
+			t.Fatalf("%s: got %d '%s'", tt.path, fctx.Response.StatusCode(), fctx.Response.Body())
+		}
+	}
+}
+
+func TestMuxNotFoundMethod(t *testing.T) {
+	r := NewRouter()
+	r.NotFound(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.SetStatusCode(404)
+		fctx.WriteString(`{"error":"not found"}`)
+	})
+	r.NotFoundMethod("get", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.SetStatusCode(404)
+		fctx.WriteString("<h1>lost</h1>")
+	})
+	r.Route("/api", func(r Router) {
+		r.Post("/items", func(fctx *fasthttp.RequestCtx) {})
+	})
+
+	// Set after the Mount, the handler is the subrouter's alone
+	sr := NewRouter()
+	sr.Get("/", func(fctx *fasthttp.RequestCtx) {})
+	r.Mount("/admin", sr)
+	sr.NotFoundMethod("POST", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.SetStatusCode(404)
+		fctx.WriteString("no such action")
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{"GET", "/missing", "<h1>lost</h1>"},
+		{"HEAD", "/missing", "<h1>lost</h1>"},
+		{"POST", "/missing", `{"error":"not found"}`},
+		{"GET", "/api/missing", "<h1>lost</h1>"},
+		{"POST", "/api/missing", `{"error":"not found"}`},
+		{"GET", "/admin/missing", "<h1>lost</h1>"},
+		{"POST", "/admin/missing", "no such action"},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.Header.SetMethod(tt.method)
+		fctx.Request.SetRequestURI(tt.path)
+		r.ServeHTTP(&fctx)
+		if fctx.Response.StatusCode() != 404 || string(fctx.Response.Body()) != tt.body {
+			t.Fatalf("%s %s: got %d '%s'", tt.method, tt.path, fctx.Response.StatusCode(), fctx.Response.Body())
+		}
+	}
+}
+
+func TestMuxFileServer(t *testing.T) {
+	r := NewRouter()
+
+	recv := catchPanic(func() {
+		r.FileServer("/noFilepath", os.TempDir())
+	})
+	if recv == nil {
+		t.Fatal("registering path not ending with '*filepath' did not panic")
+	}
+	body := []byte("fake ico")
+	ioutil.WriteFile(os.TempDir()+"/favicon.ico", body, 0644)
+
+	r.FileServer("/*filepath", os.TempDir())
+
+	ts := &fasthttp.Server{
+		Handler: r.ServeHTTP,
+	}
+
+	rw := &readWriter{}
+	ch := make(chan error)
+
+	rw.r.WriteString(string("GET /favicon.ico HTTP/1.1\r\n\r\n"))
+	go func() {
+		ch <- ts.ServeConn(rw)
+	}()
+	select {
+	case err := <-ch:
+		if err != nil {
+			t.Fatalf("return error %s", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatalf("timeout")
+	}
+
+	br := bufio.NewReader(&rw.w)
+	var resp fasthttp.Response
+	if err := resp.Read(br); err != nil {
+		t.Fatalf("Unexpected error when reading response: %s", err)
+	}
+	if resp.Header.StatusCode() != 200 {
+		t.Fatalf("Unexpected status code %d. Expected %d", resp.Header.StatusCode(), 423)
+	}
+	if !bytes.Equal(resp.Body(), body) {
+		t.Fatalf("Unexpected body %q. Expected %q", resp.Body(), string(body))
+	}
+}
+
+func testRequest(t *testing.T, ts *fasthttp.Server, method, path string) string {
+	rw := &readWriter{}
+	ch := make(chan error)
+
+	rw.r.WriteString(method + " " + path + " HTTP/1.1\r\n\r\n")
+	go func() {
+		ch <- ts.ServeConn(rw)
+	}()
+	select {
+	case err := <-ch:
+		if err != nil {
+			t.Fatal(err)
+			return ""
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatalf("timeout")
+		return ""
+	}
+	br := bufio.NewReader(&rw.w)
+	var resp fasthttp.Response
+	if err := resp.Read(br); err != nil {
+		t.Fatal(err)
+		return ""
+	}
+	return string(resp.Body())
+}
+
+func TestMuxTraceHeader(t *testing.T) {
+	r := NewRouter()
+	r.TraceHeader("X-Chi-Trace")
+	sr := NewRouter()
+	sr.TrailingSlash(TrailingSlashMatch)
+	sr.Get("/users/:id", func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	r.Mount("/api", sr)
+
+	tests := []struct {
+		path  string
+		trace string
+	}{
+		{"/api/users/1", "route GET /api/users/1; GET tree: matched /api/*, 3 nodes visited; serve /api/*; mount /api/* routes /users/1; GET tree: matched /users/:id, 2 nodes visited; serve /api/users/:id"},
+		{"/api/users/1/", "route GET /api/users/1/; GET tree: matched /api/*, 3 nodes visited; serve /api/*; mount /api/* routes /users/1/; GET tree: no match, 3 nodes visited; retry /users/1; GET tree: matched /users/:id, 2 nodes visited; serve /api/users/:id"},
+		{"/nope", "route GET /nope; GET tree: no match, 1 nodes visited; not found"},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.path)
+		fctx.Request.Header.Set("X-Chi-Trace", "1")
+		r.ServeHTTP(&fctx)
+		if trace := string(fctx.Response.Header.Peek("X-Chi-Trace")); trace != tt.trace {
+			t.Fatalf("%s: got trace '%s'", tt.path, trace)
+		}
+	}
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/api/users/1")
+	r.ServeHTTP(&fctx)
+	if trace := fctx.Response.Header.Peek("X-Chi-Trace"); trace != nil {
+		t.Fatalf("got trace '%s' for an untraced request", trace)
+	}
+}
+
+func TestNewRouterOptions(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "env", "test")
+	r := NewRouterContext(ctx,
+		WithTrailingSlashRedirect(),
+		WithCaseInsensitive(),
+		WithMaxParams(2),
+		WithNotFound(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.SetStatusCode(404)
+			fctx.WriteString("lost in " + ctx.Value("env").(string))
+		}),
+	)
+	r.Get("/ping", func(fctx *fasthttp.RequestCtx) {
+		fctx.WriteString("pong")
+	})
+	r.Route("/hubs/:hubID", func(r Router) {
+		r.Get("/users/:userID", func(fctx *fasthttp.RequestCtx) {})
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/ping", 200, "pong"},
+		{"/PING", 200, "pong"},
+		{"/ping/", 301, ""},
+		{"/nothing", 404, "lost in test"},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.path)
+		r.ServeHTTP(&fctx)
+		if fctx.Response.StatusCode() != tt.status || (tt.body != "" && string(fctx.Response.Body()) != tt.body) {
+			t.Fatalf("%s: got %d '%s'", tt.path, fctx.Response.StatusCode(), fctx.Response.Body())
+		}
+	}
+
+	mustPanic := func(name string, fn func()) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("%s: expecting a panic", name)
+			}
+		}()
+		fn()
+	}
+	mustPanic("too many params", func() {
+		r.Get("/a/:x/b/:y/c/:z", func(fctx *fasthttp.RequestCtx) {})
+	})
+	mustPanic("too many subrouter params", func() {
+		r.Route("/orgs", func(r Router) {
+			r.Get("/:x/:y/:z", func(fctx *fasthttp.RequestCtx) {})
+		})
+	})
 }
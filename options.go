@@ -0,0 +1,159 @@
+package chi
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// An Option configures a router created by NewRouter, so its settings are
+// fixed before it serves, ie.
+//
+//	r := chi.NewRouter(chi.WithCaseInsensitive(), chi.WithMaxParams(4))
+type Option func(mx *Mux)
+
+// WithTrailingSlashRedirect redirects the requests matching a route with
+// their trailing slash toggled, see Mux.TrailingSlash.
+func WithTrailingSlashRedirect() Option {
+	return func(mx *Mux) {
+		mx.TrailingSlash(TrailingSlashRedirect)
+	}
+}
+
+// WithCaseInsensitive matches the static segments of route patterns
+// ignoring case, see Mux.CaseInsensitive.
+func WithCaseInsensitive() Option {
+	return func(mx *Mux) {
+		mx.CaseInsensitive(false)
+	}
+}
+
+// WithMaxParams bounds the number of named params of the route patterns of
+// the router and the subrouters it creates with Route, registering a route
+// with more panics. Catch-alls count as params when named, ie. `*filepath`.
+func WithMaxParams(n int) Option {
+	if n < 1 {
+		panic("chi: WithMaxParams expects n > 0")
+	}
+	return func(mx *Mux) {
+		mx.router.maxParams = n
+	}
+}
+
+// WithUnknownMethods sets how the requests of unknown methods are
+// answered, see Mux.UnknownMethods.
+func WithUnknownMethods(policy UnknownMethodPolicy) Option {
+	return func(mx *Mux) {
+		mx.UnknownMethods(policy)
+	}
+}
+
+// WithNotFound sets the handler of missing routes, see Mux.NotFound.
+func WithNotFound(h HandlerFunc) Option {
+	return func(mx *Mux) {
+		mx.NotFound(h)
+	}
+}
+
+// A RouteOption configures a route, passed to the routing methods along
+// with its handlers, ie.
+//
+//	r.Get("/slow", slowHandler, chi.WithTimeout(2*time.Second))
+type RouteOption func(o *routeOptions)
+
+// routeOptions are the settings of a route set with RouteOptions.
+type routeOptions struct {
+	timeout time.Duration
+
+	// Time allowed to read the streamed request body
+	bodyReadTimeout time.Duration
+
+	// Frame ending the streamed responses that panicked
+	streamTerminal []byte
+}
+
+// WithTimeout cancels the context of the requests of the route after the
+// `timeout`, like middleware.Timeout, answering a 504 to the requests that
+// reached it. The timeout covers the inline middlewares of the route, and
+// is reported along the route by the router introspection.
+func WithTimeout(timeout time.Duration) RouteOption {
+	return func(o *routeOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithBodyReadTimeout bounds the time the requests of the route may take to
+// send their body, ie. for uploads, answering a 408 and cancelling the
+// request context once it's spent, so slow clients can't hold a handler
+// up beyond the server's ReadTimeout. Reads of the body, either from
+// fctx.Request.BodyStream() or fctx.PostBody(), fail with
+// ErrBodyReadTimeout by then.
+//
+// Bodies are read by fasthttp before routing unless the server streams
+// them, see fasthttp.Server.StreamRequestBody, so the timeout only guards
+// streamed bodies.
+func WithBodyReadTimeout(timeout time.Duration) RouteOption {
+	return func(o *routeOptions) {
+		o.bodyReadTimeout = timeout
+	}
+}
+
+// streamTerminalKey is the route metadata key of the frame set with
+// WithStreamRecover.
+const streamTerminalKey = "chi.streamTerminal"
+
+// WithStreamRecover makes middleware.Recoverer recover the panics of the
+// body stream writers of the route, ie. of server-sent events, set with
+// middleware.SetBodyStreamWriter. As the status and headers are sent by
+// then, the stream is ended with the `terminal` frame instead of a 500,
+// ie. "event: error\ndata: internal error\n\n", and the connection is
+// closed, so clients detect the failure rather than waiting for more.
+func WithStreamRecover(terminal []byte) RouteOption {
+	return func(o *routeOptions) {
+		o.streamTerminal = append([]byte{}, terminal...)
+	}
+}
+
+// StreamTerminal returns the frame ending the streamed responses that
+// panicked, set with WithStreamRecover on the route matched by the request.
+func StreamTerminal(ctx context.Context) ([]byte, bool) {
+	terminal, ok := RouteMeta(ctx, streamTerminalKey).([]byte)
+	return terminal, ok
+}
+
+// splitRouteOptions separates the RouteOptions from the handlers passed to
+// a routing method.
+func splitRouteOptions(handlers []interface{}) ([]interface{}, routeOptions) {
+	var opts routeOptions
+	hs := handlers[:0:0]
+	for _, h := range handlers {
+		if opt, ok := h.(RouteOption); ok {
+			opt(&opts)
+			continue
+		}
+		hs = append(hs, h)
+	}
+	return hs, opts
+}
+
+// wrap returns the endpoint of a route with its options applied.
+func (o routeOptions) wrap(h Handler) Handler {
+	if o.bodyReadTimeout > 0 {
+		h = bodyReadTimeout(h, o.bodyReadTimeout)
+	}
+	if o.timeout <= 0 {
+		return h
+	}
+	timeout := o.timeout
+	return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer func() {
+			cancel()
+			if ctx.Err() == context.DeadlineExceeded {
+				fctx.SetStatusCode(fasthttp.StatusGatewayTimeout)
+			}
+		}()
+		h.ServeHTTPC(ctx, fctx)
+	})
+}
@@ -0,0 +1,120 @@
+package chi
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// MiddlewareWarnings returns the common ordering mistakes found in the
+// middleware stacks of the Mux and of its mounted subrouters, each stack
+// chained with the stacks of the routers it's mounted on, ie.
+//
+//	admin: middleware.Recoverer runs inside middleware.Throttle, move it up so panics in middleware.Throttle are recovered
+//
+// Middlewares are recognized by name, see Mux.Middlewares: Recoverer should
+// only follow RequestID, RealIP and Logger, Logger and Recoverer should
+// follow RequestID to print request IDs, Logger should come before Compress
+// to see the responses as sent, and CORS should come before the
+// authentication middlewares, their names containing "auth", for preflight
+// requests without credentials to be answered. Inline middlewares of routes
+// aren't checked.
+func (mx *Mux) MiddlewareWarnings() []string {
+	var warnings []string
+	mx.current().checkMiddlewares("/", nil, func(w string) {
+		warnings = append(warnings, w)
+	})
+	return warnings
+}
+
+// WarnMiddlewareOrder logs the MiddlewareWarnings of the Mux, to be called
+// at startup once the routes are defined.
+func (mx *Mux) WarnMiddlewareOrder() {
+	for _, w := range mx.MiddlewareWarnings() {
+		log.Printf("chi: %s", w)
+	}
+}
+
+// checkMiddlewares reports the ordering mistakes of the stack of the Mux
+// chained to the stack of the routers it's mounted on, then checks its
+// mounted subrouters.
+func (mx *Mux) checkMiddlewares(pattern string, stack []string, fn func(w string)) {
+	names := append([]string(nil), stack...)
+	for _, mw := range mx.middlewares {
+		names = append(names, middlewareName(mw))
+	}
+	for _, w := range orderWarnings(names[len(stack):], names) {
+		fn(pattern + ": " + w)
+	}
+
+	mx.router.mu.RLock()
+	entries := append([]*routeEntry(nil), mx.router.entries...)
+	mx.router.mu.RUnlock()
+	for _, e := range entries {
+		if e.mount != nil && strings.HasSuffix(e.pattern, "/*") {
+			e.mount.current().checkMiddlewares(strings.TrimSuffix(pattern, "/")+e.pattern[:len(e.pattern)-2], names, fn)
+		}
+	}
+}
+
+// orderWarnings returns the ordering mistakes involving the `added`
+// middlewares at the end of the `names` stack.
+func orderWarnings(added, names []string) []string {
+	var warnings []string
+	index := func(base string) int {
+		for i, name := range names {
+			if middlewareBase(name) == base {
+				return i
+			}
+		}
+		return -1
+	}
+	isAdded := func(i int) bool {
+		return i >= len(names)-len(added)
+	}
+
+	for i, name := range names {
+		switch middlewareBase(name) {
+		case "Recoverer", "RecovererHook":
+			for _, prev := range names[:i] {
+				switch middlewareBase(prev) {
+				case "RequestID", "RealIP", "Logger":
+					continue
+				}
+				if isAdded(i) {
+					warnings = append(warnings, fmt.Sprintf("%s runs inside %s, move it up so panics in %s are recovered", name, prev, prev))
+				}
+				break
+			}
+		case "Logger":
+			if c := index("Compress"); c >= 0 && c < i && (isAdded(i) || isAdded(c)) {
+				warnings = append(warnings, fmt.Sprintf("%s runs inside %s and sees the responses before compression, use it before %s", name, names[c], names[c]))
+			}
+		case "CORS":
+			for _, prev := range names[:i] {
+				if strings.Contains(strings.ToLower(middlewareBase(prev)), "auth") && isAdded(i) {
+					warnings = append(warnings, fmt.Sprintf("%s runs inside %s, which rejects preflight requests without credentials, use it before %s", name, prev, prev))
+					break
+				}
+			}
+		case "RequestID":
+			for _, prev := range names[:i] {
+				switch middlewareBase(prev) {
+				case "Logger", "Recoverer", "RecovererHook":
+					if isAdded(i) {
+						warnings = append(warnings, fmt.Sprintf("%s runs before %s and prints no request IDs, use it after %s", prev, name, name))
+					}
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// middlewareBase returns the name of a middleware without its package.
+func middlewareBase(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
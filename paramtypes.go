@@ -0,0 +1,131 @@
+package chi
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// paramTypes are the value types available to typed URL param segments,
+// ie. `/orders/:id|int`. A request whose param value doesn't conform to
+// the type won't match the route.
+var paramTypes = map[string]func(string) bool{
+	"int":  isInt,
+	"uuid": isUUID,
+	"slug": isSlug,
+}
+
+// parseParam splits a param segment like ":id|int" into its key and
+// value type. A type starting with "^" is an anchored regexp, as set by
+// the `{id:[0-9]+}` brace syntax. It panics on unknown types and invalid
+// regexps, as routes are registered at startup.
+func parseParam(segment string) (key, typ string) {
+	key = segment[1:]
+	if p := strings.IndexByte(key, '|'); p >= 0 {
+		key, typ = key[:p], key[p+1:]
+		if strings.HasPrefix(typ, "^") {
+			if _, err := regexp.Compile(typ); err != nil {
+				panic(fmt.Sprintf("chi: invalid regexp in segment '%s': %v", segment, err))
+			}
+		} else if _, ok := paramTypes[typ]; !ok {
+			panic(fmt.Sprintf("chi: unknown param type '%s' in segment '%s'", typ, segment))
+		}
+	}
+	return key, typ
+}
+
+// checkParamNames panics if a param name is repeated in the pattern, ie.
+// `/a/:id/b/:id`, whose later value would hide the earlier one, returning
+// the number of named params of the pattern.
+func checkParamNames(pattern string) int {
+	var keys []string
+	for _, segment := range strings.Split(pattern, "/") {
+		var key string
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			key, _ = parseParam(segment)
+		case strings.HasPrefix(segment, "*"):
+			key = catchAllKey(segment)
+		default:
+			continue
+		}
+		for _, k := range keys {
+			if k == key {
+				panic(fmt.Sprintf("chi: param '%s' repeated in pattern '%s'", key, pattern))
+			}
+		}
+		keys = append(keys, key)
+	}
+	n := len(keys)
+	for _, k := range keys {
+		if k == "*" {
+			n--
+		}
+	}
+	return n
+}
+
+// paramValidator returns the func checking values of a param type, or
+// nil for untyped params.
+func paramValidator(typ string) func(string) bool {
+	if typ == "" {
+		return nil
+	}
+	if fn, ok := paramTypes[typ]; ok {
+		return fn
+	}
+	return regexp.MustCompile(typ).MatchString
+}
+
+// isInt reports whether s is a base 10 integer, ie. "42" or "-7".
+func isInt(s string) bool {
+	if s == "" || s[0] == '+' {
+		return false
+	}
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// isUUID reports whether s is a hyphenated UUID in its canonical
+// 8-4-4-4-12 hex form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isHex(s[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isSlug reports whether s is made of lowercase alphanumeric words joined
+// by single hyphens, ie. "going-all-the-way".
+func isSlug(s string) bool {
+	if s == "" || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' && s[i-1] != '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
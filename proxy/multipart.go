@@ -0,0 +1,144 @@
+// Package proxy forwards requests to upstream services.
+package proxy
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+var (
+	// ErrPartTooLarge is the error of a multipart upload with a part larger
+	// than the MaxPartSize of a Multipart forwarder.
+	ErrPartTooLarge = errors.New("proxy: multipart part too large")
+
+	// ErrTooManyParts is the error of a multipart upload with more parts
+	// than the MaxParts of a Multipart forwarder.
+	ErrTooManyParts = errors.New("proxy: too many multipart parts")
+)
+
+// A Doer sends a request to an upstream, ie. a *fasthttp.HostClient or a
+// *fasthttp.Client.
+type Doer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+}
+
+// Multipart is a handler streaming multipart uploads to an upstream part
+// by part, without buffering the whole bodies, so gateways in front of
+// upload services keep a bounded memory whatever the size of the uploads:
+//
+//	up := &proxy.Multipart{
+//		Client:      &fasthttp.HostClient{Addr: "uploads:8080"},
+//		Upstream:    "http://uploads:8080",
+//		MaxPartSize: 64 << 20,
+//	}
+//	r.Post("/uploads/*", up.ServeHTTPC)
+//
+// The request body is read as a stream when the fasthttp server streams
+// request bodies, ie. with Server.StreamRequestBody, and from the buffered
+// body otherwise. Each part is checked against the limits while it's
+// forwarded: an upload exceeding them is aborted upstream and answered
+// with a 413. Requests that aren't multipart are answered with a 415, and
+// upstream failures with a 502.
+type Multipart struct {
+	// Client sending the requests upstream
+	Client Doer
+
+	// Base URL of the upstream, ie. "http://uploads:8080", the request URI
+	// being appended to it
+	Upstream string
+
+	// Max size of a part's body in bytes, unlimited if 0
+	MaxPartSize int64
+
+	// Max number of parts of an upload, unlimited if 0
+	MaxParts int
+}
+
+// ServeHTTPC forwards the multipart upload of the request upstream, and
+// answers with the upstream response.
+func (m *Multipart) ServeHTTPC(ctx context.Context, fctx *fasthttp.RequestCtx) {
+	mediaType, params, err := mime.ParseMediaType(string(fctx.Request.Header.ContentType()))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		fctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnsupportedMediaType), fasthttp.StatusUnsupportedMediaType)
+		return
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	fctx.Request.Header.VisitAll(func(k, v []byte) {
+		req.Header.SetBytesKV(k, v)
+	})
+	req.Header.Del("Connection")
+	req.Header.Del("Transfer-Encoding")
+	req.SetRequestURI(strings.TrimSuffix(m.Upstream, "/") + string(fctx.RequestURI()))
+
+	// The parts are copied into the upstream body as they're read, the
+	// boundary left as is
+	pr, pw := io.Pipe()
+	req.SetBodyStream(pr, -1)
+	copied := make(chan error, 1)
+	go func() {
+		err := m.copyParts(pw, fctx.Request.BodyStream(), params["boundary"])
+		pw.CloseWithError(err)
+		copied <- err
+	}()
+
+	err = m.Client.Do(req, &fctx.Response)
+	// Unblocks the copy of a body the upstream didn't read to its end
+	pr.CloseWithError(io.ErrClosedPipe)
+	if cerr := <-copied; cerr == ErrPartTooLarge || cerr == ErrTooManyParts {
+		fctx.Response.Reset()
+		fctx.Error(cerr.Error(), fasthttp.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		fctx.Response.Reset()
+		fctx.Error(fasthttp.StatusMessage(fasthttp.StatusBadGateway), fasthttp.StatusBadGateway)
+	}
+}
+
+// copyParts copies the parts of the multipart body `src` to `dst`, checking
+// them against the limits of the forwarder.
+func (m *Multipart) copyParts(dst io.Writer, src io.Reader, boundary string) error {
+	mr := multipart.NewReader(src, boundary)
+	mw := multipart.NewWriter(dst)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	for n := 1; ; n++ {
+		// Raw parts, so their Content-Transfer-Encoding is kept
+		part, err := mr.NextRawPart()
+		if err == io.EOF {
+			return mw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		if m.MaxParts > 0 && n > m.MaxParts {
+			return ErrTooManyParts
+		}
+
+		w, err := mw.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+		body := io.Reader(part)
+		if m.MaxPartSize > 0 {
+			body = io.LimitReader(part, m.MaxPartSize+1)
+		}
+		written, err := io.Copy(w, body)
+		if err != nil {
+			return err
+		}
+		if m.MaxPartSize > 0 && written > m.MaxPartSize {
+			return ErrPartTooLarge
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// upstream records the parts of the uploads it's sent, reading them from the
+// request body stream like a client sending them.
+type upstream struct {
+	uri   string
+	parts map[string]string
+	err   error
+}
+
+func (u *upstream) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	u.uri = string(req.RequestURI())
+	_, params, _ := mime.ParseMediaType(string(req.Header.ContentType()))
+	mr := multipart.NewReader(req.BodyStream(), params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			if err != io.EOF {
+				return err
+			}
+			break
+		}
+		b, err := ioutil.ReadAll(part)
+		if err != nil {
+			return err
+		}
+		u.parts[part.FormName()] = string(b)
+	}
+	if u.err != nil {
+		return u.err
+	}
+	resp.SetStatusCode(201)
+	resp.SetBodyString("stored")
+	return nil
+}
+
+func TestMultipart(t *testing.T) {
+	upload := func(parts ...string) (string, []byte) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for i := 0; i < len(parts); i += 2 {
+			w, _ := mw.CreateFormFile(parts[i], parts[i]+".bin")
+			w.Write([]byte(parts[i+1]))
+		}
+		mw.Close()
+		return mw.FormDataContentType(), buf.Bytes()
+	}
+
+	tests := []struct {
+		name        string
+		parts       []string
+		contentType string
+		err         error
+		status      int
+		stored      int
+	}{
+		{"upload", []string{"a", "hello", "b", strings.Repeat("x", 16)}, "", nil, 201, 2},
+		{"part too large", []string{"a", "hello", "b", strings.Repeat("x", 17)}, "", nil, 413, 1},
+		{"too many parts", []string{"a", "1", "b", "2", "c", "3", "d", "4"}, "", nil, 413, 2},
+		{"not multipart", nil, "application/json", nil, 415, 0},
+		{"upstream down", []string{"a", "hello"}, "", errors.New("dial failed"), 502, 1},
+	}
+	for _, tt := range tests {
+		up := &upstream{parts: map[string]string{}, err: tt.err}
+		m := &Multipart{Client: up, Upstream: "http://uploads:8080/", MaxPartSize: 16, MaxParts: 3}
+
+		contentType, body := upload(tt.parts...)
+		if tt.contentType != "" {
+			contentType = tt.contentType
+		}
+		var fctx fasthttp.RequestCtx
+		fctx.Request.Header.SetMethod("POST")
+		fctx.Request.SetRequestURI("/uploads/photos?album=1")
+		fctx.Request.Header.SetContentType(contentType)
+		fctx.Request.SetBodyStream(bytes.NewReader(body), len(body))
+		m.ServeHTTPC(context.Background(), &fctx)
+
+		if fctx.Response.StatusCode() != tt.status || len(up.parts) != tt.stored {
+			t.Fatalf("%s: got %d with %d parts stored", tt.name, fctx.Response.StatusCode(), len(up.parts))
+		}
+		if tt.stored > 0 && up.uri != "http://uploads:8080/uploads/photos?album=1" {
+			t.Fatalf("%s: upstream got '%s'", tt.name, up.uri)
+		}
+		if tt.status == 201 && (up.parts["b"] != strings.Repeat("x", 16) || string(fctx.Response.Body()) != "stored") {
+			t.Fatalf("%s: got parts %v, body '%s'", tt.name, up.parts, fctx.Response.Body())
+		}
+	}
+}
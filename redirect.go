@@ -0,0 +1,49 @@
+package chi
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ErrUnsafeRedirect is the error of redirect locations with control
+// characters, ie. CR/LF splitting the Location header, or bytes that must
+// be percent-encoded.
+var ErrUnsafeRedirect = errors.New("chi: unsafe redirect location")
+
+// ValidRedirect returns ErrUnsafeRedirect if the `location` can't be sent
+// as is in a Location header: empty, or with bytes other than the printable
+// ASCII ones, which must be percent-encoded, ie. with url.PathEscape. Paths
+// starting with `//` or `/\` are rejected too, being taken as
+// protocol-relative URLs to another host by browsers.
+func ValidRedirect(location string) error {
+	if location == "" {
+		return ErrUnsafeRedirect
+	}
+	for i := 0; i < len(location); i++ {
+		if c := location[i]; c <= ' ' || c >= 0x7f {
+			return ErrUnsafeRedirect
+		}
+	}
+	if strings.HasPrefix(location, "//") || strings.HasPrefix(location, "/\\") {
+		return ErrUnsafeRedirect
+	}
+	return nil
+}
+
+// Redirect redirects the request to the `location` with the `code` status,
+// like fasthttp.RequestCtx.Redirect, once checked with ValidRedirect. The
+// response is left as is on errors, for locations built by hand from
+// request data:
+//
+//	if err := chi.Redirect(fctx, "/search?q="+url.QueryEscape(q), 302); err != nil {
+//		fctx.Error("Bad Request", 400)
+//	}
+func Redirect(fctx *fasthttp.RequestCtx, location string, code int) error {
+	if err := ValidRedirect(location); err != nil {
+		return err
+	}
+	fctx.Redirect(location, code)
+	return nil
+}
@@ -0,0 +1,68 @@
+package chi
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestValidRedirect(t *testing.T) {
+	tests := map[string]bool{
+		"/home":                          true,
+		"/search?q=a%20b":                true,
+		"https://example.com/":           true,
+		"":                               false,
+		"/home\r\nSet-Cookie: session=x": false,
+		"/a b":                           false,
+		"/caf\xc3\xa9":                   false,
+		"/\\evil.com":                    false,
+		"//evil.com":                     false,
+	}
+	for location, valid := range tests {
+		if err := ValidRedirect(location); (err == nil) != valid {
+			t.Fatalf("%q: expecting valid %v, got %v", location, valid, err)
+		}
+	}
+
+	var fctx fasthttp.RequestCtx
+	if err := Redirect(&fctx, "/home\nX: 1", 302); err != ErrUnsafeRedirect || fctx.Response.StatusCode() == 302 {
+		t.Fatalf("expecting the redirect to be rejected, got %v", err)
+	}
+}
+
+func TestMuxRedirectEncoding(t *testing.T) {
+	var url string
+	r := NewRouter(WithTrailingSlashRedirect())
+	r.Get("/users/:name", func(fctx *fasthttp.RequestCtx) {})
+
+	tests := []struct {
+		path     string
+		location string
+	}{
+		{"/users/a%20b/", "http://example.com/users/a%20b"},
+		{"/users/a%0D%0ASet-Cookie:%20x=1/", "http://example.com/users/a%0D%0ASet-Cookie:%20x=1"},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.path)
+		fctx.Request.Header.SetHost("example.com")
+		r.ServeHTTP(&fctx)
+		if fctx.Response.StatusCode() != 301 || string(fctx.Response.Header.Peek("Location")) != tt.location {
+			t.Fatalf("%s: got %d '%s'", tt.path, fctx.Response.StatusCode(), fctx.Response.Header.Peek("Location"))
+		}
+	}
+
+	// Catch-alls starting with a slash don't make protocol-relative URLs
+	r = NewRouter()
+	r.Get("/*", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		url, _ = URLFor(ctx, "any", map[string]string{"*": "/evil.com\r\nX: 1"})
+	}).Name("any")
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/x")
+	r.ServeHTTP(&fctx)
+	if url != "/%2Fevil.com%0D%0AX:%201" || ValidRedirect(url) != nil {
+		t.Fatalf("got '%s'", url)
+	}
+}
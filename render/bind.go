@@ -1,13 +1,121 @@
 package render
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"gopkg.in/yaml.v2"
 )
 
+// defaultMaxXMLBody is the number of bytes BindXML reads at most from a
+// body, unless the Renderer sets MaxXMLBody.
+const defaultMaxXMLBody = 1 << 20
+
+// ErrBodyTooLarge is returned by BindXML for bodies over the MaxXMLBody of
+// the Renderer.
+var ErrBodyTooLarge = errors.New("render: request body too large")
+
 // Bind is a short-hand method for decoding a JSON request body.
 func Bind(r io.Reader, v interface{}) error {
 	defer io.Copy(ioutil.Discard, r)
 	return json.NewDecoder(r).Decode(v)
 }
+
+// BindYAML decodes a YAML request body.
+func BindYAML(r io.Reader, v interface{}) error {
+	defer io.Copy(ioutil.Discard, r)
+	return yaml.NewDecoder(r).Decode(v)
+}
+
+// BindBody decodes the request body with the binder of its Content-Type:
+// BindXML for XML, BindYAML for YAML, and Bind for JSON and any other type.
+func BindBody(fctx *fasthttp.RequestCtx, v interface{}) error {
+	return defaultRenderer.BindBody(fctx, v)
+}
+
+// BindXML decodes an XML request body, reading at most 1 MiB of it. Bodies
+// may be encoded in UTF-8, US-ASCII or ISO-8859-1, as declared by their XML
+// declaration, ie. `<?xml version="1.0" encoding="ISO-8859-1"?>`, other
+// charsets fail to decode.
+func BindXML(r io.Reader, v interface{}) error {
+	return defaultRenderer.BindXML(r, v)
+}
+
+// BindBody decodes the request body like the BindBody func, binding XML
+// bodies with the MaxXMLBody of the Renderer.
+func (r *Renderer) BindBody(fctx *fasthttp.RequestCtx, v interface{}) error {
+	body := bytes.NewReader(fctx.PostBody())
+	contentType := string(fctx.Request.Header.ContentType())
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	switch strings.ToLower(strings.TrimSpace(contentType)) {
+	case "application/xml", "text/xml":
+		return r.BindXML(body, v)
+	case "application/yaml", "application/x-yaml", "text/yaml":
+		return BindYAML(body, v)
+	}
+	return Bind(body, v)
+}
+
+// BindXML decodes an XML request body like the BindXML func, reading at
+// most MaxXMLBody bytes of it.
+func (r *Renderer) BindXML(body io.Reader, v interface{}) error {
+	defer io.Copy(ioutil.Discard, body)
+
+	max := r.MaxXMLBody
+	if max <= 0 {
+		max = defaultMaxXMLBody
+	}
+	lr := &io.LimitedReader{R: body, N: max + 1}
+	dec := xml.NewDecoder(lr)
+	dec.CharsetReader = charsetReader
+	err := dec.Decode(v)
+	if lr.N <= 0 {
+		return ErrBodyTooLarge
+	}
+	return err
+}
+
+// charsetReader converts the bodies declaring a charset other than UTF-8
+// to UTF-8.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "l1":
+		return &latin1Reader{r: input}, nil
+	}
+	return nil, fmt.Errorf("render: unsupported charset '%s'", charset)
+}
+
+// latin1Reader decodes an ISO-8859-1 stream to UTF-8.
+type latin1Reader struct {
+	r       io.Reader
+	buf     [512]byte
+	pending []byte
+	err     error
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	for len(l.pending) == 0 {
+		if l.err != nil {
+			return 0, l.err
+		}
+		var n int
+		n, l.err = l.r.Read(l.buf[:])
+		for _, b := range l.buf[:n] {
+			l.pending = append(l.pending, string(rune(b))...)
+		}
+	}
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,65 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestBindXML(t *testing.T) {
+	type article struct {
+		Title string `xml:"title"`
+	}
+
+	var a article
+	body := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<article><title>Caf\xe9</title></article>"
+	if err := BindXML(strings.NewReader(body), &a); err != nil || a.Title != "Café" {
+		t.Fatalf("got '%s' (%v)", a.Title, err)
+	}
+
+	body = `<?xml version="1.0" encoding="EBCDIC"?><article/>`
+	if err := BindXML(strings.NewReader(body), &a); err == nil {
+		t.Fatal("expecting an unsupported charset to fail")
+	}
+
+	renderer := &Renderer{MaxXMLBody: 64}
+	body = "<article><title>" + strings.Repeat("chi ", 20) + "</title></article>"
+	if err := renderer.BindXML(strings.NewReader(body), &a); err != ErrBodyTooLarge {
+		t.Fatalf("expecting ErrBodyTooLarge, got %v", err)
+	}
+	if err := BindXML(strings.NewReader(body), &a); err != nil {
+		t.Fatalf("expecting the package default to read the body, got %v", err)
+	}
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.SetContentType("application/xml")
+	fctx.Request.SetBodyString(body)
+	if err := renderer.BindBody(&fctx, &a); err != ErrBodyTooLarge {
+		t.Fatalf("expecting ErrBodyTooLarge, got %v", err)
+	}
+}
+
+func TestBindBody(t *testing.T) {
+	type config struct {
+		Name     string `json:"name" yaml:"name"`
+		Replicas int    `json:"replicas" yaml:"replicas"`
+	}
+
+	tests := []struct {
+		contentType, body string
+	}{
+		{"application/yaml", "name: api\nreplicas: 3\n"},
+		{"application/x-yaml; charset=utf-8", "name: api\nreplicas: 3\n"},
+		{"application/json", `{"name":"api","replicas":3}`},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.Header.SetContentType(tt.contentType)
+		fctx.Request.SetBodyString(tt.body)
+		var c config
+		if err := BindBody(&fctx, &c); err != nil || c != (config{"api", 3}) {
+			t.Fatalf("%s: got %+v (%v)", tt.contentType, c, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// putBuffer returns a buffer to the pool, unless it grew over `max` bytes.
+func putBuffer(buf *bytes.Buffer, max int) {
+	if buf.Cap() > max {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// writeJSON renders `v` as JSON, through a pooled buffer when enabled with
+// MaxBufferedBody.
+func (r *Renderer) writeJSON(fctx *fasthttp.RequestCtx, status int, v interface{}) error {
+	const contentType = "application/json; charset=utf-8"
+
+	max := r.MaxBufferedBody
+	if max <= 0 {
+		b, err := marshalJSON(v)
+		if err != nil {
+			return err
+		}
+		r.writeBody(fctx, status, contentType, b)
+		return nil
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		putBuffer(buf, max)
+		return err
+	}
+	buf.Truncate(buf.Len() - 1) // Encode ends the value with a newline
+
+	if buf.Len() <= max {
+		r.writeBody(fctx, status, contentType, buf.Bytes())
+		putBuffer(buf, max)
+		return nil
+	}
+
+	// Spill the body over to the body stream, which fasthttp reads to the
+	// connection without copying it to the response buffer. The buffer grew
+	// over the max, so it's left out of the pool.
+	n := buf.Len()
+	fctx.Response.Header.Set("Content-Type", contentType)
+	fctx.SetStatusCode(status)
+	fctx.Response.SetBodyStream(bytes.NewReader(buf.Bytes()), n)
+	r.setContentHeaders(fctx, n)
+	return nil
+}
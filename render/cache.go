@@ -0,0 +1,237 @@
+package render
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hmgle/chi"
+	"github.com/hmgle/chi/middleware"
+	"github.com/valyala/fasthttp"
+)
+
+// A Cache memoizes the serialized bytes of immutable responses, and their
+// gzip compressed form, keyed by a version provided by the caller. Hot
+// endpoints returning the same document then marshal and compress it once
+// per version instead of once per request:
+//
+//	var articleCache = render.NewCache(1000)
+//
+//	func getArticle(ctx context.Context, fctx *fasthttp.RequestCtx) {
+//		article := ctx.Value("article").(*Article)
+//		articleCache.JSON(fctx, 200, article.ID+"-"+article.Revision, article)
+//	}
+//
+// The version is also sent in the response's ETag, along with the format of
+// the response so the JSON and XML renders of a version don't match each
+// other, and requests with a matching If-None-Match header are answered with
+// a 304. Characters not allowed in ETags, ie. quotes and spaces, are
+// percent-encoded.
+//
+// A new version must be used whenever the response changes, as the cache
+// doesn't look at `v` once the version is cached, until MaxAge elapsed.
+type Cache struct {
+	// Bodies smaller than MinGzipSize bytes are never compressed.
+	MinGzipSize int
+
+	// Renderer whose content header settings apply to the responses, the
+	// one of the package funcs when nil
+	Renderer *Renderer
+
+	// Time a cached body is served for before `v` is marshalled again,
+	// ie. for versions bumped less often than the responses change,
+	// unlimited if 0
+	MaxAge time.Duration
+
+	// Clock timing MaxAge, middleware.DefaultClock as of NewCache
+	Clock middleware.Clock
+
+	mu      sync.Mutex
+	max     int
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type cacheEntry struct {
+	key    string
+	body   []byte
+	stored time.Time
+
+	gzipOnce sync.Once
+	gzip     []byte
+}
+
+// NewCache returns a Cache holding up to `maxEntries` responses, evicting
+// the least recently used ones first.
+func NewCache(maxEntries int) *Cache {
+	return &Cache{
+		MinGzipSize: 512,
+		Clock:       middleware.DefaultClock,
+		max:         maxEntries,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+// JSON renders `v` as JSON like the JSON func, reusing the bytes cached for
+// `version` if any.
+func (c *Cache) JSON(fctx *fasthttp.RequestCtx, status int, version string, v interface{}) {
+	c.serve(fctx, status, version, "json", "application/json; charset=utf-8", func() ([]byte, error) {
+		return marshalJSON(v)
+	})
+}
+
+// XML renders `v` as XML like the XML func, reusing the bytes cached for
+// `version` if any.
+func (c *Cache) XML(fctx *fasthttp.RequestCtx, status int, version string, v interface{}) {
+	c.serve(fctx, status, version, "xml", "application/xml; charset=utf-8", func() ([]byte, error) {
+		return marshalXML(v)
+	})
+}
+
+// Len returns the number of cached responses.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Purge drops all cached responses.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+func (c *Cache) serve(fctx *fasthttp.RequestCtx, status int, version, format, contentType string, marshal func() ([]byte, error)) {
+	e, err := c.entry(contentType+" "+version, marshal)
+	if err != nil {
+		c.renderer().marshalError(fctx, err)
+		return
+	}
+
+	etag := cacheETag(version, format)
+	fctx.Response.Header.Set("Content-Type", contentType)
+	fctx.Response.Header.Set("ETag", etag)
+	fctx.Response.Header.Set("Vary", "Accept-Encoding")
+
+	if status == fasthttp.StatusOK && etagMatch(fctx, etag) {
+		fctx.SetStatusCode(fasthttp.StatusNotModified)
+		return
+	}
+
+	fctx.SetStatusCode(status)
+	body := e.body
+	if len(body) >= c.MinGzipSize && acceptsGzip(fctx) {
+		fctx.Response.Header.Set("Content-Encoding", "gzip")
+		body = e.gzipped()
+	}
+	c.renderer().setContentHeaders(fctx, len(body))
+	fctx.Write(body)
+}
+
+// renderer returns the Renderer of the cache, or the one of the package
+// funcs.
+func (c *Cache) renderer() *Renderer {
+	if c.Renderer != nil {
+		return c.Renderer
+	}
+	return defaultRenderer
+}
+
+// entry returns the cached entry for `key`, marshalling and storing a new
+// one if needed. Marshalling happens outside of the lock, so concurrent
+// misses on the same key may marshal more than once.
+func (c *Cache) entry(key string, marshal func() ([]byte, error)) (*cacheEntry, error) {
+	clock := c.Clock
+	if clock == nil {
+		clock = middleware.DefaultClock
+	}
+
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		if e := el.Value.(*cacheEntry); !c.expired(e, clock) {
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+			return e, nil
+		}
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+
+	b, err := marshal()
+	if err != nil {
+		return nil, err
+	}
+	e := &cacheEntry{key: key, body: b, stored: clock.Now()}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		// Stored by a concurrent miss meanwhile
+		c.lru.Remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.max > 0 && c.lru.Len() > c.max {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.entries, el.Value.(*cacheEntry).key)
+	}
+	return e, nil
+}
+
+// expired reports whether the entry is older than MaxAge.
+func (c *Cache) expired(e *cacheEntry, clock middleware.Clock) bool {
+	return c.MaxAge > 0 && clock.Now().Sub(e.stored) >= c.MaxAge
+}
+
+// cacheETag returns the ETag of the `format` render of `version`,
+// percent-encoding the bytes not allowed in an ETag and the percent sign.
+func cacheETag(version, format string) string {
+	const hex = "0123456789ABCDEF"
+	b := make([]byte, 0, len(version)+len(format)+3)
+	b = append(b, '"')
+	for i := 0; i < len(version); i++ {
+		if c := version[i]; c <= ' ' || c == '"' || c == '%' || c == 0x7f {
+			b = append(b, '%', hex[c>>4], hex[c&0xf])
+		} else {
+			b = append(b, c)
+		}
+	}
+	b = append(b, '-')
+	b = append(b, format...)
+	return string(append(b, '"'))
+}
+
+func (e *cacheEntry) gzipped() []byte {
+	e.gzipOnce.Do(func() {
+		e.gzip = fasthttp.AppendGzipBytes(nil, e.body)
+	})
+	return e.gzip
+}
+
+// acceptsGzip reports whether the client accepts gzip encoded responses.
+func acceptsGzip(fctx *fasthttp.RequestCtx) bool {
+	for _, qv := range chi.QValues(fctx, "Accept-Encoding") {
+		if qv.Value == "gzip" || qv.Value == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatch reports whether the request's If-None-Match header matches
+// `etag`, using the weak comparison.
+func etagMatch(fctx *fasthttp.RequestCtx, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range chi.HeaderList(fctx, "If-None-Match") {
+		tag := strings.TrimPrefix(string(v), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hmgle/chi/middleware"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCache(t *testing.T) {
+	c := NewCache(2)
+	c.MinGzipSize = 0
+
+	type article struct{ Title string }
+	doc := map[string]string{"title": strings.Repeat("chi ", 10)}
+
+	var fctx fasthttp.RequestCtx
+	c.JSON(&fctx, 200, "v1", doc)
+	body := string(fctx.Response.Body())
+
+	var gctx fasthttp.RequestCtx
+	gctx.Request.Header.Set("Accept-Encoding", "gzip, deflate")
+	c.JSON(&gctx, 200, "v1", map[string]string{"title": "stale"})
+	if string(gctx.Response.Header.Peek("Content-Encoding")) != "gzip" {
+		t.Fatalf("expecting a gzip encoded response")
+	}
+	b, err := gctx.Response.BodyGunzip()
+	if err != nil || string(b) != body {
+		t.Fatalf("expecting cached body %s, got %s (%v)", body, b, err)
+	}
+
+	var nctx fasthttp.RequestCtx
+	nctx.Request.Header.Set("If-None-Match", "W/"+string(fctx.Response.Header.Peek("ETag")))
+	c.JSON(&nctx, 200, "v1", nil)
+	if nctx.Response.StatusCode() != fasthttp.StatusNotModified {
+		t.Fatalf("expecting 304, got %d", nctx.Response.StatusCode())
+	}
+
+	// The XML render of v1 doesn't match the JSON one cached by the client
+	var xctx fasthttp.RequestCtx
+	xctx.Request.Header.Set("If-None-Match", string(fctx.Response.Header.Peek("ETag")))
+	c.XML(&xctx, 200, "v1", &article{"chi"})
+	if xctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("expecting the XML render to be served, got %d", xctx.Response.StatusCode())
+	}
+
+	// Evicts v1
+	c.JSON(&fasthttp.RequestCtx{}, 200, "v2", doc)
+	c.XML(&fasthttp.RequestCtx{}, 200, "v2", &article{"chi"})
+	if c.Len() != 2 {
+		t.Fatalf("expecting 2 cached entries, got %d", c.Len())
+	}
+}
+
+func TestCacheETag(t *testing.T) {
+	tests := map[string]string{
+		"v1":       `"v1-json"`,
+		`v1" , "*`: `"v1%22%20,%20%22*-json"`,
+		"50%\n":    `"50%25%0A-json"`,
+	}
+	for version, expected := range tests {
+		if etag := cacheETag(version, "json"); etag != expected {
+			t.Fatalf("%q: expecting %s, got %s", version, expected, etag)
+		}
+	}
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	clock := middleware.NewManualClock(time.Now())
+	c := NewCache(10)
+	c.MaxAge = time.Minute
+	c.Clock = clock
+
+	render := func(title string) string {
+		var fctx fasthttp.RequestCtx
+		c.JSON(&fctx, 200, "v1", map[string]string{"title": title})
+		return string(fctx.Response.Body())
+	}
+	render("first")
+	clock.Advance(59 * time.Second)
+	if body := render("second"); body != `{"title":"first"}` {
+		t.Fatalf("expecting the cached body, got %s", body)
+	}
+	clock.Advance(time.Second)
+	if body := render("second"); body != `{"title":"second"}` {
+		t.Fatalf("expecting the body to be marshalled again once expired, got %s", body)
+	}
+	if c.Len() != 1 {
+		t.Fatalf("expecting 1 cached entry, got %d", c.Len())
+	}
+}
@@ -1,8 +1,6 @@
 package render
 
 import (
-	"strings"
-
 	"github.com/hmgle/chi"
 	"github.com/valyala/fasthttp"
 	"golang.org/x/net/context"
@@ -17,39 +15,48 @@ const (
 	ContentTypeJSON
 	ContentTypeEventStream
 	ContentTypeXML
+	ContentTypeYAML
 )
 
 func ParseContentType(next chi.Handler) chi.Handler {
 	return chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
-		var contentType ContentType
-
-		// Parse request Accept header.
-		fields := strings.Split(string(fctx.Request.Header.Peek("Accept")), ",")
-		if len(fields) > 0 {
-			switch strings.TrimSpace(fields[0]) {
-			case "text/plain":
-				contentType = ContentTypePlainText
-			case "text/html", "application/xhtml+xml":
-				contentType = ContentTypeHTML
-			case "application/json", "text/javascript":
-				contentType = ContentTypeJSON
-			case "text/event-stream":
-				contentType = ContentTypeEventStream
-			case "text/xml":
-				contentType = ContentTypeXML
-			default:
-				contentType = ContentTypeJSON
-			}
-		}
+		ctx = context.WithValue(ctx, "contentType", negotiateContentType(fctx))
+		next.ServeHTTPC(ctx, fctx)
+	})
+}
 
-		// TODO
-		// Explicitly requested stream.
-		// if _, ok := r.URL.Query()["stream"]; ok {
-		if fctx.URI().QueryArgs().Peek("stream") != nil {
+// negotiateContentType returns the content type to render for the request.
+func negotiateContentType(fctx *fasthttp.RequestCtx) ContentType {
+	contentType := ContentType(ContentTypeJSON)
+
+	// Parse request Accept header, preferring the highest quality
+	// media type we know how to render.
+accept:
+	for _, qv := range chi.QValues(fctx, "Accept") {
+		switch qv.Value {
+		case "text/plain":
+			contentType = ContentTypePlainText
+		case "text/html", "application/xhtml+xml":
+			contentType = ContentTypeHTML
+		case "application/json", "text/javascript":
+			contentType = ContentTypeJSON
+		case "text/event-stream":
 			contentType = ContentTypeEventStream
+		case "application/xml", "text/xml":
+			contentType = ContentTypeXML
+		case "application/yaml", "application/x-yaml", "text/yaml":
+			contentType = ContentTypeYAML
+		default:
+			continue
 		}
-
-		ctx = context.WithValue(ctx, "contentType", contentType)
-		next.ServeHTTPC(ctx, fctx)
-	})
+		break accept
+	}
+
+	// TODO
+	// Explicitly requested stream.
+	// if _, ok := r.URL.Query()["stream"]; ok {
+	if fctx.URI().QueryArgs().Peek("stream") != nil {
+		contentType = ContentTypeEventStream
+	}
+	return contentType
 }
@@ -0,0 +1,149 @@
+package render
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+)
+
+// CursorParam is the query param of the cursors sent by polling clients,
+// see RequestCursor.
+var CursorParam = "cursor"
+
+// ErrInvalidCursor is returned for cursors that don't decode.
+var ErrInvalidCursor = errors.New("render: invalid cursor")
+
+// EncodeCursor returns `v`, the state of a collection a client synced to,
+// ie. the sequence number of the last change it got, encoded as an opaque
+// cursor.
+func EncodeCursor(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor decodes a cursor returned by EncodeCursor into `v`.
+func DecodeCursor(cursor string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// RequestCursor decodes the cursor sent by the client into `v`, reporting
+// whether the request has one, either in the CursorParam query param or,
+// for the delta encoding of RFC 3229, in an If-None-Match header along an
+// `A-IM: feed` header. Requests without a cursor get the full collection.
+func RequestCursor(fctx *fasthttp.RequestCtx, v interface{}) (bool, error) {
+	cursor := string(fctx.QueryArgs().Peek(CursorParam))
+	if cursor == "" && deltaEncoded(fctx) {
+		cursor = strings.Trim(strings.TrimPrefix(string(fctx.Request.Header.Peek("If-None-Match")), "W/"), `"`)
+	}
+	if cursor == "" {
+		return false, nil
+	}
+	return true, DecodeCursor(cursor, v)
+}
+
+// deltaEncoded reports whether the client asks for the delta encoding of
+// RFC 3229, with an `A-IM: feed` header and an ETag to diff against.
+func deltaEncoded(fctx *fasthttp.RequestCtx) bool {
+	if len(fctx.Request.Header.Peek("If-None-Match")) == 0 {
+		return false
+	}
+	for _, qv := range chi.QValues(fctx, "A-IM") {
+		if qv.Value == "feed" && qv.Q > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// A Delta is the envelope of the changes of a collection since the cursor
+// sent by a polling client, see ResponseWriter.Delta.
+type Delta struct {
+	XMLName xml.Name `json:"-" xml:"delta" yaml:"-"`
+
+	// Items changed since the cursor, or all the items when Full
+	Changed interface{} `json:"changed" xml:"changed" yaml:"changed"`
+
+	// IDs of the items deleted since the cursor
+	Deleted []string `json:"deleted,omitempty" xml:"deleted>id,omitempty" yaml:"deleted,omitempty"`
+
+	// Full tells the client to replace its items rather than apply the
+	// changes, ie. when its cursor is too old to diff against
+	Full bool `json:"full,omitempty" xml:"full,attr,omitempty" yaml:"full,omitempty"`
+
+	// Cursor of the collection to send with the next request, set by
+	// ResponseWriter.Delta
+	Cursor string `json:"cursor" xml:"cursor,attr" yaml:"cursor"`
+}
+
+// empty reports whether the delta holds no changes.
+func (d *Delta) empty() bool {
+	if d.Full || len(d.Deleted) > 0 {
+		return false
+	}
+	if d.Changed == nil {
+		return true
+	}
+	v := reflect.ValueOf(d.Changed)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() == 0
+	}
+	return false
+}
+
+// Delta renders the changes of a collection since the cursor of the request
+// along the `cursor` of the collection, encoded with EncodeCursor, for the
+// sync endpoints of mobile clients:
+//
+//	func syncArticles(ctx context.Context, fctx *fasthttp.RequestCtx) {
+//		var since int64
+//		ok, err := render.RequestCursor(fctx, &since)
+//		if err != nil {
+//			render.Writer(ctx, fctx).BadRequest(err)
+//			return
+//		}
+//		changed, deleted, seq := db.ArticlesSince(since)
+//		render.Writer(ctx, fctx).Delta(&render.Delta{Changed: changed, Deleted: deleted, Full: !ok}, seq)
+//	}
+//
+// The cursor is sent in the ETag header too. Requests asking for the delta
+// encoding of RFC 3229 are answered with a 226 and an `IM: feed` header, or
+// with a 304 when nothing changed, and the others with a 200.
+func (w *ResponseWriter) Delta(d *Delta, cursor interface{}) {
+	c, err := EncodeCursor(cursor)
+	if err != nil {
+		w.marshalError(err)
+		return
+	}
+	delta := *d
+	delta.Cursor = c
+	delta.Changed = emptySlice(delta.Changed)
+	w.fctx.Response.Header.Set("ETag", `"`+c+`"`)
+
+	status := fasthttp.StatusOK
+	if deltaEncoded(w.fctx) && !delta.Full {
+		if delta.empty() {
+			w.fctx.SetStatusCode(fasthttp.StatusNotModified)
+			return
+		}
+		w.fctx.Response.Header.Set("IM", "feed")
+		status = fasthttp.StatusIMUsed
+	}
+	w.Respond(status, &delta)
+}
@@ -0,0 +1,58 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestDelta(t *testing.T) {
+	cursor, err := EncodeCursor(41)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/articles?cursor=" + cursor)
+	var since int
+	if ok, err := RequestCursor(&fctx, &since); !ok || err != nil || since != 41 {
+		t.Fatalf("expecting cursor 41, got %d (%v, %v)", since, ok, err)
+	}
+	Writer(context.Background(), &fctx).Delta(&Delta{Changed: []string{"a"}, Deleted: []string{"b"}}, 42)
+	next, _ := EncodeCursor(42)
+	expected := `{"changed":["a"],"deleted":["b"],"cursor":"` + next + `"}`
+	if body := string(fctx.Response.Body()); fctx.Response.StatusCode() != 200 || body != expected {
+		t.Fatalf("got %d '%s'", fctx.Response.StatusCode(), body)
+	}
+	if etag := string(fctx.Response.Header.Peek("ETag")); etag != `"`+next+`"` {
+		t.Fatalf("got ETag %s", etag)
+	}
+
+	var dctx fasthttp.RequestCtx
+	dctx.Request.Header.Set("A-IM", "feed")
+	dctx.Request.Header.Set("If-None-Match", `"`+cursor+`"`)
+	since = 0
+	if ok, err := RequestCursor(&dctx, &since); !ok || err != nil || since != 41 {
+		t.Fatalf("expecting cursor 41, got %d (%v, %v)", since, ok, err)
+	}
+	Writer(context.Background(), &dctx).Delta(&Delta{Changed: []string{"a"}}, 42)
+	if dctx.Response.StatusCode() != fasthttp.StatusIMUsed || string(dctx.Response.Header.Peek("IM")) != "feed" {
+		t.Fatalf("expecting a 226, got %d", dctx.Response.StatusCode())
+	}
+
+	dctx.Response.Reset()
+	Writer(context.Background(), &dctx).Delta(&Delta{Changed: []string{}}, 41)
+	if dctx.Response.StatusCode() != fasthttp.StatusNotModified {
+		t.Fatalf("expecting a 304, got %d", dctx.Response.StatusCode())
+	}
+
+	var nctx fasthttp.RequestCtx
+	if ok, err := RequestCursor(&nctx, &since); ok || err != nil {
+		t.Fatalf("expecting no cursor, got %v (%v)", ok, err)
+	}
+	nctx.Request.SetRequestURI("/articles?cursor=bogus")
+	if _, err := RequestCursor(&nctx, &since); err != ErrInvalidCursor {
+		t.Fatalf("expecting ErrInvalidCursor, got %v", err)
+	}
+}
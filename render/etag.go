@@ -0,0 +1,50 @@
+package render
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// CollectionETag returns a weak ETag for a list response, computed from a
+// version vector of the collection provided by the caller: values changing
+// whenever the list does, ie. the latest update time of its items and their
+// count, so the ETag of a list is known without rendering it:
+//
+//	func listArticles(ctx context.Context, fctx *fasthttp.RequestCtx) {
+//		updatedAt, count := db.ArticlesVersion()
+//		if render.NotModified(fctx, render.CollectionETag(updatedAt, count)) {
+//			return
+//		}
+//		render.JSON(fctx, 200, db.Articles())
+//	}
+//
+// Times are compared at their instant, whatever their location.
+func CollectionETag(version ...interface{}) string {
+	h := fnv.New64a()
+	for _, v := range version {
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC().Format(time.RFC3339Nano)
+		}
+		fmt.Fprintf(h, "%v\x00", v)
+	}
+	return fmt.Sprintf(`W/"%x"`, h.Sum64())
+}
+
+// NotModified sets the `etag` of the response, and answers GET and HEAD
+// requests whose If-None-Match header matches it with a 304, returning true
+// so the handler skips rendering the response.
+func NotModified(fctx *fasthttp.RequestCtx, etag string) bool {
+	fctx.Response.Header.Set("ETag", etag)
+	if !fctx.IsGet() && !fctx.IsHead() {
+		return false
+	}
+	if !etagMatch(fctx, etag) {
+		return false
+	}
+	fctx.ResetBody()
+	fctx.SetStatusCode(fasthttp.StatusNotModified)
+	return true
+}
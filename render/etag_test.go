@@ -0,0 +1,43 @@
+package render
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestCollectionETag(t *testing.T) {
+	updatedAt := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+	etag := CollectionETag(updatedAt, 10)
+	if etag[:3] != `W/"` {
+		t.Fatalf("expecting a weak ETag, got %s", etag)
+	}
+	if other := CollectionETag(updatedAt.In(time.FixedZone("CET", 3600)), 10); other != etag {
+		t.Fatalf("expecting the same ETag for the same instant, got %s and %s", etag, other)
+	}
+	if other := CollectionETag(updatedAt, 11); other == etag {
+		t.Fatalf("expecting a new ETag for a new count")
+	}
+
+	var fctx fasthttp.RequestCtx
+	if NotModified(&fctx, etag) {
+		t.Fatal("expecting a request without If-None-Match to be served")
+	}
+	if string(fctx.Response.Header.Peek("ETag")) != etag {
+		t.Fatalf("expecting the ETag to be set")
+	}
+
+	var nctx fasthttp.RequestCtx
+	nctx.Request.Header.Set("If-None-Match", etag)
+	if !NotModified(&nctx, etag) || nctx.Response.StatusCode() != fasthttp.StatusNotModified {
+		t.Fatalf("expecting a 304, got %d", nctx.Response.StatusCode())
+	}
+
+	var pctx fasthttp.RequestCtx
+	pctx.Request.Header.SetMethod("POST")
+	pctx.Request.Header.Set("If-None-Match", etag)
+	if NotModified(&pctx, etag) {
+		t.Fatal("expecting a POST request to be served")
+	}
+}
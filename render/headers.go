@@ -0,0 +1,35 @@
+package render
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// languageKey is the user value holding the language set with SetLanguage.
+const languageKey = "render.language"
+
+// SetLanguage sets the language of the responses rendered for the request,
+// sent in their Content-Language header, ie. by an i18n middleware once it
+// negotiated the language of the request.
+func SetLanguage(fctx *fasthttp.RequestCtx, lang string) {
+	fctx.SetUserValue(languageKey, lang)
+}
+
+// writeBody writes a rendered body with its content headers.
+func (r *Renderer) writeBody(fctx *fasthttp.RequestCtx, status int, contentType string, b []byte) {
+	fctx.Response.Header.Set("Content-Type", contentType)
+	r.setContentHeaders(fctx, len(b))
+	fctx.SetStatusCode(status)
+	fctx.Write(b)
+}
+
+// setContentHeaders sets the Content-Length and Content-Language headers of
+// a rendered body, unless the Renderer omits them.
+func (r *Renderer) setContentHeaders(fctx *fasthttp.RequestCtx, n int) {
+	if r.OmitContentHeaders {
+		return
+	}
+	fctx.Response.Header.SetContentLength(n)
+	if lang, _ := fctx.UserValue(languageKey).(string); lang != "" {
+		fctx.Response.Header.Set("Content-Language", lang)
+	}
+}
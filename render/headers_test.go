@@ -0,0 +1,27 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestContentHeaders(t *testing.T) {
+	var fctx fasthttp.RequestCtx
+	SetLanguage(&fctx, "fr")
+	JSON(&fctx, 200, map[string]string{"title": "chi"})
+	if n := fctx.Response.Header.ContentLength(); n != len(`{"title":"chi"}`) {
+		t.Fatalf("got Content-Length %d", n)
+	}
+	if lang := string(fctx.Response.Header.Peek("Content-Language")); lang != "fr" {
+		t.Fatalf("got Content-Language '%s'", lang)
+	}
+
+	r := &Renderer{OmitContentHeaders: true}
+	fctx = fasthttp.RequestCtx{}
+	SetLanguage(&fctx, "fr")
+	r.String(&fctx, 200, "chi")
+	if lang := fctx.Response.Header.Peek("Content-Language"); lang != nil {
+		t.Fatalf("expecting no Content-Language, got '%s'", lang)
+	}
+}
@@ -0,0 +1,57 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// A PartialResult is a slice rendered element by element, leaving out the
+// elements failing to marshal rather than failing the whole response, for
+// the long running reports preferring partial data over a 500. It renders
+// as JSON, whatever the negotiated content type, with the failures listed
+// in its meta:
+//
+//	{"data": [{"id": 1}, {"id": 3}], "meta": {"errors": [{"index": 1, "error": "json: unsupported value: NaN"}]}}
+type PartialResult struct {
+	Data []json.RawMessage `json:"data"`
+	Meta PartialMeta       `json:"meta"`
+
+	errs []error
+}
+
+// PartialMeta lists the elements left out of a PartialResult.
+type PartialMeta struct {
+	Errors []ElementError `json:"errors,omitempty"`
+}
+
+// An ElementError is the error of an element of a PartialResult failing to
+// marshal, at its index in the slice.
+type ElementError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// Partial marshals the elements of the slice or array `v` one by one into
+// a PartialResult, to be rendered with Respond or a ResponseWriter, which
+// also reports the failures to the OnError hooks of the Mux:
+//
+//	render.Writer(ctx, fctx).OK(render.Partial(rows))
+func Partial(v interface{}) *PartialResult {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		panic(fmt.Sprintf("render: Partial expects a slice, got %T", v))
+	}
+
+	p := &PartialResult{Data: make([]json.RawMessage, 0, val.Len())}
+	for i := 0; i < val.Len(); i++ {
+		b, err := marshalJSON(val.Index(i).Interface())
+		if err != nil {
+			p.Meta.Errors = append(p.Meta.Errors, ElementError{Index: i, Error: err.Error()})
+			p.errs = append(p.errs, fmt.Errorf("render: element %d: %v", i, err))
+			continue
+		}
+		p.Data = append(p.Data, b)
+	}
+	return p
+}
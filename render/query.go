@@ -0,0 +1,301 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultQueryParser is the QueryParser used by ParseQuery and BindQuery.
+var DefaultQueryParser = &QueryParser{MaxDepth: 5}
+
+// ParseQuery parses the URL query of the request with the DefaultQueryParser.
+func ParseQuery(fctx *fasthttp.RequestCtx) (map[string]interface{}, error) {
+	return DefaultQueryParser.Parse(fctx.QueryArgs())
+}
+
+// BindQuery decodes the URL query of the request into the struct or map
+// pointed to by v with the DefaultQueryParser.
+func BindQuery(fctx *fasthttp.RequestCtx, v interface{}) error {
+	return DefaultQueryParser.Decode(fctx.QueryArgs(), v)
+}
+
+// A QueryParser parses URL query args using the bracket syntax for lists
+// and nested objects, ie.
+//
+//	a[]=1&a[]=2&filter[author][id]=3&sort=title
+//
+// is parsed as
+//
+//	map[string]interface{}{
+//		"a":      []string{"1", "2"},
+//		"filter": map[string]interface{}{"author": map[string]interface{}{"id": "3"}},
+//		"sort":   "title",
+//	}
+//
+// Repeating a plain key also makes a list. Keys nested deeper than MaxDepth
+// levels are rejected with an error.
+type QueryParser struct {
+	// Maximum number of bracket segments in a key, or no limit if zero
+	MaxDepth int
+}
+
+// Parse parses query args into a tree of maps holding string and []string
+// values.
+func (p *QueryParser) Parse(args *fasthttp.Args) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+
+	var err error
+	args.VisitAll(func(k, v []byte) {
+		if err != nil {
+			return
+		}
+		err = p.set(m, string(k), string(v))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Decode parses query args into the struct or map pointed to by v. Struct
+// fields are named by their `query` tag, or by the field name otherwise,
+// and string values are converted to the field's type.
+func (p *QueryParser) Decode(args *fasthttp.Args, v interface{}) error {
+	m, err := p.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("render: query decode target must be a non-nil pointer")
+	}
+	return decodeQueryValue(rv.Elem(), m, "")
+}
+
+// set stores a single key/value pair of the query into m.
+func (p *QueryParser) set(m map[string]interface{}, key, value string) error {
+	segments, err := p.splitKey(key)
+	if err != nil {
+		return err
+	}
+
+	for _, seg := range segments[:len(segments)-1] {
+		if seg == "" {
+			return fmt.Errorf("render: query key '%s' has a list segment before its end", key)
+		}
+	}
+
+	for i, seg := range segments {
+		last := i == len(segments)-1
+
+		if seg == "" {
+			continue // handled by the parent segment below
+		}
+
+		if last || segments[i+1] == "" {
+			// Leaf value, a list if the key ends with [] or is repeated
+			switch cur := m[seg].(type) {
+			case nil:
+				if !last {
+					m[seg] = []string{value}
+				} else {
+					m[seg] = value
+				}
+			case string:
+				m[seg] = []string{cur, value}
+			case []string:
+				m[seg] = append(cur, value)
+			default:
+				return fmt.Errorf("render: query key '%s' conflicts with an object", key)
+			}
+			return nil
+		}
+
+		// Nested object
+		switch cur := m[seg].(type) {
+		case nil:
+			child := map[string]interface{}{}
+			m[seg] = child
+			m = child
+		case map[string]interface{}:
+			m = cur
+		default:
+			return fmt.Errorf("render: query key '%s' conflicts with a value", key)
+		}
+	}
+	return nil
+}
+
+// splitKey splits a key like `filter[author][id]` into its segments, an
+// empty segment standing for a list.
+func (p *QueryParser) splitKey(key string) ([]string, error) {
+	i := strings.IndexByte(key, '[')
+	if i < 0 {
+		return []string{key}, nil
+	}
+	if i == 0 {
+		return nil, fmt.Errorf("render: query key '%s' has no name", key)
+	}
+
+	segments := []string{key[:i]}
+	rest := key[i:]
+	for len(rest) > 0 {
+		j := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || j < 0 {
+			return nil, fmt.Errorf("render: query key '%s' is malformed", key)
+		}
+		segments = append(segments, rest[1:j])
+		rest = rest[j+1:]
+
+		if p.MaxDepth > 0 && len(segments)-1 > p.MaxDepth {
+			return nil, fmt.Errorf("render: query key '%s' is nested deeper than %d levels", key, p.MaxDepth)
+		}
+	}
+	return segments, nil
+}
+
+// decodeQueryValue decodes a parsed query value into rv.
+func decodeQueryValue(rv reflect.Value, val interface{}, name string) error {
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodeQueryValue(rv.Elem(), val, name)
+	}
+
+	switch v := val.(type) {
+	case map[string]interface{}:
+		switch rv.Kind() {
+		case reflect.Struct:
+			t := rv.Type()
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				if f.PkgPath != "" {
+					continue // unexported
+				}
+				key := f.Name
+				if tag := f.Tag.Get("query"); tag == "-" {
+					continue
+				} else if tag != "" {
+					key = tag
+				}
+				fv, ok := v[key]
+				if !ok {
+					continue
+				}
+				if err := decodeQueryValue(rv.Field(i), fv, joinQueryName(name, key)); err != nil {
+					return err
+				}
+			}
+			return nil
+
+		case reflect.Map:
+			if rv.Type().Key().Kind() != reflect.String {
+				break
+			}
+			if rv.IsNil() {
+				rv.Set(reflect.MakeMap(rv.Type()))
+			}
+			for k, mv := range v {
+				ev := reflect.New(rv.Type().Elem()).Elem()
+				if err := decodeQueryValue(ev, mv, joinQueryName(name, k)); err != nil {
+					return err
+				}
+				rv.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), ev)
+			}
+			return nil
+
+		case reflect.Interface:
+			rv.Set(reflect.ValueOf(v))
+			return nil
+		}
+		return fmt.Errorf("render: query param '%s' is an object, can't decode into %s", name, rv.Type())
+
+	case []string:
+		switch rv.Kind() {
+		case reflect.Slice:
+			sv := reflect.MakeSlice(rv.Type(), len(v), len(v))
+			for i, s := range v {
+				if err := setQueryString(sv.Index(i), s, name); err != nil {
+					return err
+				}
+			}
+			rv.Set(sv)
+			return nil
+		case reflect.Interface:
+			rv.Set(reflect.ValueOf(v))
+			return nil
+		}
+		// The last value wins for single values
+		return setQueryString(rv, v[len(v)-1], name)
+
+	case string:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+			sv := reflect.MakeSlice(rv.Type(), 1, 1)
+			if err := setQueryString(sv.Index(0), v, name); err != nil {
+				return err
+			}
+			rv.Set(sv)
+			return nil
+		}
+		return setQueryString(rv, v, name)
+	}
+	return nil
+}
+
+// setQueryString converts a query string value into rv.
+func setQueryString(rv reflect.Value, s string, name string) error {
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+
+	var err error
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			rv.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(s, 10, rv.Type().Bits()); err == nil {
+			rv.SetInt(n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		if n, err = strconv.ParseUint(s, 10, rv.Type().Bits()); err == nil {
+			rv.SetUint(n)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(s, rv.Type().Bits()); err == nil {
+			rv.SetFloat(f)
+		}
+	case reflect.Interface:
+		rv.Set(reflect.ValueOf(s))
+	default:
+		return fmt.Errorf("render: query param '%s' can't decode into %s", name, rv.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("render: query param '%s': %v", name, err)
+	}
+	return nil
+}
+
+func joinQueryName(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "[" + key + "]"
+}
@@ -0,0 +1,64 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestQueryParser(t *testing.T) {
+	var args fasthttp.Args
+	args.Parse("a[]=1&a[]=2&filter[author][id]=3&sort=title&tag=x&tag=y")
+
+	m, err := DefaultQueryParser.Parse(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"a":      []string{"1", "2"},
+		"filter": map[string]interface{}{"author": map[string]interface{}{"id": "3"}},
+		"sort":   "title",
+		"tag":    []string{"x", "y"},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Fatalf("got %#v", m)
+	}
+
+	var q struct {
+		A      []int `query:"a"`
+		Filter struct {
+			Author struct {
+				ID int64 `query:"id"`
+			} `query:"author"`
+		} `query:"filter"`
+		Sort string            `query:"sort"`
+		Tag  map[string]string `query:"-"`
+	}
+	if err := DefaultQueryParser.Decode(&args, &q); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q.A, []int{1, 2}) || q.Filter.Author.ID != 3 || q.Sort != "title" {
+		t.Fatalf("got %+v", q)
+	}
+
+	// Depth limit
+	args.Parse("a[b][c][d]=1")
+	if _, err := (&QueryParser{MaxDepth: 2}).Parse(&args); err == nil {
+		t.Fatalf("expecting an error for a key nested deeper than the limit")
+	}
+
+	// Conflicting value and object
+	args.Parse("a=1&a[b]=2")
+	if _, err := DefaultQueryParser.Parse(&args); err == nil {
+		t.Fatalf("expecting an error for conflicting keys")
+	}
+
+	// List segments before the end of the key
+	for _, q := range []string{"a[][b]=1", "a[][]=1"} {
+		args.Parse(q)
+		if _, err := DefaultQueryParser.Parse(&args); err == nil {
+			t.Fatalf("%s: expecting an error for a list segment before the end", q)
+		}
+	}
+}
@@ -4,28 +4,32 @@ import (
 	"bytes"
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"reflect"
 
 	"github.com/valyala/fasthttp"
+	"gopkg.in/yaml.v2"
 )
 
 func String(fctx *fasthttp.RequestCtx, status int, v string) {
-	fctx.Response.Header.Set("Content-Type", "text/plain; charset=utf-8")
-	fctx.SetStatusCode(status)
-	fctx.Write([]byte(v))
+	defaultRenderer.String(fctx, status, v)
 }
 
 func HTML(fctx *fasthttp.RequestCtx, status int, v string) {
-	fctx.Response.Header.Set("Content-Type", "text/html; charset=utf-8")
-	fctx.SetStatusCode(status)
-	fctx.Write([]byte(v))
+	defaultRenderer.HTML(fctx, status, v)
 }
 
+// JSON renders `v` as JSON. Values failing to marshal are answered like a
+// ResponseWriter does, the error being reported to the OnError hooks of the
+// Mux and the 500 telling the request ID and route pattern, see Writer.
 func JSON(fctx *fasthttp.RequestCtx, status int, v interface{}) {
+	defaultRenderer.JSON(fctx, status, v)
+}
+
+func marshalJSON(v interface{}) ([]byte, error) {
 	b, err := json.Marshal(v)
 	if err != nil {
-		fctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
 	if len(b) > 0 {
@@ -33,26 +37,34 @@ func JSON(fctx *fasthttp.RequestCtx, status int, v interface{}) {
 		b = bytes.Replace(b, []byte("\\u003e"), []byte(">"), -1)
 		b = bytes.Replace(b, []byte("\\u0026"), []byte("&"), -1)
 	}
-
-	fctx.Response.Header.Set("Content-Type", "application/json; charset=utf-8")
-	fctx.SetStatusCode(status)
-	fctx.Write(b)
+	return b, nil
 }
 
 func Noop(fctx *fasthttp.RequestCtx) {
 	String(fctx, fasthttp.StatusOK, "")
 }
 
+// XML renders `v` as XML, answering values failing to marshal like JSON.
 func XML(fctx *fasthttp.RequestCtx, status int, v interface{}) {
+	defaultRenderer.XML(fctx, status, v)
+}
+
+// StreamXML renders `v` as XML encoded straight to the response body
+// stream, without buffering the document, for the large responses of XML
+// integrations. The value is encoded twice, first to check it encodes and
+// count its size without holding the document, so values failing to
+// encode are answered with a 500 like XML rather than a response cut
+// short, and the others are sent with their Content-Length.
+func StreamXML(fctx *fasthttp.RequestCtx, status int, v interface{}) {
+	defaultRenderer.StreamXML(fctx, status, v)
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
 	b, err := xml.Marshal(v)
 	if err != nil {
-		fctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	fctx.Response.Header.Set("Content-Type", "application/xml; charset=utf-8")
-	fctx.SetStatusCode(status)
-
 	// Try to find <?xml header in first 100 bytes (just in case there're some XML comments).
 	findHeaderUntil := len(b)
 	if findHeaderUntil > 100 {
@@ -60,23 +72,43 @@ func XML(fctx *fasthttp.RequestCtx, status int, v interface{}) {
 	}
 	if bytes.Index(b[:findHeaderUntil], []byte("<?xml")) == -1 {
 		// No header found. Print it out first.
-		fctx.Write([]byte(xml.Header))
+		b = append([]byte(xml.Header), b...)
 	}
+	return b, nil
+}
+
+// internalError answers a response that can't be rendered with the status
+// text of a 500, as marshalling errors may tell the internals of the value.
+func internalError(fctx *fasthttp.RequestCtx) {
+	fctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+}
+
+// YAML renders `v` as YAML, answering values failing to marshal like JSON.
+func YAML(fctx *fasthttp.RequestCtx, status int, v interface{}) {
+	defaultRenderer.YAML(fctx, status, v)
+}
 
-	fctx.Write(b)
+// marshalYAML marshals `v` as YAML, returning the panics of the encoder on
+// values it can't marshal, ie. channels, as errors.
+func marshalYAML(v interface{}) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("render: %v", r)
+		}
+	}()
+	return yaml.Marshal(v)
 }
 
 func Respond(fctx *fasthttp.RequestCtx, status int, v interface{}) {
-	if err, ok := v.(error); ok {
-		JSON(fctx, status, map[string]interface{}{"error": err.Error()})
-		return
-	}
+	defaultRenderer.Respond(fctx, status, v)
+}
 
-	// Force to return empty JSON array [] instead of null in case of zero slice.
+// emptySlice returns an empty slice in place of a nil one, to render an
+// empty JSON array [] instead of null.
+func emptySlice(v interface{}) interface{} {
 	val := reflect.ValueOf(v)
 	if val.Kind() == reflect.Slice && val.IsNil() {
-		v = reflect.MakeSlice(val.Type(), 0, 0).Interface()
+		return reflect.MakeSlice(val.Type(), 0, 0).Interface()
 	}
-
-	JSON(fctx, status, v)
+	return v
 }
@@ -0,0 +1,64 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestStreamXML(t *testing.T) {
+	type item struct {
+		ID int `xml:"id,attr"`
+	}
+	type feed struct {
+		Items []item `xml:"item"`
+	}
+
+	var fctx fasthttp.RequestCtx
+	StreamXML(&fctx, 200, &feed{Items: []item{{1}, {2}}})
+	if !fctx.Response.IsBodyStream() {
+		t.Fatal("expecting a streamed body")
+	}
+	expected := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<feed><item id=\"1\"></item><item id=\"2\"></item></feed>"
+	if n := fctx.Response.Header.ContentLength(); n != len(expected) {
+		t.Fatalf("expecting a Content-Length of %d, got %d", len(expected), n)
+	}
+	if body := string(fctx.Response.Body()); body != expected {
+		t.Fatalf("got '%s'", body)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	StreamXML(&fctx, 200, map[string]string{"id": "1"})
+	if fctx.Response.IsBodyStream() || fctx.Response.StatusCode() != 500 {
+		t.Fatalf("expecting a 500 for a value failing to encode, got %d", fctx.Response.StatusCode())
+	}
+}
+
+func TestJSONMaxBufferedBody(t *testing.T) {
+	r := &Renderer{MaxBufferedBody: 16}
+
+	var small fasthttp.RequestCtx
+	r.JSON(&small, 200, map[string]string{"a": "<b>"})
+	if small.Response.IsBodyStream() {
+		t.Fatal("expecting a small body to be buffered")
+	}
+	if body := string(small.Response.Body()); body != `{"a":"<b>"}` {
+		t.Fatalf("got '%s'", body)
+	}
+
+	var large fasthttp.RequestCtx
+	r.JSON(&large, 201, []string{"lorem ipsum", "dolor sit amet"})
+	if !large.Response.IsBodyStream() {
+		t.Fatal("expecting a large body to be streamed")
+	}
+	expected := `["lorem ipsum","dolor sit amet"]`
+	if n := large.Response.Header.ContentLength(); n != len(expected) {
+		t.Fatalf("expecting a Content-Length of %d, got %d", len(expected), n)
+	}
+	if large.Response.StatusCode() != 201 {
+		t.Fatalf("got status %d", large.Response.StatusCode())
+	}
+	if body := string(large.Response.Body()); body != expected {
+		t.Fatalf("got '%s'", body)
+	}
+}
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"bufio"
+	"encoding/xml"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// A Renderer renders responses like the package funcs, with settings of its
+// own, ie. for a service rendering the occasional huge JSON body:
+//
+//	var renderer = &render.Renderer{MaxBufferedBody: 64 << 10}
+//
+//	renderer.JSON(fctx, 200, articles)
+//
+// The package funcs render like the zero Renderer.
+type Renderer struct {
+	// OmitContentHeaders leaves the Content-Length of the rendered bodies,
+	// and the Content-Language set with SetLanguage, to the handlers.
+	// fasthttp sends the length of buffered bodies anyway, setting it early
+	// shows it to the middlewares once the handler returned.
+	OmitContentHeaders bool
+
+	// MaxBufferedBody, when positive, makes the JSON renders encode their
+	// values in buffers pooled by the package, and write the bodies over
+	// this many bytes to the response through its body stream rather than
+	// its buffer. fasthttp keeps the response buffers of its connections at
+	// the size of the largest body they held, so the occasional huge
+	// response otherwise leaves them bloated for the life of the server.
+	//
+	// The pool only keeps the buffers of up to MaxBufferedBody bytes. Zero,
+	// the default, writes all bodies to the response buffer.
+	MaxBufferedBody int
+
+	// MaxXMLBody is the number of bytes BindXML reads at most from a body
+	// before failing with ErrBodyTooLarge. Zero, the default, reads up to
+	// 1 MiB.
+	MaxXMLBody int64
+}
+
+// defaultRenderer renders the responses of the package funcs.
+var defaultRenderer = &Renderer{}
+
+// String renders `v` as plain text.
+func (r *Renderer) String(fctx *fasthttp.RequestCtx, status int, v string) {
+	r.writeBody(fctx, status, "text/plain; charset=utf-8", []byte(v))
+}
+
+// HTML renders `v` as HTML.
+func (r *Renderer) HTML(fctx *fasthttp.RequestCtx, status int, v string) {
+	r.writeBody(fctx, status, "text/html; charset=utf-8", []byte(v))
+}
+
+// JSON renders `v` as JSON, see the JSON func.
+func (r *Renderer) JSON(fctx *fasthttp.RequestCtx, status int, v interface{}) {
+	if err := r.writeJSON(fctx, status, v); err != nil {
+		r.marshalError(fctx, err)
+	}
+}
+
+// XML renders `v` as XML, see the XML func.
+func (r *Renderer) XML(fctx *fasthttp.RequestCtx, status int, v interface{}) {
+	b, err := marshalXML(v)
+	if err != nil {
+		r.marshalError(fctx, err)
+		return
+	}
+
+	r.writeBody(fctx, status, "application/xml; charset=utf-8", b)
+}
+
+// StreamXML renders `v` as XML encoded to the response body stream, see
+// the StreamXML func.
+func (r *Renderer) StreamXML(fctx *fasthttp.RequestCtx, status int, v interface{}) {
+	var size byteCounter
+	if err := xml.NewEncoder(&size).Encode(v); err != nil {
+		r.marshalError(fctx, err)
+		return
+	}
+
+	fctx.Response.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	fctx.SetStatusCode(status)
+	fctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		w.WriteString(xml.Header)
+		xml.NewEncoder(w).Encode(v)
+	})
+	r.setContentHeaders(fctx, len(xml.Header)+int(size))
+}
+
+// A byteCounter counts the bytes written to it, discarding them.
+type byteCounter int
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// YAML renders `v` as YAML, see the YAML func.
+func (r *Renderer) YAML(fctx *fasthttp.RequestCtx, status int, v interface{}) {
+	b, err := marshalYAML(v)
+	if err != nil {
+		r.marshalError(fctx, err)
+		return
+	}
+
+	r.writeBody(fctx, status, "application/yaml; charset=utf-8", b)
+}
+
+// marshalError answers the request of a value failing to marshal like a
+// ResponseWriter does, see ResponseWriter.marshalError, or with a bare 500
+// when the request isn't routed by a Mux.
+func (r *Renderer) marshalError(fctx *fasthttp.RequestCtx, err error) {
+	ctx := chi.RequestContext(fctx)
+	if ctx == nil {
+		internalError(fctx)
+		return
+	}
+	r.Writer(ctx, fctx).marshalError(err)
+}
+
+// Respond renders `v` as JSON, see the Respond func.
+func (r *Renderer) Respond(fctx *fasthttp.RequestCtx, status int, v interface{}) {
+	if err, ok := v.(error); ok {
+		r.JSON(fctx, status, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	r.JSON(fctx, status, emptySlice(v))
+}
+
+// Writer returns the ResponseWriter of the request rendering with the
+// Renderer, see the Writer func.
+func (r *Renderer) Writer(ctx context.Context, fctx *fasthttp.RequestCtx) *ResponseWriter {
+	contentType, ok := ctx.Value("contentType").(ContentType)
+	if !ok {
+		contentType = negotiateContentType(fctx)
+	}
+	return &ResponseWriter{ContentType: contentType, ctx: ctx, fctx: fctx, r: r}
+}
@@ -0,0 +1,203 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Sections composes a response of named sections filled concurrently, ie.
+// by the backend calls of a BFF endpoint, each with its own timeout:
+//
+//	s := render.NewSections(ctx)
+//	s.Add("user", time.Second, func(ctx context.Context) (interface{}, error) {
+//		return users.Get(ctx, id)
+//	})
+//	s.Add("feed", 2*time.Second, func(ctx context.Context) (interface{}, error) {
+//		return feeds.Latest(ctx, id)
+//	})
+//	render.Writer(ctx, fctx).OK(s.Wait())
+//
+// The sections render in the order they were added, see SectionsResult.
+// Its methods are safe for concurrent use.
+type Sections struct {
+	ctx context.Context
+
+	mu       sync.Mutex
+	sections []*section
+}
+
+// section is a named section of a Sections response.
+type section struct {
+	name   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan sectionValue
+}
+
+type sectionValue struct {
+	v   interface{}
+	err error
+}
+
+// NewSections returns the Sections of a response, filled within the
+// request context `ctx`.
+func NewSections(ctx context.Context) *Sections {
+	return &Sections{ctx: ctx}
+}
+
+// Add fills the section `name` with the value returned by `fn`, run in a
+// goroutine with a context cancelled after the `timeout`, if not 0. Its
+// error, timeout or panic leaves the section empty.
+func (s *Sections) Add(name string, timeout time.Duration, fn func(ctx context.Context) (interface{}, error)) {
+	sec := &section{name: name, done: make(chan sectionValue, 1)}
+	if timeout > 0 {
+		sec.ctx, sec.cancel = context.WithTimeout(s.ctx, timeout)
+	} else {
+		sec.ctx, sec.cancel = context.WithCancel(s.ctx)
+	}
+
+	s.mu.Lock()
+	s.sections = append(s.sections, sec)
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				sec.done <- sectionValue{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		v, err := fn(sec.ctx)
+		sec.done <- sectionValue{v, err}
+	}()
+}
+
+// Wait waits for the sections to be filled, or for their context to be
+// done, returning them in the order they were added.
+func (s *Sections) Wait() *SectionsResult {
+	s.mu.Lock()
+	sections := append([]*section(nil), s.sections...)
+	s.mu.Unlock()
+
+	res := &SectionsResult{}
+	for _, sec := range sections {
+		var sv sectionValue
+		select {
+		case sv = <-sec.done:
+		case <-sec.ctx.Done():
+			// The goroutine of a section ignoring its context is left
+			// behind, its value dropped
+			sv.err = sec.ctx.Err()
+		}
+		sec.cancel()
+
+		res.names = append(res.names, sec.name)
+		res.values = append(res.values, sv.v)
+		if sv.err != nil {
+			res.values[len(res.values)-1] = nil
+			res.Meta.Errors = append(res.Meta.Errors, SectionError{Section: sec.name, Error: sv.err.Error()})
+			res.errs = append(res.errs, fmt.Errorf("render: section %s: %v", sec.name, sv.err))
+		}
+	}
+	return res
+}
+
+// A SectionsResult is the response of filled Sections, rendered by Respond
+// or a ResponseWriter, which also reports the errors of the sections to
+// the OnError hooks of the Mux. It renders as JSON, the sections in the
+// order they were added and the failed ones listed in its meta:
+//
+//	{"data": {"user": {"id": 1}, "feed": null}, "meta": {"errors": [{"section": "feed", "error": "context deadline exceeded"}]}}
+//
+// HTML requests get the sections concatenated instead, ie. HTML fragments
+// as strings or template.HTML, other values being escaped, and the failed
+// sections left out.
+type SectionsResult struct {
+	Meta SectionsMeta
+
+	names  []string
+	values []interface{}
+	errs   []error
+}
+
+// SectionsMeta lists the sections left empty in a SectionsResult.
+type SectionsMeta struct {
+	Errors []SectionError `json:"errors,omitempty"`
+}
+
+// A SectionError is the error of a section left empty.
+type SectionError struct {
+	Section string `json:"section"`
+	Error   string `json:"error"`
+}
+
+// Get returns the value of the section `name`, nil if it failed.
+func (r *SectionsResult) Get(name string) interface{} {
+	for i, n := range r.names {
+		if n == name {
+			return r.values[i]
+		}
+	}
+	return nil
+}
+
+// Err returns the error of the first section that failed, if any, for
+// handlers answering an error rather than a partial response.
+func (r *SectionsResult) Err() error {
+	if len(r.errs) == 0 {
+		return nil
+	}
+	return r.errs[0]
+}
+
+// MarshalJSON renders the sections in the order they were added.
+func (r *SectionsResult) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"data":{`)
+	for i, name := range r.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(name)
+		v, err := marshalJSON(r.values[i])
+		if err != nil {
+			return nil, fmt.Errorf("render: section %s: %v", name, err)
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteString(`},"meta":`)
+	meta, err := json.Marshal(r.Meta)
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(meta)
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// html returns the sections concatenated as HTML.
+func (r *SectionsResult) html() string {
+	var buf bytes.Buffer
+	for _, v := range r.values {
+		switch v := v.(type) {
+		case nil:
+		case template.HTML:
+			buf.WriteString(string(v))
+		case string:
+			buf.WriteString(v)
+		case []byte:
+			buf.Write(v)
+		default:
+			buf.WriteString(html.EscapeString(fmt.Sprint(v)))
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestSections(t *testing.T) {
+	var reported []error
+	r := chi.NewRouter()
+	r.OnError(func(ctx context.Context, fctx *fasthttp.RequestCtx, err error) {
+		reported = append(reported, err)
+	})
+	r.Get("/home", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		s := NewSections(ctx)
+		s.Add("user", 0, func(ctx context.Context) (interface{}, error) {
+			time.Sleep(5 * time.Millisecond)
+			return map[string]int{"id": 1}, nil
+		})
+		s.Add("feed", 10*time.Millisecond, func(ctx context.Context) (interface{}, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		s.Add("ads", 0, func(ctx context.Context) (interface{}, error) {
+			panic("no ads")
+		})
+		s.Add("alerts", 0, func(ctx context.Context) (interface{}, error) {
+			return []string{"<b>"}, nil
+		})
+		res := s.Wait()
+		if res.Get("alerts") == nil || res.Get("feed") != nil {
+			t.Errorf("unexpected sections %v and %v", res.Get("alerts"), res.Get("feed"))
+		}
+		if err := res.Err(); err == nil || !strings.HasPrefix(err.Error(), "render: section feed: ") {
+			t.Errorf("expecting the error of the feed, got %v", err)
+		}
+		Writer(ctx, fctx).OK(res)
+	})
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/home")
+	r.ServeHTTP(&fctx)
+	expected := `{"data":{"user":{"id":1},"feed":null,"ads":null,"alerts":["<b>"]},` +
+		`"meta":{"errors":[{"section":"feed","error":"context deadline exceeded"},{"section":"ads","error":"panic: no ads"}]}}`
+	if body := string(fctx.Response.Body()); fctx.Response.StatusCode() != 200 || body != expected {
+		t.Fatalf("got %d '%s'", fctx.Response.StatusCode(), body)
+	}
+	if len(reported) != 2 {
+		t.Fatalf("expecting the 2 section errors to be reported, got %v", reported)
+	}
+}
+
+func TestSectionsHTML(t *testing.T) {
+	s := NewSections(context.Background())
+	s.Add("header", 0, func(ctx context.Context) (interface{}, error) {
+		return "<header>hi</header>", nil
+	})
+	s.Add("broken", 0, func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("down")
+	})
+	s.Add("count", 0, func(ctx context.Context) (interface{}, error) {
+		return "<p>3 < 4</p>", nil
+	})
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.Set("Accept", "text/html")
+	Writer(context.Background(), &fctx).OK(s.Wait())
+	if body := string(fctx.Response.Body()); body != "<header>hi</header><p>3 < 4</p>" {
+		t.Fatalf("got '%s'", body)
+	}
+	if ct := string(fctx.Response.Header.ContentType()); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("expecting HTML, got '%s'", ct)
+	}
+}
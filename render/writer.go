@@ -0,0 +1,231 @@
+package render
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/hmgle/chi"
+	"github.com/hmgle/chi/middleware"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// A ResponseWriter renders the responses of a request in its negotiated
+// content type, so handlers state the outcome and leave the format, the
+// error envelope and the status to the writer:
+//
+//	func createArticle(ctx context.Context, fctx *fasthttp.RequestCtx) {
+//		w := render.Writer(ctx, fctx)
+//		article, err := newArticle(fctx)
+//		if err != nil {
+//			w.BadRequest(err)
+//			return
+//		}
+//		w.Created(article, "/articles/"+article.ID)
+//	}
+//
+// Errors are rendered as {"error": "message"} in JSON, <error>message</error>
+// in XML, `error: message` in YAML, and as their message in plain text.
+//
+// Values failing to marshal are answered with a 500 carrying the request ID
+// set by middleware.RequestID and the route pattern, to look the failure up
+// in the logs, while the error itself is only reported to the OnError hooks
+// of the Mux, see chi.ReportError, as it may tell the internals of the value.
+type ResponseWriter struct {
+	// Content type negotiated for the request
+	ContentType ContentType
+
+	ctx  context.Context
+	fctx *fasthttp.RequestCtx
+	r    *Renderer
+}
+
+// Writer returns the ResponseWriter of the request, rendering the content
+// type set by the ParseContentType middleware or negotiated from the
+// request's Accept header.
+func Writer(ctx context.Context, fctx *fasthttp.RequestCtx) *ResponseWriter {
+	return defaultRenderer.Writer(ctx, fctx)
+}
+
+// ErrorRenderer renders the status text of an error in the content type
+// negotiated from the request's Accept header, to be set with
+// Mux.ErrorRenderer so the errors raised by fasthttp get the envelope of
+// the ResponseWriter errors.
+func ErrorRenderer(fctx *fasthttp.RequestCtx, status int, err error) {
+	w := &ResponseWriter{ContentType: negotiateContentType(fctx), ctx: context.Background(), fctx: fctx, r: defaultRenderer}
+	w.Respond(status, nil)
+}
+
+// Created renders `v` in the negotiated content type with a 201 status, and
+// the URL of the route named `routeName` with `params` in the Location
+// header, see chi.URLFor, ie.
+//
+//	r.Route("/articles", func(r chi.Router) {
+//		r.Post("/", createArticle)
+//		r.Get("/:articleID", getArticle).Name("article")
+//	})
+//
+//	render.Created(fctx, ctx, "article", map[string]string{"articleID": article.ID}, article)
+//
+// It responds with nothing and returns the error if the URL can't be built.
+func Created(fctx *fasthttp.RequestCtx, ctx context.Context, routeName string, params map[string]string, v interface{}) error {
+	location, err := chi.URLFor(ctx, routeName, params)
+	if err != nil {
+		return err
+	}
+	Writer(ctx, fctx).Created(v, location)
+	return nil
+}
+
+// errorBody is the envelope of the errors rendered by a ResponseWriter.
+type errorBody struct {
+	XMLName   xml.Name `json:"-" xml:"error" yaml:"-"`
+	Message   string   `json:"error" xml:",chardata" yaml:"error"`
+	RequestID string   `json:"requestId,omitempty" xml:"requestId,attr,omitempty" yaml:"requestId,omitempty"`
+	Route     string   `json:"route,omitempty" xml:"route,attr,omitempty" yaml:"route,omitempty"`
+}
+
+// String returns the message of the error, followed by its request ID and
+// route if any.
+func (e *errorBody) String() string {
+	switch {
+	case e.RequestID != "" && e.Route != "":
+		return fmt.Sprintf("%s (request %s, route %s)", e.Message, e.RequestID, e.Route)
+	case e.RequestID != "":
+		return fmt.Sprintf("%s (request %s)", e.Message, e.RequestID)
+	case e.Route != "":
+		return fmt.Sprintf("%s (route %s)", e.Message, e.Route)
+	}
+	return e.Message
+}
+
+// OK renders `v` with a 200 status.
+func (w *ResponseWriter) OK(v interface{}) {
+	w.Respond(fasthttp.StatusOK, v)
+}
+
+// Created renders `v` with a 201 status, and the `location` of the new
+// resource in the Location header.
+func (w *ResponseWriter) Created(v interface{}, location string) {
+	w.fctx.Response.Header.Set("Location", location)
+	w.Respond(fasthttp.StatusCreated, v)
+}
+
+// Accepted renders `v` with a 202 status.
+func (w *ResponseWriter) Accepted(v interface{}) {
+	w.Respond(fasthttp.StatusAccepted, v)
+}
+
+// NoContent responds with a 204 status and no body.
+func (w *ResponseWriter) NoContent() {
+	w.fctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// BadRequest renders `err` with a 400 status.
+func (w *ResponseWriter) BadRequest(err error) {
+	w.Respond(fasthttp.StatusBadRequest, err)
+}
+
+// Unauthorized renders `err` with a 401 status.
+func (w *ResponseWriter) Unauthorized(err error) {
+	w.Respond(fasthttp.StatusUnauthorized, err)
+}
+
+// Forbidden renders `err` with a 403 status.
+func (w *ResponseWriter) Forbidden(err error) {
+	w.Respond(fasthttp.StatusForbidden, err)
+}
+
+// NotFound renders `err` with a 404 status.
+func (w *ResponseWriter) NotFound(err error) {
+	w.Respond(fasthttp.StatusNotFound, err)
+}
+
+// Conflict renders `err` with a 409 status.
+func (w *ResponseWriter) Conflict(err error) {
+	w.Respond(fasthttp.StatusConflict, err)
+}
+
+// Error renders `err` with a 500 status.
+func (w *ResponseWriter) Error(err error) {
+	w.Respond(fasthttp.StatusInternalServerError, err)
+}
+
+// Respond renders `v` with the `status` in the negotiated content type.
+// Errors are wrapped in the error envelope, and a nil error renders the
+// status text. Values other than strings and errors are rendered as JSON
+// for plain text and HTML requests, as are all values of event streams,
+// PartialResults and SectionsResults but for HTML requests.
+func (w *ResponseWriter) Respond(status int, v interface{}) {
+	if err, ok := v.(error); ok || (v == nil && status >= 400) {
+		msg := fasthttp.StatusMessage(status)
+		if err != nil {
+			msg = err.Error()
+		}
+		v = &errorBody{Message: msg}
+	}
+	if p, ok := v.(*PartialResult); ok {
+		for _, err := range p.errs {
+			chi.ReportError(w.ctx, err)
+		}
+		if err := w.r.writeJSON(w.fctx, status, p); err != nil {
+			w.marshalError(err)
+		}
+		return
+	}
+	if r, ok := v.(*SectionsResult); ok {
+		for _, err := range r.errs {
+			chi.ReportError(w.ctx, err)
+		}
+		if w.ContentType == ContentTypeHTML {
+			w.r.HTML(w.fctx, status, r.html())
+			return
+		}
+		if err := w.r.writeJSON(w.fctx, status, r); err != nil {
+			w.marshalError(err)
+		}
+		return
+	}
+
+	switch w.ContentType {
+	case ContentTypeXML:
+		b, err := marshalXML(v)
+		if err != nil {
+			w.marshalError(err)
+			return
+		}
+		w.r.writeBody(w.fctx, status, "application/xml; charset=utf-8", b)
+		return
+	case ContentTypeYAML:
+		b, err := marshalYAML(emptySlice(v))
+		if err != nil {
+			w.marshalError(err)
+			return
+		}
+		w.r.writeBody(w.fctx, status, "application/yaml; charset=utf-8", b)
+		return
+	case ContentTypePlainText, ContentTypeHTML:
+		switch v := v.(type) {
+		case string:
+			w.r.String(w.fctx, status, v)
+			return
+		case fmt.Stringer:
+			w.r.String(w.fctx, status, v.String())
+			return
+		}
+	}
+	if err := w.r.writeJSON(w.fctx, status, emptySlice(v)); err != nil {
+		w.marshalError(err)
+	}
+}
+
+// marshalError reports the error of a value failing to marshal and renders
+// a 500 telling the request ID and route pattern instead.
+func (w *ResponseWriter) marshalError(err error) {
+	chi.ReportError(w.ctx, err)
+	w.Respond(fasthttp.StatusInternalServerError, &errorBody{
+		Message:   fasthttp.StatusMessage(fasthttp.StatusInternalServerError),
+		RequestID: middleware.GetReqID(w.ctx),
+		Route:     chi.RoutePattern(w.ctx),
+	})
+}
@@ -0,0 +1,192 @@
+package render
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hmgle/chi"
+	"github.com/hmgle/chi/middleware"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestWriter(t *testing.T) {
+	type article struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	}
+
+	var fctx fasthttp.RequestCtx
+	Writer(context.Background(), &fctx).Created(&article{"1", "chi"}, "/articles/1")
+	if fctx.Response.StatusCode() != 201 || string(fctx.Response.Header.Peek("Location")) != "/articles/1" {
+		t.Fatalf("expecting a 201 with the location, got %d", fctx.Response.StatusCode())
+	}
+	if body := string(fctx.Response.Body()); body != `{"id":"1","title":"chi"}` {
+		t.Fatalf("got '%s'", body)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	Writer(context.Background(), &fctx).NotFound(errors.New("no such article"))
+	if body := string(fctx.Response.Body()); fctx.Response.StatusCode() != 404 || body != `{"error":"no such article"}` {
+		t.Fatalf("got %d '%s'", fctx.Response.StatusCode(), body)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	fctx.Request.Header.Set("Accept", "text/xml")
+	Writer(context.Background(), &fctx).NotFound(nil)
+	if body := string(fctx.Response.Body()); body != "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<error>Not Found</error>" {
+		t.Fatalf("got '%s'", body)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	fctx.Request.Header.Set("Accept", "application/xml")
+	Writer(context.Background(), &fctx).NotFound(nil)
+	if ct := string(fctx.Response.Header.ContentType()); !strings.HasPrefix(ct, "application/xml") {
+		t.Fatalf("expecting XML for Accept: application/xml, got '%s'", ct)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	fctx.Request.Header.Set("Accept", "application/yaml")
+	Writer(context.Background(), &fctx).OK(&article{"1", "chi"})
+	if body := string(fctx.Response.Body()); body != "id: \"1\"\ntitle: chi\n" {
+		t.Fatalf("got '%s'", body)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	ctx := context.WithValue(context.Background(), "contentType", ContentType(ContentTypePlainText))
+	Writer(ctx, &fctx).BadRequest(errors.New("missing title"))
+	if body := string(fctx.Response.Body()); fctx.Response.StatusCode() != 400 || body != "missing title" {
+		t.Fatalf("got %d '%s'", fctx.Response.StatusCode(), body)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	var articles []*article
+	Writer(context.Background(), &fctx).OK(articles)
+	if body := string(fctx.Response.Body()); body != "[]" {
+		t.Fatalf("got '%s'", body)
+	}
+}
+
+func TestCreated(t *testing.T) {
+	type user struct {
+		ID string `json:"id"`
+	}
+
+	r := chi.NewRouter()
+	r.Route("/hubs/:hubID/users", func(r chi.Router) {
+		r.Post("/", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			err := Created(fctx, ctx, "user", map[string]string{"userID": "5"}, &user{"5"})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+		r.Get("/:userID", func(ctx context.Context, fctx *fasthttp.RequestCtx) {}).Name("user")
+	})
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.SetMethod("POST")
+	fctx.Request.SetRequestURI("/hubs/123/users")
+	r.ServeHTTP(&fctx)
+	if fctx.Response.StatusCode() != 201 {
+		t.Fatalf("got %d", fctx.Response.StatusCode())
+	}
+	if location := string(fctx.Response.Header.Peek("Location")); location != "/hubs/123/users/5" {
+		t.Fatalf("got Location '%s'", location)
+	}
+	if body := string(fctx.Response.Body()); body != `{"id":"5"}` {
+		t.Fatalf("got '%s'", body)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	if err := Created(&fctx, context.Background(), "user", nil, nil); err == nil {
+		t.Fatal("expecting an error outside of a route")
+	}
+}
+
+func TestErrorRenderer(t *testing.T) {
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.Set("Accept", "text/plain")
+	ErrorRenderer(&fctx, fasthttp.StatusBadRequest, errors.New("cannot parse request"))
+	if body := string(fctx.Response.Body()); fctx.Response.StatusCode() != 400 || body != "Bad Request" {
+		t.Fatalf("got %d '%s'", fctx.Response.StatusCode(), body)
+	}
+}
+
+func TestWriterMarshalError(t *testing.T) {
+	var reported error
+	r := chi.NewRouter()
+	r.OnError(func(ctx context.Context, fctx *fasthttp.RequestCtx, err error) {
+		reported = err
+	})
+	r.Use(middleware.RequestID)
+	r.Get("/feeds/:feedID", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		Writer(ctx, fctx).OK(map[string]interface{}{"updates": make(chan int)})
+	})
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/feeds/1")
+	r.ServeHTTP(&fctx)
+	body := string(fctx.Response.Body())
+	if fctx.Response.StatusCode() != 500 || strings.Contains(body, "chan") {
+		t.Fatalf("got %d '%s'", fctx.Response.StatusCode(), body)
+	}
+	if !strings.Contains(body, `"error":"Internal Server Error","requestId":"`) || !strings.HasSuffix(body, `"route":"/feeds/:feedID"}`) {
+		t.Fatalf("got '%s'", body)
+	}
+	if reported == nil || !strings.Contains(reported.Error(), "chan") {
+		t.Fatalf("expecting the marshal error to be reported, got %v", reported)
+	}
+
+	// The package funcs answer like a ResponseWriter on routed requests
+	reported = nil
+	r.Get("/feeds/:feedID/xml", func(fctx *fasthttp.RequestCtx) {
+		XML(fctx, 200, map[string]string{"id": "1"})
+	})
+	fctx = fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("/feeds/1/xml")
+	r.ServeHTTP(&fctx)
+	body = string(fctx.Response.Body())
+	if fctx.Response.StatusCode() != 500 || !strings.Contains(body, `"requestId":"`) || !strings.HasSuffix(body, `"route":"/feeds/:feedID/xml"}`) {
+		t.Fatalf("got %d '%s'", fctx.Response.StatusCode(), body)
+	}
+	if reported == nil {
+		t.Fatal("expecting the marshal error to be reported")
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	JSON(&fctx, 200, make(chan int))
+	if body := string(fctx.Response.Body()); fctx.Response.StatusCode() != 500 || body != "Internal Server Error" {
+		t.Fatalf("got %d '%s'", fctx.Response.StatusCode(), body)
+	}
+}
+
+func TestWriterPartial(t *testing.T) {
+	var reported []error
+	r := chi.NewRouter()
+	r.OnError(func(ctx context.Context, fctx *fasthttp.RequestCtx, err error) {
+		reported = append(reported, err)
+	})
+	r.Get("/report", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		rows := []interface{}{map[string]int{"id": 1}, make(chan int), map[string]int{"id": 3}}
+		Writer(ctx, fctx).OK(Partial(rows))
+	})
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/report")
+	fctx.Request.Header.Set("Accept", "application/xml")
+	r.ServeHTTP(&fctx)
+	body := string(fctx.Response.Body())
+	if fctx.Response.StatusCode() != 200 || body != `{"data":[{"id":1},{"id":3}],"meta":{"errors":[{"index":1,"error":"json: unsupported type: chan int"}]}}` {
+		t.Fatalf("got %d '%s'", fctx.Response.StatusCode(), body)
+	}
+	if len(reported) != 1 || !strings.HasPrefix(reported[0].Error(), "render: element 1: ") {
+		t.Fatalf("expecting the element error to be reported, got %v", reported)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	Respond(&fctx, 200, Partial([]int{1, 2}))
+	if body := string(fctx.Response.Body()); body != `{"data":[1,2],"meta":{}}` {
+		t.Fatalf("got '%s'", body)
+	}
+}
@@ -0,0 +1,252 @@
+// Package routeconf builds chi routers from a YAML or JSON description of
+// their routes, whose handlers and middlewares are named in a Registry, so
+// gateways reconfigure their routing without being recompiled, ie.
+//
+//	reg := routeconf.NewRegistry()
+//	reg.Handler("listArticles", listArticles)
+//	reg.Handler("getArticle", getArticle)
+//	reg.Middleware("auth", auth)
+//
+//	r, err := routeconf.LoadFile(reg, "routes.yaml")
+//
+// with routes.yaml:
+//
+//	middlewares: [auth]
+//	routes:
+//	  - pattern: /articles
+//	    routes:
+//	      - method: GET
+//	        pattern: /
+//	        handler: listArticles
+//	      - method: GET
+//	        pattern: /:id|int
+//	        handler: getArticle
+//	        name: article
+//	        timeout: 2s
+//
+// Routes are reloaded while serving with Reload.
+package routeconf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/hmgle/chi"
+	"gopkg.in/yaml.v2"
+)
+
+// A Registry holds the handlers and middlewares named by the configurations,
+// of any type the chi routing methods accept.
+type Registry struct {
+	mu          sync.RWMutex
+	handlers    map[string]interface{}
+	middlewares map[string]interface{}
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers:    make(map[string]interface{}),
+		middlewares: make(map[string]interface{}),
+	}
+}
+
+// Handler registers the handler `h` under `name`.
+func (reg *Registry) Handler(name string, h interface{}) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.handlers[name] = h
+}
+
+// Middleware registers the middleware `mw` under `name`.
+func (reg *Registry) Middleware(name string, mw interface{}) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.middlewares[name] = mw
+}
+
+func (reg *Registry) handler(name string) (interface{}, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	h, ok := reg.handlers[name]
+	return h, ok
+}
+
+func (reg *Registry) middleware(name string) (interface{}, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	mw, ok := reg.middlewares[name]
+	return mw, ok
+}
+
+// A Config describes a router: its middlewares and routes.
+type Config struct {
+	Middlewares []string      `yaml:"middlewares" json:"middlewares,omitempty"`
+	Routes      []RouteConfig `yaml:"routes" json:"routes"`
+}
+
+// A RouteConfig describes a route, served by the handler named Handler, or
+// a subrouter mounted along the Pattern with its own Routes.
+type RouteConfig struct {
+	// Method of the route, all methods when empty
+	Method  string `yaml:"method" json:"method,omitempty"`
+	Pattern string `yaml:"pattern" json:"pattern"`
+	Handler string `yaml:"handler" json:"handler,omitempty"`
+
+	// Name of the route, see chi.Route.Name
+	Name string `yaml:"name" json:"name,omitempty"`
+
+	// Inline middlewares of the route, or middlewares of the subrouter
+	Middlewares []string `yaml:"middlewares" json:"middlewares,omitempty"`
+
+	// Timeout of the route, ie. "2s", see chi.WithTimeout
+	Timeout string `yaml:"timeout" json:"timeout,omitempty"`
+
+	Routes []RouteConfig `yaml:"routes" json:"routes,omitempty"`
+}
+
+// Parse parses a Config from YAML, or JSON as YAML is a superset of it.
+func Parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("routeconf: %v", err)
+	}
+	return &cfg, nil
+}
+
+// Load returns the router described by the YAML or JSON `data`.
+func Load(reg *Registry, data []byte) (*chi.Mux, error) {
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return Build(reg, cfg)
+}
+
+// LoadFile returns the router described by the YAML or JSON file at `path`.
+func LoadFile(reg *Registry, path string) (*chi.Mux, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Load(reg, data)
+}
+
+// Build returns the router described by the config. Names missing from the
+// registry, and the routes the router refuses, ie. conflicting ones, are
+// returned as errors rather than panicking, as configurations are loaded
+// while serving.
+func Build(reg *Registry, cfg *Config) (mux *chi.Mux, err error) {
+	defer recoverError(&err)
+	mux = chi.NewRouter()
+	if err := define(reg, mux, cfg); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+// Reload swaps the routes of the Mux for the ones described by the YAML or
+// JSON `data`, see chi.Mux.Reload, so gateways reload their routes while
+// serving, ie. on SIGHUP. The routes served so far are kept if the
+// description is invalid.
+func Reload(mux *chi.Mux, reg *Registry, data []byte) (err error) {
+	cfg, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	defer recoverError(&err)
+	mux.Reload(func(r chi.Router) {
+		if err := define(reg, r, cfg); err != nil {
+			// Reload keeps the routes served so far on panics
+			panic(err)
+		}
+	})
+	return nil
+}
+
+// recoverError returns the panics of the router as errors.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		if e, ok := r.(error); ok {
+			*err = e
+			return
+		}
+		*err = fmt.Errorf("routeconf: %v", r)
+	}
+}
+
+// define adds the middlewares and routes of the config to the router.
+func define(reg *Registry, r chi.Router, cfg *Config) error {
+	mws, err := middlewares(reg, cfg.Middlewares)
+	if err != nil {
+		return err
+	}
+	r.Use(mws...)
+	return addRoutes(reg, r, cfg.Routes)
+}
+
+func middlewares(reg *Registry, names []string) ([]interface{}, error) {
+	mws := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		mw, ok := reg.middleware(name)
+		if !ok {
+			return nil, fmt.Errorf("routeconf: unknown middleware '%s'", name)
+		}
+		mws = append(mws, mw)
+	}
+	return mws, nil
+}
+
+func addRoutes(reg *Registry, r chi.Router, routes []RouteConfig) error {
+	for _, rc := range routes {
+		if err := addRoute(reg, r, rc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addRoute(reg *Registry, r chi.Router, rc RouteConfig) error {
+	mws, err := middlewares(reg, rc.Middlewares)
+	if err != nil {
+		return fmt.Errorf("%v of '%s'", err, rc.Pattern)
+	}
+
+	if len(rc.Routes) > 0 {
+		if rc.Handler != "" || rc.Method != "" {
+			return fmt.Errorf("routeconf: route '%s' has both a handler and routes", rc.Pattern)
+		}
+		var err error
+		r.Route(rc.Pattern, func(r chi.Router) {
+			r.Use(mws...)
+			err = addRoutes(reg, r, rc.Routes)
+		})
+		return err
+	}
+
+	h, ok := reg.handler(rc.Handler)
+	if !ok {
+		return fmt.Errorf("routeconf: unknown handler '%s' of '%s'", rc.Handler, rc.Pattern)
+	}
+	handlers := append(mws, h)
+	if rc.Timeout != "" {
+		timeout, err := time.ParseDuration(rc.Timeout)
+		if err != nil {
+			return fmt.Errorf("routeconf: timeout of '%s': %v", rc.Pattern, err)
+		}
+		handlers = append(handlers, chi.WithTimeout(timeout))
+	}
+
+	var route *chi.Route
+	if rc.Method == "" {
+		route = r.Handle(rc.Pattern, handlers...)
+	} else {
+		route = r.Method(rc.Method, rc.Pattern, handlers...)
+	}
+	if rc.Name != "" {
+		route.Name(rc.Name)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package routeconf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestLoad(t *testing.T) {
+	reg := NewRegistry()
+	reg.Handler("listArticles", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.WriteString("articles")
+	})
+	reg.Handler("getArticle", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.WriteString("article " + chi.URLParam(ctx, "id"))
+	})
+	reg.Middleware("tag", func(next chi.Handler) chi.Handler {
+		return chi.HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.Response.Header.Set("X-Tag", "1")
+			next.ServeHTTPC(ctx, fctx)
+		})
+	})
+
+	serve := func(r *chi.Mux, method, path string) (int, string, string) {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.Header.SetMethod(method)
+		fctx.Request.SetRequestURI(path)
+		r.ServeHTTP(&fctx)
+		return fctx.Response.StatusCode(), string(fctx.Response.Body()), string(fctx.Response.Header.Peek("X-Tag"))
+	}
+
+	yamlConfig := `
+routes:
+  - pattern: /articles
+    middlewares: [tag]
+    routes:
+      - method: GET
+        pattern: /
+        handler: listArticles
+      - method: GET
+        pattern: /:id|int
+        handler: getArticle
+        name: article
+        timeout: 2s
+`
+	r, err := Load(reg, []byte(yamlConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, body, tag := serve(r, "GET", "/articles/5"); status != 200 || body != "article 5" || tag != "1" {
+		t.Fatalf("got %d '%s' '%s'", status, body, tag)
+	}
+	if status, _, _ := serve(r, "GET", "/articles/abc"); status != 404 {
+		t.Fatalf("expecting a 404, got %d", status)
+	}
+	if status, _, _ := serve(r, "POST", "/articles"); status != 405 {
+		t.Fatalf("expecting a 405, got %d", status)
+	}
+
+	jsonConfig := `{"routes": [{"pattern": "/articles", "handler": "listArticles"}]}`
+	r, err = Load(reg, []byte(jsonConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, body, _ := serve(r, "DELETE", "/articles"); status != 200 || body != "articles" {
+		t.Fatalf("got %d '%s'", status, body)
+	}
+
+	bad := map[string]string{
+		`{"routes": [{"pattern": "/a", "handler": "missing"}]}`:                                                  "unknown handler 'missing'",
+		`{"middlewares": ["missing"]}`:                                                                           "unknown middleware 'missing'",
+		`{"routes": [{"pattern": "/a", "handler": "listArticles", "timeout": "soon"}]}`:                          "timeout of '/a'",
+		`{"routes": [{"pattern": "/a", "handler": "listArticles"}, {"pattern": "/a", "handler": "getArticle"}]}`: "routeconf: chi:",
+	}
+	for config, msg := range bad {
+		if _, err := Load(reg, []byte(config)); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("expecting an error with '%s' for %s, got %v", msg, config, err)
+		}
+	}
+
+	mux := chi.NewRouter()
+	if err := Reload(mux, reg, []byte(jsonConfig)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Reload(mux, reg, []byte(`{"routes": [{"pattern": "/a", "handler": "missing"}]}`)); err == nil {
+		t.Fatal("expecting an error")
+	}
+	if status, body, _ := serve(mux, "GET", "/articles"); status != 200 || body != "articles" {
+		t.Fatalf("got %d '%s'", status, body)
+	}
+}
@@ -0,0 +1,526 @@
+package chi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A routeEntry records a route registered on a treeRouter, to report
+// conflicts and list the routes of a Mux.
+type routeEntry struct {
+	method  methodTyp
+	pattern string
+
+	// Pattern as registered in the brace syntax, if it was, ie.
+	// `/articles/{id:[0-9]+}` for `/articles/:id|^(?:[0-9]+)$`
+	spelled string
+
+	// Where the route was registered, ie. "main.go:42"
+	site string
+
+	// Number of inline middlewares in front of the route's handler
+	middlewares int
+
+	// Subrouter mounted along the route, if any
+	mount *Mux
+
+	// Metadata attached with Route.Meta, replaced on updates
+	meta map[string]interface{}
+
+	// Name given with Route.Name, to build its URLs with URLFor
+	name string
+
+	// Request conditions of the route, see Mux.Header
+	conds routeConds
+
+	// Timeout set with WithTimeout, if any
+	timeout time.Duration
+
+	// Registered with Any, serving the unknown methods whatever the
+	// UnknownMethodPolicy
+	any bool
+}
+
+// A Route is a route registered on a Mux, returned by the routing methods to
+// attach metadata to it, ie.
+//
+//	r.Get("/reports", listReports).Meta("auth", "admin")
+type Route struct {
+	tr    *treeRouter
+	entry *routeEntry
+}
+
+// Meta attaches metadata under `key` to the route, to be read with
+// RouteMeta by the handler and its middlewares.
+func (r *Route) Meta(key string, value interface{}) *Route {
+	r.tr.mu.Lock()
+	defer r.tr.mu.Unlock()
+
+	meta := make(map[string]interface{}, len(r.entry.meta)+1)
+	for k, v := range r.entry.meta {
+		meta[k] = v
+	}
+	meta[key] = value
+	r.entry.meta = meta
+	return r
+}
+
+// Name names the route, to build the URLs of its resources with URLFor, ie.
+//
+//	r.Get("/articles/:articleID", getArticle).Name("article")
+//
+// Names are looked up on the router the route is added to, then on its
+// mounted subrouters in the order they were mounted. Naming two routes of
+// a router alike panics.
+func (r *Route) Name(name string) *Route {
+	r.tr.mu.Lock()
+	defer r.tr.mu.Unlock()
+
+	if e, ok := r.tr.names[name]; ok && e != r.entry {
+		panic(fmt.Sprintf("chi: route name '%s' of '%s' already names '%s' at %s", name, r.entry.pattern, e.pattern, e.site))
+	}
+	if r.tr.names == nil {
+		r.tr.names = make(map[string]*routeEntry)
+	}
+	delete(r.tr.names, r.entry.name)
+	r.entry.name = name
+	r.tr.names[name] = r.entry
+	return r
+}
+
+// url returns the path of the route named `name` with its params set,
+// looking the name up on the subrouters mounted on the router too.
+func (tr *treeRouter) url(name string, params map[string]string) (string, error) {
+	// The routes may be removed meanwhile, so what's needed of them is
+	// copied under the lock
+	tr.mu.RLock()
+	var pattern string
+	e, ok := tr.names[name]
+	if ok {
+		pattern = e.pattern
+	}
+	var mounts []mountSite
+	for _, e := range tr.entries {
+		if e.mount != nil && strings.HasSuffix(e.pattern, "/*") {
+			mounts = append(mounts, mountSite{pattern: e.pattern[:len(e.pattern)-2], mount: e.mount})
+		}
+	}
+	tr.mu.RUnlock()
+	if ok {
+		return expandPattern(pattern, params)
+	}
+
+	for _, m := range mounts {
+		path, err := m.mount.router.url(name, params)
+		if err != nil {
+			if _, missing := err.(*missingParamError); missing {
+				return "", err
+			}
+			continue
+		}
+		prefix, err := expandPattern(m.pattern, params)
+		if err != nil {
+			return "", err
+		}
+		if path == "/" {
+			return prefix, nil
+		}
+		return prefix + path, nil
+	}
+	return "", fmt.Errorf("chi: no route named '%s'", name)
+}
+
+// A mountSite is a subrouter along with the path it's mounted on, native
+// and as registered, and the inline middlewares of the mount.
+type mountSite struct {
+	pattern, spelled string
+	middlewares      int
+	mount            *Mux
+}
+
+// A missingParamError reports a param missing to build the URL of a route.
+type missingParamError struct {
+	key, pattern string
+}
+
+func (e *missingParamError) Error() string {
+	return fmt.Sprintf("chi: missing param '%s' of route '%s'", e.key, e.pattern)
+}
+
+// expandPattern returns the path of the pattern with its params set.
+func expandPattern(pattern string, params map[string]string) (string, error) {
+	segments := strings.Split(pattern, "/")
+	for i, s := range segments {
+		var key string
+		switch {
+		case strings.HasPrefix(s, ":"):
+			key = s[1:]
+			if p := strings.IndexByte(key, '|'); p >= 0 {
+				key = key[:p]
+			}
+		case strings.HasPrefix(s, "*"):
+			key = catchAllKey(s)
+		default:
+			continue
+		}
+		v, ok := params[key]
+		if !ok {
+			return "", &missingParamError{key, pattern}
+		}
+		segments[i] = escapePath(v)
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// escapePath escapes the segments of a path.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// add registers the route in the trees of its methods under the write
+// lock, see register.
+func (tr *treeRouter) add(method methodTyp, pattern string, conds routeConds, handler Handler, middlewares int, mount *Mux) *Route {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.checkSealed()
+
+	all := method == mALL
+	if len(conds) > 0 {
+		return tr.addVariant(method, all, pattern, conds, handler, middlewares, mount)
+	}
+	if method = tr.yieldIndex(method, pattern, mount); method == 0 {
+		// Every method has its own route
+		return &Route{tr: tr, entry: &routeEntry{pattern: pattern, mount: mount}}
+	}
+
+	e := tr.register(method, pattern, nil, middlewares)
+	e.mount = mount
+	insert := func(t *tree) {
+		t.Insert(pattern, handler)
+		n, _ := t.leaf(pattern)
+		n.route = e
+	}
+	for mt := mCONNECT; mt <= method; mt <<= 1 {
+		if method&mt != 0 {
+			insert(tr.tree(mt))
+		}
+	}
+	if all {
+		insert(tr.routes[mALL])
+	}
+	return &Route{tr: tr, entry: e}
+}
+
+// addVariant registers a route with request conditions as a variant of the
+// leaf node of its pattern.
+func (tr *treeRouter) addVariant(method methodTyp, all bool, pattern string, conds routeConds, handler Handler, middlewares int, mount *Mux) *Route {
+	e := tr.register(method, pattern, conds, middlewares)
+	e.mount = mount
+	insert := func(t *tree) {
+		n, _ := t.leaf(pattern)
+		if n == nil {
+			t.Insert(pattern, nil)
+			n, _ = t.leaf(pattern)
+		}
+		if n.pattern == "" {
+			n.pattern = pattern
+		}
+		n.variants = append(n.variants, &variant{conds: conds, handler: handler, route: e})
+	}
+	for mt := mCONNECT; mt <= method; mt <<= 1 {
+		if method&mt != 0 {
+			insert(tr.tree(mt))
+		}
+	}
+	if all {
+		insert(tr.routes[mALL])
+	}
+	return &Route{tr: tr, entry: e}
+}
+
+// yieldIndex lets the routes registered on the path a subrouter is mounted
+// on take over the index routes of the mount for their methods, whichever
+// is registered first, ie. to serve an index the subrouter lacks:
+//
+//	r.Get("/users", listUsers)
+//	r.Mount("/users", usersRouter)
+//
+// It returns the methods left to register the route for.
+func (tr *treeRouter) yieldIndex(method methodTyp, pattern string, mount *Mux) methodTyp {
+	key := normalizePattern(pattern)
+	index := mount != nil && !strings.HasSuffix(pattern, "*")
+
+	for mt := mCONNECT; mt <= method; mt <<= 1 {
+		if method&mt == 0 {
+			continue
+		}
+		prev, ok := tr.sites[mt][key]
+		switch {
+		case !ok:
+		case index:
+			method &^= mt
+		case prev.mount != nil && !strings.HasSuffix(prev.pattern, "*"):
+			tr.unregister(mt, prev.pattern, nil)
+		}
+	}
+	return method
+}
+
+// register records the route of the method and pattern, panicking if a
+// route of the method with the same pattern and conditions was registered
+// before. Patterns are the same when they only differ by their param names,
+// ie. `/ping/:id` and `/ping/:key`. Static segments overlapping params, ie.
+// `/ping/new` and `/ping/:id`, don't conflict as static segments always
+// match first.
+func (tr *treeRouter) register(method methodTyp, pattern string, conds routeConds, middlewares int) *routeEntry {
+	if tr.sites == nil {
+		tr.sites = make(map[methodTyp]map[string]*routeEntry)
+	}
+	key := normalizePattern(pattern) + conds.key()
+	e := &routeEntry{method: method, pattern: pattern, site: callerSite(), middlewares: middlewares, conds: conds}
+
+	for mt := mCONNECT; mt <= method; mt <<= 1 {
+		if method&mt == 0 {
+			continue
+		}
+		if prev, ok := tr.sites[mt][key]; ok {
+			panic(fmt.Sprintf("chi: route '%s %s%s' registered at %s conflicts with '%s %s%s' registered at %s",
+				methodName(mt), pattern, conds.key(), e.site, methodName(mt), prev.pattern, conds.key(), prev.site))
+		}
+	}
+
+	for mt := mCONNECT; mt <= method; mt <<= 1 {
+		if method&mt == 0 {
+			continue
+		}
+		if tr.sites[mt] == nil {
+			tr.sites[mt] = make(map[string]*routeEntry)
+		}
+		tr.sites[mt][key] = e
+	}
+	tr.entries = append(tr.entries, e)
+	return e
+}
+
+// routePattern returns the pattern of the route as registered.
+func (e *routeEntry) routePattern() string {
+	if e.spelled != "" {
+		return e.spelled
+	}
+	return e.pattern
+}
+
+// unregister drops the route of the methods, pattern and conditions from
+// the records and the trees, returning the methods it was registered for.
+// Routes of all methods leave the tree serving custom methods once
+// unregistered for every method.
+func (tr *treeRouter) unregister(method methodTyp, pattern string, conds routeConds) methodTyp {
+	key := normalizePattern(pattern) + conds.key()
+
+	var removed methodTyp
+	for mt := mCONNECT; mt <= method; mt <<= 1 {
+		if method&mt == 0 {
+			continue
+		}
+		e, ok := tr.sites[mt][key]
+		if !ok {
+			continue
+		}
+		delete(tr.sites[mt], key)
+		removed |= mt
+		if t := tr.routes[mt]; t != nil {
+			t.deleteRoute(e)
+		}
+
+		if e.method &^= mt; e.method == 0 {
+			for i, ee := range tr.entries {
+				if ee == e {
+					tr.entries = append(tr.entries[:i], tr.entries[i+1:]...)
+					break
+				}
+			}
+			tr.routes[mALL].deleteRoute(e)
+			if tr.names[e.name] == e {
+				delete(tr.names, e.name)
+			}
+		}
+	}
+	return removed
+}
+
+// normalizePattern drops the param names of a pattern, keeping their types.
+func normalizePattern(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			_, typ := parseParam(seg)
+			segments[i] = ":" + typ
+		case strings.HasPrefix(seg, "*"):
+			segments[i] = "*"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// callerSite returns the file:line of the first caller outside of chi's own
+// sources, ie. the application code registering a route.
+func callerSite() string {
+	_, self, _, _ := runtime.Caller(0)
+	dir := filepath.Dir(self)
+
+	pc := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(2, pc)])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != dir || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// methodName returns the http method names of a method type, or "*" for
+// all methods.
+func methodName(method methodTyp) string {
+	if method == mALL {
+		return "*"
+	}
+	var names []string
+	for m, mt := range methodMap {
+		if method&mt != 0 {
+			names = append(names, m)
+		}
+	}
+	methods, _ := customMethods.Load().(map[string]methodTyp)
+	for m, mt := range methods {
+		if method&mt != 0 {
+			names = append(names, m)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// A RouteInfo is a route served by a Mux, with the full pattern of routes
+// of mounted subrouters, see Mux.Routes.
+type RouteInfo struct {
+	// Method of the route, "*" for routes of all methods
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+
+	// Name given with Route.Name, if any
+	Name string `json:"name,omitempty"`
+
+	// Request conditions of the route, ie. `[x-api-version=2]`, see
+	// Mux.Header
+	Conditions string `json:"conditions,omitempty"`
+
+	// Number of middlewares in front of the handler, from the router
+	// stacks down to the inline ones
+	Middlewares int `json:"middlewares"`
+
+	// Timeout set with WithTimeout, in nanoseconds in JSON
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// Metadata attached with Route.Meta, left out of JSON as values may
+	// not be serializable
+	Meta map[string]interface{} `json:"-"`
+
+	// Pattern in the colon syntax of the trees, keying the diffs
+	native string
+}
+
+// Routes returns the routes of the Mux and its mounted subrouters, sorted
+// by pattern and method, ie. to list them on an admin endpoint, see
+// RoutesJSON, or to diff them across deployments.
+func (mx *Mux) Routes() []RouteInfo {
+	return mx.routeList()
+}
+
+// RoutesJSON returns the routes of the Mux as a JSON array, see Routes.
+func (mx *Mux) RoutesJSON() ([]byte, error) {
+	routes := mx.Routes()
+	if routes == nil {
+		routes = []RouteInfo{}
+	}
+	return json.MarshalIndent(routes, "", "  ")
+}
+
+// routeList returns the routes of the Mux and its mounted subrouters,
+// sorted by pattern and method.
+func (mx *Mux) routeList() []RouteInfo {
+	var routes []RouteInfo
+	mx.current().walkRoutes("", "", 0, func(ri RouteInfo) {
+		routes = append(routes, ri)
+	})
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		if routes[i].Method != routes[j].Method {
+			return routes[i].Method < routes[j].Method
+		}
+		return routes[i].Conditions < routes[j].Conditions
+	})
+	return routes
+}
+
+// walkRoutes calls fn for each route of the Mux, prefixing patterns, as
+// registered and native, and adding middlewares of the routers it's
+// mounted on.
+func (mx *Mux) walkRoutes(prefix, native string, middlewares int, fn func(ri RouteInfo)) {
+	middlewares += len(mx.middlewares)
+
+	// The routes are copied under the lock, as they may be removed while
+	// fn runs
+	var routes []RouteInfo
+	var mounts []mountSite
+	mx.router.mu.RLock()
+	for _, e := range mx.router.entries {
+		if e.mount != nil {
+			// Mounts register the path, the path with a trailing slash, and
+			// the catch-all serving the subrouter.
+			if strings.HasSuffix(e.pattern, "/*") {
+				spelled := e.routePattern()
+				mounts = append(mounts, mountSite{
+					pattern:     e.pattern[:len(e.pattern)-2],
+					spelled:     spelled[:len(spelled)-2],
+					middlewares: e.middlewares,
+					mount:       e.mount,
+				})
+			}
+			continue
+		}
+		routes = append(routes, RouteInfo{
+			Method:      methodName(e.method),
+			Pattern:     prefix + e.routePattern(),
+			Name:        e.name,
+			Conditions:  strings.TrimSpace(e.conds.key()),
+			Middlewares: middlewares + e.middlewares,
+			Timeout:     e.timeout,
+			Meta:        e.meta,
+			native:      native + e.pattern,
+		})
+	}
+	mx.router.mu.RUnlock()
+
+	for _, ri := range routes {
+		fn(ri)
+	}
+	for _, m := range mounts {
+		m.mount.current().walkRoutes(prefix+m.spelled, native+m.pattern, middlewares+m.middlewares, fn)
+	}
+}
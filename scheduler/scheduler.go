@@ -0,0 +1,231 @@
+// Package scheduler runs the background jobs of a web service, ie. cache
+// warmups or cleanups, on the lifecycle of its router: jobs run with
+// contexts derived from the parent context shared with chi.NewRouter, so
+// they're cancelled on shutdown like the requests are, and their status is
+// served by a subrouter to mount on the ops routes of the service:
+//
+//	ctx, shutdown := context.WithCancel(context.Background())
+//	r := chi.NewRouterContext(ctx)
+//
+//	jobs := scheduler.New(ctx)
+//	jobs.Every("purge-sessions", 10*time.Minute, purgeSessions)
+//	jobs.Start()
+//	r.Mount("/ops/jobs", jobs.Routes())
+//
+//	// on SIGTERM
+//	shutdown()
+//	jobs.Wait()
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hmgle/chi"
+	"github.com/hmgle/chi/render"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// A Job is run by the scheduler with a context cancelled on shutdown, which
+// long jobs should watch to return early.
+type Job func(ctx context.Context) error
+
+// A Scheduler runs jobs at intervals until its parent context is done.
+type Scheduler struct {
+	parent context.Context
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	started bool
+	wg      sync.WaitGroup
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       Job
+
+	// Status, guarded by the scheduler's mutex
+	running      bool
+	runs         int
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastErr      error
+	nextRun      time.Time
+}
+
+// New returns a Scheduler running jobs with contexts derived from the
+// optional `parent` context, the one of the router to share its shutdown.
+func New(parent ...context.Context) *Scheduler {
+	pctx := context.Background()
+	if len(parent) > 0 {
+		pctx = parent[0]
+	}
+	return &Scheduler{parent: pctx, jobs: make(map[string]*job)}
+}
+
+// Every registers the job `fn` under `name`, run every `interval` once the
+// scheduler started. A run is skipped while the previous one is still
+// running. Registering a name twice or once started panics.
+func (s *Scheduler) Every(name string, interval time.Duration, fn Job) {
+	if interval <= 0 {
+		panic(fmt.Sprintf("scheduler: interval of job '%s' must be positive", name))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		panic(fmt.Sprintf("scheduler: job '%s' registered once started", name))
+	}
+	if _, ok := s.jobs[name]; ok {
+		panic(fmt.Sprintf("scheduler: job '%s' already registered", name))
+	}
+	s.jobs[name] = &job{name: name, interval: interval, fn: fn}
+}
+
+// Start starts running the jobs, their first run being one interval away.
+// Jobs stop being run once the parent context is done.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	now := time.Now()
+	for _, j := range s.jobs {
+		j.nextRun = now.Add(j.interval)
+		s.wg.Add(1)
+		go s.loop(j)
+	}
+}
+
+// Wait blocks until the parent context is done and the running jobs have
+// returned.
+func (s *Scheduler) Wait() {
+	<-s.parent.Done()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(j *job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.parent.Done():
+			return
+		case <-ticker.C:
+			s.run(j)
+		}
+	}
+}
+
+// run runs the job once, recording its outcome. Panics of the job are
+// recovered as its error, so a faulty job doesn't take the service down.
+func (s *Scheduler) run(j *job) {
+	start := time.Now()
+	s.mu.Lock()
+	j.running = true
+	s.mu.Unlock()
+
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		err = j.fn(s.parent)
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.running = false
+	j.runs++
+	j.lastRun = start
+	j.lastDuration = time.Since(start)
+	j.lastErr = err
+	j.nextRun = time.Now().Add(j.interval)
+}
+
+// A JobStatus is the status of a job, served by Routes.
+type JobStatus struct {
+	Name     string `json:"name"`
+	Interval string `json:"interval"`
+	Running  bool   `json:"running"`
+	Runs     int    `json:"runs"`
+
+	// Outcome of the last run, if any
+	LastRun      *time.Time `json:"lastRun,omitempty"`
+	LastDuration string     `json:"lastDuration,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+
+	// Time of the next run, once started
+	NextRun *time.Time `json:"nextRun,omitempty"`
+}
+
+// Status returns the status of the jobs, sorted by name.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, j.status())
+	}
+	sort.Slice(statuses, func(i, k int) bool { return statuses[i].Name < statuses[k].Name })
+	return statuses
+}
+
+func (j *job) status() JobStatus {
+	st := JobStatus{
+		Name:     j.name,
+		Interval: j.interval.String(),
+		Running:  j.running,
+		Runs:     j.runs,
+	}
+	if j.runs > 0 {
+		lastRun := j.lastRun
+		st.LastRun = &lastRun
+		st.LastDuration = j.lastDuration.String()
+		if j.lastErr != nil {
+			st.LastError = j.lastErr.Error()
+		}
+	}
+	if !j.nextRun.IsZero() {
+		nextRun := j.nextRun
+		st.NextRun = &nextRun
+	}
+	return st
+}
+
+// Routes returns a router serving the status of the jobs as JSON, the list
+// of them on `/` and the status of a job on `/:name`.
+func (s *Scheduler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		render.JSON(fctx, fasthttp.StatusOK, s.Status())
+	})
+	r.Get("/:name", func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		name := chi.URLParam(ctx, "name")
+		s.mu.Lock()
+		j, ok := s.jobs[name]
+		var st JobStatus
+		if ok {
+			st = j.status()
+		}
+		s.mu.Unlock()
+		if !ok {
+			render.Writer(ctx, fctx).NotFound(fmt.Errorf("no job named '%s'", name))
+			return
+		}
+		render.JSON(fctx, fasthttp.StatusOK, st)
+	})
+	return r
+}
@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hmgle/chi"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestScheduler(t *testing.T) {
+	ctx, shutdown := context.WithCancel(context.Background())
+	s := New(ctx)
+
+	var runs int32
+	s.Every("count", 5*time.Millisecond, func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	})
+	s.Every("fail", 5*time.Millisecond, func(ctx context.Context) error {
+		return errors.New("boom")
+	})
+	s.Every("panic", 5*time.Millisecond, func(ctx context.Context) error {
+		panic("oops")
+	})
+	s.Start()
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&runs) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	r := chi.NewRouter()
+	r.Mount("/ops/jobs", s.Routes())
+	serve := func(path string) (int, []byte) {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(path)
+		r.ServeHTTP(&fctx)
+		return fctx.Response.StatusCode(), fctx.Response.Body()
+	}
+
+	status, body := serve("/ops/jobs")
+	var statuses []JobStatus
+	if err := json.Unmarshal(body, &statuses); status != 200 || err != nil || len(statuses) != 3 {
+		t.Fatalf("got %d '%s' (%v)", status, body, err)
+	}
+	if st := statuses[0]; st.Name != "count" || st.Runs < 2 || st.LastRun == nil || st.LastError != "" {
+		t.Fatalf("got %+v", st)
+	}
+	if st := statuses[1]; st.Name != "fail" || st.LastError != "boom" {
+		t.Fatalf("got %+v", st)
+	}
+	if st := statuses[2]; st.Name != "panic" || st.LastError != "panic: oops" {
+		t.Fatalf("got %+v", st)
+	}
+
+	if status, _ := serve("/ops/jobs/count"); status != 200 {
+		t.Fatalf("expecting a 200, got %d", status)
+	}
+	if status, _ := serve("/ops/jobs/missing"); status != 404 {
+		t.Fatalf("expecting a 404, got %d", status)
+	}
+
+	shutdown()
+	s.Wait()
+	n := atomic.LoadInt32(&runs)
+	time.Sleep(20 * time.Millisecond)
+	if atomic.LoadInt32(&runs) != n {
+		t.Fatal("expecting jobs to stop on shutdown")
+	}
+}
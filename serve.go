@@ -0,0 +1,188 @@
+package chi
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// LifecycleTimeout bounds the time each OnStart and OnStop hook may take.
+var LifecycleTimeout = 30 * time.Second
+
+// lifecycle holds the hooks run by Serve, and the readiness of the service.
+var lifecycle struct {
+	mu    sync.Mutex
+	start []func(ctx context.Context) error
+	stop  []func(ctx context.Context) error
+	ready int32
+}
+
+// OnStart registers `fn` to be run by Serve before it starts serving, ie. to
+// open the db pools, warm the caches and connect the session store of the
+// service. Hooks run in the order they were registered, each bounded by
+// LifecycleTimeout, and a hook failing or timing out fails the startup.
+func OnStart(fn func(ctx context.Context) error) {
+	lifecycle.mu.Lock()
+	defer lifecycle.mu.Unlock()
+	lifecycle.start = append(lifecycle.start, fn)
+}
+
+// OnStop registers `fn` to be run by Serve once it stopped serving, ie. to
+// drain the resources set up by the OnStart hooks. Hooks run in the reverse
+// order they were registered, so resources set up first are drained last,
+// and all of them run even when some fail.
+func OnStop(fn func(ctx context.Context) error) {
+	lifecycle.mu.Lock()
+	defer lifecycle.mu.Unlock()
+	lifecycle.stop = append(lifecycle.stop, fn)
+}
+
+// Ready reports whether Serve is serving, its OnStart hooks having all
+// succeeded, and not stopping.
+func Ready() bool {
+	return atomic.LoadInt32(&lifecycle.ready) == 1
+}
+
+// ReadyCheck is a readiness check handler responding 200 while Ready and
+// 503 otherwise, for load balancers to route requests to the service once
+// it started and away from it once it stops.
+func ReadyCheck(ctx context.Context, fctx *fasthttp.RequestCtx) {
+	if !Ready() {
+		fctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+		return
+	}
+	fctx.SetStatusCode(fasthttp.StatusOK)
+	fctx.WriteString("ready")
+}
+
+func setReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&lifecycle.ready, v)
+}
+
+// Serve runs the OnStart hooks, serves the Mux on the TCP address `addr`
+// until its parent context is done, see NewRouterContext, then stops
+// accepting connections, waits for the requests being served to finish and
+// runs the OnStop hooks, managing the lifecycle of the service in one
+// place:
+//
+//	ctx, shutdown := context.WithCancel(context.Background())
+//	r := chi.NewRouterContext(ctx)
+//	r.Get("/ready", chi.ReadyCheck)
+//
+//	chi.OnStart(db.Open)
+//	chi.OnStop(db.Close)
+//
+//	// shutdown() on SIGTERM
+//	if err := chi.Serve(":3333", r); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Serve returns the error of the OnStart hook failing the startup without
+// serving, nor running the OnStop hooks, or else the error of the server
+// or of the first OnStop hook failing.
+func Serve(addr string, mx *Mux) error {
+	setReady(false)
+	if err := runLifecycle(mx.parentCtx, "start", lifecycleHooks(false)); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &fasthttp.Server{
+		Handler:      mx.ServeHTTP,
+		ErrorHandler: mx.ServerErrorHandler(),
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+	setReady(true)
+
+	select {
+	case <-mx.parentCtx.Done():
+		setReady(false)
+		// Waits for the requests being served, so the stop hooks don't
+		// drain resources their handlers still use
+		err = srv.Shutdown()
+		<-served
+	case err = <-served:
+		setReady(false)
+	}
+
+	// The parent context is done by now, the stop hooks get a fresh one
+	if stopErr := runLifecycle(context.Background(), "stop", lifecycleHooks(true)); err == nil {
+		err = stopErr
+	}
+	return err
+}
+
+// lifecycleHooks returns the OnStart hooks, or the OnStop hooks in the
+// order they run.
+func lifecycleHooks(stop bool) []func(ctx context.Context) error {
+	lifecycle.mu.Lock()
+	defer lifecycle.mu.Unlock()
+	if !stop {
+		return append([]func(ctx context.Context) error(nil), lifecycle.start...)
+	}
+	hooks := make([]func(ctx context.Context) error, 0, len(lifecycle.stop))
+	for i := len(lifecycle.stop) - 1; i >= 0; i-- {
+		hooks = append(hooks, lifecycle.stop[i])
+	}
+	return hooks
+}
+
+// runLifecycle runs the hooks of the `stage` in order, each with a context
+// derived from `parent` cancelled after LifecycleTimeout. Start hooks stop
+// at the first failure, while all stop hooks run, the first failure being
+// returned.
+func runLifecycle(parent context.Context, stage string, hooks []func(ctx context.Context) error) error {
+	var first error
+	for _, fn := range hooks {
+		err := runHook(parent, fn)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("chi: %s hook %s: %v", stage, middlewareName(fn), err)
+		if stage == "start" {
+			return err
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// runHook runs a hook, giving up on it once its context is done.
+func runHook(parent context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(parent, LifecycleTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,151 @@
+package chi
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+func TestServe(t *testing.T) {
+	defer func() {
+		lifecycle.start, lifecycle.stop = nil, nil
+	}()
+
+	var mu sync.Mutex
+	var calls []string
+	hook := func(name string, err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			calls = append(calls, name)
+			mu.Unlock()
+			return err
+		}
+	}
+	OnStart(hook("start db", nil))
+	OnStart(hook("start cache", nil))
+	OnStop(hook("stop db", nil))
+	OnStop(hook("stop cache", errors.New("flush failed")))
+
+	ctx, shutdown := context.WithCancel(context.Background())
+	r := NewRouterContext(ctx)
+	r.Get("/ready", ReadyCheck)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve("127.0.0.1:0", r)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for !Ready() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !Ready() {
+		t.Fatal("expecting the service to be ready")
+	}
+	shutdown()
+
+	err := <-served
+	if err == nil || !strings.Contains(err.Error(), "flush failed") {
+		t.Fatalf("expecting the stop hook error, got %v", err)
+	}
+	if Ready() {
+		t.Fatal("expecting the service to be unready once stopped")
+	}
+	expected := "start db, start cache, stop cache, stop db"
+	if got := strings.Join(calls, ", "); got != expected {
+		t.Fatalf("expecting %s, got %s", expected, got)
+	}
+
+	calls = nil
+	lifecycle.start = nil
+	OnStart(hook("start db", errors.New("no db")))
+	OnStart(hook("start cache", nil))
+	if err := Serve("127.0.0.1:0", NewRouter()); err == nil || !strings.Contains(err.Error(), "no db") {
+		t.Fatalf("expecting the start hook error, got %v", err)
+	}
+	if got := strings.Join(calls, ", "); got != "start db" {
+		t.Fatalf("expecting the startup to stop at the failing hook, got %s", got)
+	}
+
+	defer func(timeout time.Duration) { LifecycleTimeout = timeout }(LifecycleTimeout)
+	LifecycleTimeout = 10 * time.Millisecond
+	lifecycle.start = nil
+	OnStart(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	if err := Serve("127.0.0.1:0", NewRouter()); err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Fatalf("expecting a timeout, got %v", err)
+	}
+}
+
+func TestServeDrain(t *testing.T) {
+	defer func() {
+		lifecycle.start, lifecycle.stop = nil, nil
+	}()
+
+	var finished int32
+	drained := make(chan bool, 1)
+	OnStop(func(ctx context.Context) error {
+		drained <- atomic.LoadInt32(&finished) == 1
+		return nil
+	})
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	ctx, shutdown := context.WithCancel(context.Background())
+	r := NewRouterContext(ctx)
+	r.Get("/slow", func(fctx *fasthttp.RequestCtx) {
+		close(entered)
+		<-release
+		atomic.StoreInt32(&finished, 1)
+		fctx.WriteString("done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(addr, r)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for !Ready() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	conn.Write([]byte("GET /slow HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n"))
+	<-entered
+
+	// Shutting down with the request still in its handler
+	shutdown()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	resp, _ := ioutil.ReadAll(conn)
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+	if !<-drained {
+		t.Fatal("expecting the stop hooks to run once the request finished")
+	}
+	if !strings.Contains(string(resp), "200 OK") || !strings.HasSuffix(string(resp), "done") {
+		t.Fatalf("expecting the request to be served, got %q", resp)
+	}
+}
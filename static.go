@@ -0,0 +1,95 @@
+package chi
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"path/filepath"
+	"sort"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// A StaticManifest lists static assets by the path they're served on, ie.
+// as read from a JSON manifest:
+//
+//	{
+//		"/app.js": {"file": "dist/app.3f2a9c.js", "immutable": true},
+//		"/robots.txt": {"file": "robots.txt", "headers": {"X-Robots-Tag": "noindex"}}
+//	}
+type StaticManifest map[string]StaticAsset
+
+// A StaticAsset is a file served on a route of a StaticManifest.
+type StaticAsset struct {
+	// File path, relative to the root directory given to Mux.Static
+	File string `json:"file"`
+
+	// Headers set on the responses
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Immutable assets are cached by clients for a year, ie. files whose
+	// names change with their content
+	Immutable bool `json:"immutable,omitempty"`
+}
+
+// ReadStaticManifest decodes a JSON StaticManifest.
+func ReadStaticManifest(r io.Reader) (StaticManifest, error) {
+	var manifest StaticManifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Static registers a GET route for each asset of the manifest, serving the
+// asset file under the `root` directory from memory. The files are read
+// once, so a small and fixed set of assets is served without the lookups
+// of a FileServer. It returns an error if a file can't be read, before any
+// route is registered.
+func (mx *Mux) Static(root string, manifest StaticManifest) error {
+	paths := make([]string, 0, len(manifest))
+	for path := range manifest {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	handlers := make([]HandlerFunc, len(paths))
+	for i, path := range paths {
+		h, err := staticHandler(root, manifest[path])
+		if err != nil {
+			return err
+		}
+		handlers[i] = h
+	}
+
+	for i, path := range paths {
+		mx.Get(path, handlers[i])
+	}
+	return nil
+}
+
+// staticHandler reads the asset file and returns the handler serving it.
+func staticHandler(root string, asset StaticAsset) (HandlerFunc, error) {
+	body, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(asset.File)))
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(asset.File))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+		fctx.SetContentType(contentType)
+		if asset.Immutable {
+			fctx.Response.Header.Set("Cache-Control", "public, max-age=31536000, immutable")
+		}
+		for k, v := range asset.Headers {
+			fctx.Response.Header.Set(k, v)
+		}
+		fctx.SetBody(body)
+	}), nil
+}
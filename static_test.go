@@ -0,0 +1,114 @@
+package chi
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestMuxStatic(t *testing.T) {
+	root, err := ioutil.TempDir("", "chi-static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	os.Mkdir(filepath.Join(root, "dist"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "dist", "app.3f2a9c.js"), []byte("console.log('chi')"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "robots.txt"), []byte("User-agent: *"), 0644)
+
+	manifest, err := ReadStaticManifest(strings.NewReader(`{
+		"/app.js": {"file": "dist/app.3f2a9c.js", "immutable": true},
+		"/robots.txt": {"file": "robots.txt", "headers": {"X-Robots-Tag": "noindex"}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRouter()
+	if err := r.Static(root, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI("/app.js")
+	r.ServeHTTP(&fctx)
+	if body := string(fctx.Response.Body()); body != "console.log('chi')" {
+		t.Fatalf("got '%s'", body)
+	}
+	if cc := string(fctx.Response.Header.Peek("Cache-Control")); cc != "public, max-age=31536000, immutable" {
+		t.Fatalf("got Cache-Control '%s'", cc)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("/robots.txt")
+	r.ServeHTTP(&fctx)
+	if tag := string(fctx.Response.Header.Peek("X-Robots-Tag")); tag != "noindex" {
+		t.Fatalf("got X-Robots-Tag '%s'", tag)
+	}
+	if ct := string(fctx.Response.Header.ContentType()); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("got Content-Type '%s'", ct)
+	}
+
+	manifest["/missing.css"] = StaticAsset{File: "missing.css"}
+	if err := NewRouter().Static(root, manifest); err == nil {
+		t.Fatalf("expecting an error for a missing file")
+	}
+}
+
+func TestMuxFingerprint(t *testing.T) {
+	root, err := ioutil.TempDir("", "chi-assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	os.Mkdir(filepath.Join(root, "js"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "js", "app.js"), []byte("console.log('chi')"), 0644)
+	ioutil.WriteFile(filepath.Join(root, ".env"), []byte("SECRET=1"), 0644)
+
+	r := NewRouter()
+	assets, err := r.Fingerprint("/assets/", root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.FileServer("/assets/*", root)
+
+	url := assets.URL("/assets/js/app.js")
+	if !strings.HasPrefix(url, "/assets/js/app.") || !strings.HasSuffix(url, ".js") || len(url) != len("/assets/js/app.js")+11 {
+		t.Fatalf("got url '%s'", url)
+	}
+	if u := assets.URL("/assets/.env"); u != "/assets/.env" {
+		t.Fatalf("expecting dot files not to be fingerprinted, got '%s'", u)
+	}
+
+	var fctx fasthttp.RequestCtx
+	fctx.Request.SetRequestURI(url)
+	r.ServeHTTP(&fctx)
+	if body := string(fctx.Response.Body()); body != "console.log('chi')" {
+		t.Fatalf("got '%s'", body)
+	}
+	if cc := string(fctx.Response.Header.Peek("Cache-Control")); cc != "public, max-age=31536000, immutable" {
+		t.Fatalf("got Cache-Control '%s'", cc)
+	}
+
+	fctx = fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("/assets/js/app.js")
+	r.ServeHTTP(&fctx)
+	if body := string(fctx.Response.Body()); body != "console.log('chi')" || fctx.Response.Header.Peek("ETag") == nil {
+		t.Fatalf("expecting the plain path to be served with an ETag, got '%s'", body)
+	}
+
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("page").Funcs(assets.FuncMap()).Parse(`<script src="{{ asset "/assets/js/app.js" }}"></script>`))
+	tmpl.Execute(&buf, nil)
+	if buf.String() != `<script src="`+url+`"></script>` {
+		t.Fatalf("got '%s'", buf.String())
+	}
+}
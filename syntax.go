@@ -0,0 +1,83 @@
+package chi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A PatternSyntax is the syntax of the params of route patterns.
+type PatternSyntax int
+
+const (
+	// ColonSyntax declares params as `/users/:id`, with an optional value
+	// type as in `/users/:id|int`, which is the default.
+	ColonSyntax PatternSyntax = iota
+
+	// BraceSyntax declares params as upstream chi does, `/users/{id}`, with
+	// an optional regexp the value must match as in `/users/{id:[0-9]+}`.
+	BraceSyntax
+)
+
+// Syntax sets the param syntax of the patterns of the router, its Groups
+// and Route subrouters, so routes can be shared with upstream chi services.
+// Wildcards are `*` in both syntaxes.
+func (mx *Mux) Syntax(syntax PatternSyntax) {
+	mx.router.syntax = syntax
+}
+
+// native returns the pattern in the colon syntax used by the tree.
+func (s PatternSyntax) native(pattern string) string {
+	if s != BraceSyntax || strings.IndexByte(pattern, '{') < 0 {
+		return pattern
+	}
+
+	buf := make([]byte, 0, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '{' {
+			buf = append(buf, pattern[i])
+			continue
+		}
+
+		// Find the closing brace, regexps may have braces of their own
+		depth, end := 0, -1
+		for j := i; j < len(pattern) && end < 0; j++ {
+			switch pattern[j] {
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					end = j
+				}
+			}
+		}
+		if end < 0 {
+			panic(fmt.Sprintf("chi: missing closing brace in pattern '%s'", pattern))
+		}
+		if end+1 < len(pattern) && pattern[end+1] != '/' {
+			panic(fmt.Sprintf("chi: param '%s' must end its segment in pattern '%s'", pattern[i:end+1], pattern))
+		}
+
+		key, rexp := pattern[i+1:end], ""
+		if p := strings.IndexByte(key, ':'); p >= 0 {
+			key, rexp = key[:p], key[p+1:]
+		}
+		if key == "" {
+			panic(fmt.Sprintf("chi: param without a name in pattern '%s'", pattern))
+		}
+
+		buf = append(buf, ':')
+		buf = append(buf, key...)
+		if rexp != "" {
+			if strings.IndexByte(rexp, '/') >= 0 {
+				panic(fmt.Sprintf("chi: param regexp '%s' can't match '/' in pattern '%s'", rexp, pattern))
+			}
+			// Grouped, so the anchors apply to every branch of an
+			// alternation like `json|xml`
+			buf = append(buf, "|^(?:"...)
+			buf = append(buf, rexp...)
+			buf = append(buf, ")$"...)
+		}
+		i = end
+	}
+	return string(buf)
+}
@@ -0,0 +1,42 @@
+package chi
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// A Span is a named duration measured during a request with Timing.
+type Span struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Timing starts a named span on the routing context and returns the func
+// that stops it, ie.
+//
+//	defer chi.Timing(ctx, "db")()
+//
+// Recorded spans are reported to clients by middleware.ServerTiming. The
+// span is dropped if the context holds no routing context.
+func Timing(ctx context.Context, name string) func() {
+	rctx := RouteContext(ctx)
+	if rctx == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		rctx.mu.Lock()
+		rctx.spans = append(rctx.spans, Span{Name: name, Duration: time.Since(start)})
+		rctx.mu.Unlock()
+	}
+}
+
+// Spans returns the spans recorded with Timing during the request, in the
+// order they were stopped.
+func (x *Context) Spans() []Span {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return append([]Span(nil), x.spans...)
+}
@@ -0,0 +1,82 @@
+package chi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/context"
+)
+
+// TraceHeader sets the request header asking for the routing trace of a
+// request, ie. `X-Chi-Trace`. A request sending the header with the value
+// `1` gets the same header in its response, listing the steps the routers
+// took to match it: the trees consulted with the nodes visited, the
+// retries with the trailing slash toggled, the hosts and mounted
+// subrouters the request was delegated to, and the outcome:
+//
+//	$ curl -sI -H 'X-Chi-Trace: 1' localhost:3333/api/users/1
+//	X-Chi-Trace: route GET /api/users/1; GET tree: matched /api/*, 3 nodes visited; serve /api/*; mount /api/* routes /users/1; GET tree: matched /users/:id, 2 nodes visited; serve /api/users/:id
+//
+// The trace exposes the routes of the service, so enable it in staging or
+// behind a gateway stripping the header from outside requests. An empty
+// name, the default, disables it. It applies to the router and its
+// subrouters.
+func (mx *Mux) TraceHeader(name string) {
+	mx.router.traceHeader = name
+}
+
+// RouteTrace returns the routing trace of the request, see Mux.TraceHeader,
+// for middlewares to log it. It's empty unless the request asked for it.
+func RouteTrace(ctx context.Context) []string {
+	rctx := RouteContext(ctx)
+	if rctx == nil {
+		return nil
+	}
+	return rctx.trace
+}
+
+// startTrace starts tracing the request if it asks for it, returning
+// whether this router started it and sets the response header.
+func (tr *treeRouter) startTrace(rctx *Context, fctx *fasthttp.RequestCtx) bool {
+	if tr.traceHeader == "" || rctx.tracing || string(fctx.Request.Header.Peek(tr.traceHeader)) != "1" {
+		return false
+	}
+	rctx.tracing = true
+	return true
+}
+
+// tracef records a step of the routing trace, if the request is traced.
+// Routers check Context.tracing before calling it, so the arguments of the
+// steps of untraced requests aren't boxed on the hot path.
+func (x *Context) tracef(format string, args ...interface{}) {
+	if x.tracing {
+		x.trace = append(x.trace, fmt.Sprintf(format, args...))
+	}
+}
+
+// writeTrace sets the routing trace as the response header `name`.
+func (x *Context) writeTrace(fctx *fasthttp.RequestCtx, name string) {
+	fctx.Response.Header.Set(name, strings.Join(x.trace, "; "))
+}
+
+// findTraced looks the path up in the tree of the method, recording the
+// lookup in the routing trace.
+func (tr *treeRouter) findTraced(rctx *Context, method methodTyp, path []byte) *node {
+	t := tr.routes[method]
+	if !rctx.tracing {
+		return t.findRoute(rctx, path)
+	}
+
+	visited := rctx.visited
+	n := t.findRoute(rctx, path)
+	switch {
+	case n == nil:
+		rctx.tracef("%s tree: no match, %d nodes visited", methodName(method), rctx.visited-visited)
+	case rctx.visited == visited:
+		rctx.tracef("%s tree: matched %s statically", methodName(method), n.pattern)
+	default:
+		rctx.tracef("%s tree: matched %s, %d nodes visited", methodName(method), n.pattern, rctx.visited-visited)
+	}
+	return n
+}
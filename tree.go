@@ -5,8 +5,10 @@ package chi
 // (MIT licensed)
 
 import (
+	"bytes"
 	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
 type nodeTyp uint8
@@ -38,13 +40,34 @@ type node struct {
 	// HTTP handler on the leaf node
 	handler Handler
 
+	// Route pattern the leaf handler was registered with
+	pattern string
+
+	// Route registered on the leaf node by a Mux, if any
+	route *routeEntry
+
+	// Handlers serving the requests matching their conditions, see
+	// Mux.Header
+	variants []*variant
+
+	// URL param key and optional value type of a param node,
+	// ie. "id" and "int" for the `:id|int` segment, and the func
+	// checking values of the type
+	paramKey string
+	paramTyp string
+	validate func(string) bool
+
 	// Edges should be stored in-order for iteration,
 	// in groups of the node type.
 	edges [ntCatchAll + 1]edges
+
+	// First bytes of the prefixes of the static edges, in their order,
+	// indexing them for lookups
+	indices string
 }
 
 func (n *node) isLeaf() bool {
-	return n.handler != nil
+	return n.handler != nil || len(n.variants) > 0
 }
 
 func (n *node) addEdge(e edge) {
@@ -67,22 +90,27 @@ func (n *node) addEdge(e edge) {
 	if p == 0 {
 		// Path starts with a wildcard
 
-		handler := e.node.handler
+		handler, pattern := e.node.handler, e.node.pattern
 		e.node.typ = ntyp
 
-		if ntyp == ntCatchAll {
-			p = -1
-		} else {
-			p = strings.IndexByte(search, '/')
-		}
+		// A catch-all in the middle of a pattern, ie. `/assets/*/meta.json`,
+		// is split off its suffix like a param
+		p = strings.IndexByte(search, '/')
 		if p < 0 {
 			p = len(search)
 		}
 		e.node.prefix = search[:p]
 
+		if ntyp == ntCatchAll {
+			e.node.paramKey = catchAllKey(e.node.prefix)
+		} else {
+			e.node.paramKey, e.node.paramTyp = parseParam(e.node.prefix)
+			e.node.validate = paramValidator(e.node.paramTyp)
+		}
+
 		if p != len(search) {
 			// add edge for the remaining part, split the end.
-			e.node.handler = nil
+			e.node.handler, e.node.pattern = nil, ""
 
 			search = search[p:]
 			e2 := edge{
@@ -91,6 +119,7 @@ func (n *node) addEdge(e edge) {
 					typ:     ntStatic,
 					prefix:  search,
 					handler: handler,
+					pattern: pattern,
 				},
 			}
 			e.node.addEdge(e2)
@@ -100,10 +129,10 @@ func (n *node) addEdge(e edge) {
 		// Path has some wildcard
 
 		// starts with a static segment
-		handler := e.node.handler
+		handler, pattern := e.node.handler, e.node.pattern
 		e.node.typ = ntStatic
 		e.node.prefix = search[:p]
-		e.node.handler = nil
+		e.node.handler, e.node.pattern = nil, ""
 
 		// add the wild edge node
 		search = search[p:]
@@ -114,6 +143,7 @@ func (n *node) addEdge(e edge) {
 				typ:     ntyp,
 				prefix:  search,
 				handler: handler,
+				pattern: pattern,
 			},
 		}
 		e.node.addEdge(e2)
@@ -126,6 +156,16 @@ func (n *node) addEdge(e edge) {
 
 	n.edges[e.node.typ] = append(n.edges[e.node.typ], e)
 	n.edges[e.node.typ].Sort()
+	n.reindex()
+}
+
+// reindex rebuilds the index of the static edges.
+func (n *node) reindex() {
+	labels := make([]byte, len(n.edges[ntStatic]))
+	for i, e := range n.edges[ntStatic] {
+		labels[i] = e.label
+	}
+	n.indices = string(labels)
 }
 
 func (n *node) replaceEdge(e edge) {
@@ -139,6 +179,48 @@ func (n *node) replaceEdge(e edge) {
 	panic("chi: replacing missing edge")
 }
 
+// getParamEdge returns the param node matching the value type of the
+// param segment at the start of `search`. Untyped params share a single
+// node regardless of their key name.
+func (n *node) getParamEdge(search string) *node {
+	p := strings.IndexByte(search, '/')
+	if p < 0 {
+		p = len(search)
+	}
+	_, ptyp := parseParam(search[:p])
+	for _, e := range n.edges[ntParam] {
+		if e.node.paramTyp == ptyp {
+			return e.node
+		}
+	}
+	return nil
+}
+
+// getCatchAllEdge returns the catch-all node of the catch-all segment at
+// the start of `search`, catch-alls being told apart by their name.
+func (n *node) getCatchAllEdge(search string) *node {
+	p := strings.IndexByte(search, '/')
+	if p < 0 {
+		p = len(search)
+	}
+	key := catchAllKey(search[:p])
+	for _, e := range n.edges[ntCatchAll] {
+		if e.node.paramKey == key {
+			return e.node
+		}
+	}
+	return nil
+}
+
+// catchAllKey returns the param key of a catch-all segment, its name as in
+// `*rest`, or "*" when unnamed.
+func catchAllKey(segment string) string {
+	if len(segment) > 1 {
+		return segment[1:]
+	}
+	return "*"
+}
+
 func (n *node) getEdge(label byte) *node {
 	for _, edges := range n.edges {
 		num := len(edges)
@@ -151,39 +233,23 @@ func (n *node) getEdge(label byte) *node {
 	return nil
 }
 
-func (n *node) findEdge(ntyp nodeTyp, label byte) *node {
-	subedges := n.edges[ntyp]
-	num := len(subedges)
-	idx := 0
-
-	switch ntyp {
-	case ntStatic:
-		i, j := 0, num-1
-		for i <= j {
-			idx = i + (j-i)/2
-			if label > subedges[idx].label {
-				i = idx + 1
-			} else if label < subedges[idx].label {
-				j = idx - 1
-			} else {
-				i = num // breaks cond
-			}
-		}
-		if subedges[idx].label != label {
-			return nil
-		}
-		return subedges[idx].node
-
-	default: // wild nodes
-		// TODO: right now we match them all.. but regexp should
-		// run through regexp matcher
-		return subedges[idx].node
+// findStaticEdge returns the static edge node whose prefix starts with
+// `label`, looked up in the index of the node. Wild edges are all tried in
+// turn by findNode, their values checked against the param types there.
+func (n *node) findStaticEdge(label byte) *node {
+	i := strings.IndexByte(n.indices, label)
+	if i < 0 {
+		return nil
 	}
+	return n.edges[ntStatic][i].node
 }
 
 // Recursive edge traversal by checking all nodeTyp groups along the way.
-// It's like searching through a three-dimensional radix trie.
-func (n *node) findNode(ctx *Context, path string) *node {
+// It's like searching through a three-dimensional radix trie. Static
+// segments are compared ignoring case when `fold` is set. Param values are
+// collected as slices of the path, see tree.findRoute.
+func (n *node) findNode(ctx *Context, path []byte, fold bool) *node {
+	ctx.visited++
 	nn := n
 	search := path
 
@@ -193,75 +259,157 @@ func (n *node) findNode(ctx *Context, path string) *node {
 			continue
 		}
 
-		// search subset of edges of the index for a matching node
-		var label byte
-		if search != "" {
-			label = search[0]
-		}
-		xn := nn.findEdge(ntyp, label) // next node
+		if ntyp == ntStatic {
+			// search subset of edges of the index for a matching node
+			var label byte
+			if len(search) > 0 {
+				label = search[0]
+			}
+
+			if fold {
+				// Edges are sorted by their exact label, so all of them
+				// are candidates when folding case.
+				for _, e := range edges {
+					xn := e.node
+					if lower(e.label) != lower(label) || !hasPrefixFold(search, xn.prefix) {
+						continue
+					}
+					if fin := xn.findLeaf(ctx, search[len(xn.prefix):], fold); fin != nil {
+						return fin
+					}
+				}
+				continue
+			}
 
-		if xn == nil {
+			xn := nn.findStaticEdge(label) // next node
+			if xn == nil || !hasPrefix(search, xn.prefix) {
+				continue // no match
+			}
+
+			// Prepare next search path by trimming prefix from requested path
+			if fin := xn.findLeaf(ctx, search[len(xn.prefix):], fold); fin != nil {
+				return fin
+			}
 			continue
 		}
 
-		// Prepare next search path by trimming prefix from requested path
-		xsearch := search
-		if xn.typ > ntStatic {
-			p := -1
-			if xn.typ < ntCatchAll {
-				p = strings.IndexByte(xsearch, '/')
+		// Wild nodes, typed params are tried before untyped ones
+		for _, e := range edges {
+			xn := e.node
+
+			if xn.typ == ntCatchAll {
+				if fin := xn.findCatchAll(ctx, search, fold); fin != nil {
+					return fin
+				}
+				continue
 			}
+
+			p := bytes.IndexByte(search, '/')
 			if p < 0 {
-				p = len(xsearch)
+				p = len(search)
 			}
 
-			if xn.typ == ntCatchAll {
-				ctx.Params.Add("*", xsearch)
-			} else {
-				ctx.Params.Add(xn.prefix[1:], xsearch[:p])
+			// Values not conforming to the param type don't match
+			if xn.validate != nil && !xn.validate(string(search[:p])) {
+				continue
 			}
 
-			xsearch = xsearch[p:]
-		} else if strings.HasPrefix(xsearch, xn.prefix) {
-			xsearch = xsearch[len(xn.prefix):]
-		} else {
-			continue // no match
-		}
+			np := len(ctx.pending)
+			ctx.pending = append(ctx.pending, pendingParam{xn.paramKey, search[:p]})
 
-		// did we find it yet?
-		if len(xsearch) == 0 {
-			if xn.isLeaf() {
-				return xn
+			if fin := xn.findLeaf(ctx, search[p:], fold); fin != nil {
+				return fin
 			}
-		}
 
-		// recursively find the next node..
-		fin := xn.findNode(ctx, xsearch)
-		if fin != nil {
-			// found a node, return it
-			return fin
+			// Did not found final handler, let's remove the param here
+			ctx.pending = ctx.pending[:np]
 		}
+	}
 
-		// Did not found final handler, let's remove the param here if it was set
-		if xn.typ > ntStatic {
-			if xn.typ == ntCatchAll {
-				ctx.Params.Del("*")
-			} else {
-				ctx.Params.Del(xn.prefix[1:])
+	return nil
+}
+
+// findCatchAll matches the catch-all node n against the search path. The
+// suffixes of a mid-path catch-all are tried first, the value spanning as
+// many segments as possible, ie. "a/b" for `/*/meta.json` and the path
+// "/a/b/meta.json", before matching the rest of the path as a trailing
+// catch-all.
+func (n *node) findCatchAll(ctx *Context, search []byte, fold bool) *node {
+	np := len(ctx.pending)
+
+	if n.numEdges() > 0 {
+		for p := bytes.LastIndexByte(search, '/'); p > 0; p = bytes.LastIndexByte(search[:p], '/') {
+			ctx.pending = append(ctx.pending, pendingParam{n.paramKey, search[:p]})
+			if fin := n.findNode(ctx, search[p:], fold); fin != nil {
+				return fin
 			}
+			ctx.pending = ctx.pending[:np]
 		}
 	}
 
+	if n.isLeaf() {
+		ctx.pending = append(ctx.pending, pendingParam{n.paramKey, search})
+		return n
+	}
 	return nil
 }
 
+// findLeaf returns n if the search path is exhausted on a leaf, otherwise
+// it continues searching along the edges of n.
+func (n *node) findLeaf(ctx *Context, search []byte, fold bool) *node {
+	// did we find it yet?
+	if len(search) == 0 && n.isLeaf() {
+		return n
+	}
+
+	// recursively find the next node..
+	return n.findNode(ctx, search, fold)
+}
+
+// hasPrefix reports whether the path starts with the prefix, without
+// converting the path to a string.
+func hasPrefix(path []byte, prefix string) bool {
+	return len(path) >= len(prefix) && string(path[:len(prefix)]) == prefix
+}
+
+// hasPrefixFold reports whether the path starts with the prefix ignoring
+// case, comparing ASCII bytes in place.
+func hasPrefixFold(path []byte, prefix string) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if path[i] >= utf8.RuneSelf || prefix[i] >= utf8.RuneSelf {
+			return strings.EqualFold(string(path[:len(prefix)]), prefix)
+		}
+		if lower(path[i]) != lower(prefix[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// lower returns the lowercase form of an ASCII letter.
+func lower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
 type edges []edge
 
-// Sort the list of edges by label
-func (e edges) Len() int           { return len(e) }
-func (e edges) Less(i, j int) bool { return e[i].label < e[j].label }
-func (e edges) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
-func (e edges) Sort()              { sort.Sort(e) }
+// Sort the list of edges by label, keeping typed params ahead of
+// untyped ones sharing the same label.
+func (e edges) Len() int      { return len(e) }
+func (e edges) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e edges) Sort()         { sort.Stable(e) }
+func (e edges) Less(i, j int) bool {
+	if e[i].label != e[j].label {
+		return e[i].label < e[j].label
+	}
+	return e[i].node.paramTyp != "" && e[j].node.paramTyp == ""
+}
 
 // Tree implements a radix tree. This can be treated as a
 // Dictionary abstract data type. The main advantage over
@@ -269,9 +417,26 @@ func (e edges) Sort()              { sort.Sort(e) }
 // ordered iteration.
 type tree struct {
 	root *node
+
+	// Match static segments ignoring case
+	caseInsensitive bool
+
+	// Nodes of the patterns without params, looked up by path before
+	// walking the tree
+	static map[string]*node
 }
 
 func (t *tree) Insert(pattern string, handler Handler) {
+	t.insert(pattern, handler)
+	if !strings.ContainsAny(pattern, ":*") {
+		if t.static == nil {
+			t.static = make(map[string]*node)
+		}
+		t.static[pattern], _ = t.leaf(pattern)
+	}
+}
+
+func (t *tree) insert(pattern string, handler Handler) {
 	var parent *node
 	n := t.root
 	search := pattern
@@ -280,13 +445,20 @@ func (t *tree) Insert(pattern string, handler Handler) {
 		// Handle key exhaustion
 		if len(search) == 0 {
 			// Insert or update the node's leaf handler
-			n.handler = handler
+			n.handler, n.pattern = handler, pattern
 			return
 		}
 
 		// Look for the edge
 		parent = n
-		n = n.getEdge(search[0])
+		switch search[0] {
+		case ':':
+			n = n.getParamEdge(search)
+		case '*':
+			n = n.getCatchAllEdge(search)
+		default:
+			n = n.getEdge(search[0])
+		}
 
 		// No edge, create one
 		if n == nil {
@@ -295,6 +467,7 @@ func (t *tree) Insert(pattern string, handler Handler) {
 				node: &node{
 					prefix:  search,
 					handler: handler,
+					pattern: pattern,
 				},
 			}
 			parent.addEdge(e)
@@ -342,7 +515,7 @@ func (t *tree) Insert(pattern string, handler Handler) {
 		// If the new key is a subset, add to to this node
 		search = search[commonPrefix:]
 		if len(search) == 0 {
-			child.handler = handler
+			child.handler, child.pattern = handler, pattern
 			return
 		}
 
@@ -353,20 +526,217 @@ func (t *tree) Insert(pattern string, handler Handler) {
 				typ:     ntStatic,
 				prefix:  search,
 				handler: handler,
+				pattern: pattern,
 			},
 		})
 		return
 	}
 }
 
-func (t *tree) Find(ctx *Context, path string) Handler {
-	node := t.root.findNode(ctx, path)
+// Delete removes the leaf handler of the pattern, pruning the nodes left
+// without handler nor edges. It reports whether the pattern had a handler.
+func (t *tree) Delete(pattern string) bool {
+	n, parents := t.leaf(pattern)
+	if n == nil || n.handler == nil {
+		return false
+	}
+	n.handler, n.route = nil, nil
+	t.prune(pattern, n, parents)
+	return true
+}
+
+// deleteRoute removes the route of the entry from the leaf of its pattern,
+// its handler or its variant for a route with conditions.
+func (t *tree) deleteRoute(e *routeEntry) {
+	n, parents := t.leaf(e.pattern)
+	if n == nil {
+		return
+	}
+	if len(e.conds) == 0 {
+		if n.route == e {
+			n.handler, n.route = nil, nil
+			t.prune(e.pattern, n, parents)
+		}
+		return
+	}
+	for i, v := range n.variants {
+		if v.route == e {
+			n.variants = append(n.variants[:i:i], n.variants[i+1:]...)
+			t.prune(e.pattern, n, parents)
+			return
+		}
+	}
+}
+
+// prune clears the leaf n of the pattern once it has neither a handler nor
+// variants, pruning the nodes left without handler nor edges up the path.
+func (t *tree) prune(pattern string, n *node, parents []*node) {
+	if n.isLeaf() {
+		return
+	}
+	n.pattern = ""
+	delete(t.static, pattern)
+
+	// Prune the empty nodes up the path
+	i := len(parents) - 1
+	for ; i >= 0 && !n.isLeaf() && n.numEdges() == 0; i-- {
+		parents[i].removeEdge(n)
+		n = parents[i]
+	}
+	if i >= 0 {
+		parents[i].compress(n)
+	}
+}
+
+// compress merges the static node n, left without handler and with a
+// single static edge, into that edge, so lookups don't step through it.
+// Inserting routes only splits nodes where the prefixes of two edges
+// diverge, so such chains are left by deleted routes alone, see
+// tree.deleteRoute. The child is kept rather than n, being the node of the
+// patterns indexed by tree.static.
+func (parent *node) compress(n *node) {
+	if n.typ != ntStatic || n.isLeaf() || n.numEdges() != 1 || len(n.edges[ntStatic]) != 1 {
+		return
+	}
+	child := n.edges[ntStatic][0].node
+	child.prefix = n.prefix + child.prefix
+	parent.replaceEdge(edge{label: n.prefix[0], node: child})
+}
+
+// leaf returns the node of the pattern, as inserted, along with its
+// parents from the root.
+func (t *tree) leaf(pattern string) (*node, []*node) {
+	var parents []*node
+	n := t.root
+	search := pattern
+
+	for len(search) > 0 {
+		parents = append(parents, n)
+		switch search[0] {
+		case ':':
+			n = n.getParamEdge(search)
+		case '*':
+			n = n.getCatchAllEdge(search)
+		default:
+			n = n.getEdge(search[0])
+		}
+		if n == nil {
+			return nil, nil
+		}
+
+		if n.typ > ntStatic {
+			p := strings.Index(search, "/")
+			if p < 0 {
+				p = len(search)
+			}
+			search = search[p:]
+			continue
+		}
+
+		if !strings.HasPrefix(search, n.prefix) {
+			return nil, nil
+		}
+		search = search[len(n.prefix):]
+	}
+	return n, parents
+}
+
+func (n *node) numEdges() int {
+	num := 0
+	for _, edges := range n.edges {
+		num += len(edges)
+	}
+	return num
+}
+
+func (n *node) removeEdge(child *node) {
+	edges := n.edges[child.typ]
+	for i := range edges {
+		if edges[i].node == child {
+			n.edges[child.typ] = append(edges[:i], edges[i+1:]...)
+			n.reindex()
+			return
+		}
+	}
+}
+
+func (t *tree) Find(ctx *Context, path []byte) Handler {
+	node := t.findRoute(ctx, path)
 	if node == nil {
 		return nil
 	}
 	return node.handler
 }
 
+// findRoute returns the leaf node matching the path. Paths of patterns
+// without params are looked up directly, as the tree would match them
+// first anyway, static segments matching before params. The tree is walked
+// on the bytes of the path, which are turned into a string once the route
+// matched, only if it has params, their values being cut from it.
+func (t *tree) findRoute(ctx *Context, path []byte) *node {
+	if !t.caseInsensitive {
+		if n := t.static[string(path)]; n != nil && n.isLeaf() {
+			return n
+		}
+	}
+
+	np := len(ctx.pending)
+	n := t.root.findNode(ctx, path, t.caseInsensitive)
+	if n != nil && len(ctx.pending) > np {
+		spath := string(path)
+		for _, p := range ctx.pending[np:] {
+			// Values are slices of the path, starting where their capacity
+			// tells
+			i := cap(path) - cap(p.value)
+			ctx.Params.Add(p.key, spath[i:i+len(p.value)])
+		}
+	}
+	ctx.pending = ctx.pending[:np]
+	return n
+}
+
+// canonicalPath returns the path as spelled by the pattern it matched,
+// keeping the case of param and wildcard values from the path.
+func canonicalPath(pattern, path string) string {
+	buf := make([]byte, 0, len(path))
+	i, j := 0, 0
+	for i < len(pattern) && j < len(path) {
+		switch pattern[i] {
+		case ':':
+			for i < len(pattern) && pattern[i] != '/' {
+				i++
+			}
+			for j < len(path) && path[j] != '/' {
+				buf = append(buf, path[j])
+				j++
+			}
+		case '*':
+			for i < len(pattern) && pattern[i] != '/' {
+				i++
+			}
+			if i == len(pattern) {
+				return string(append(buf, path[j:]...))
+			}
+			// Mid-path catch-all, spanning the path up to as many segments
+			// from its end as the rest of the pattern has
+			end := len(path)
+			for k := strings.Count(pattern[i:], "/"); k > 0 && end > j; k-- {
+				end = j + strings.LastIndexByte(path[j:end], '/')
+				if end < j {
+					end = j
+				}
+			}
+			buf = append(buf, path[j:end]...)
+			j = end
+		default:
+			buf = append(buf, pattern[i])
+			i++
+			j++
+		}
+	}
+	return string(append(buf, path[j:]...))
+}
+
 // Walk is used to walk the tree
 func (t *tree) Walk(fn WalkFn) {
 	t.recursiveWalk(t.root, fn)
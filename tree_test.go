@@ -72,7 +72,7 @@ func TestTree(t *testing.T) {
 	tr.Insert("/admin/apps/:id", hAdminAppShow)
 	tr.Insert("/admin/apps/:id/*ff", hAdminAppShowCatchall)
 
-	tr.Insert("/admin/*ff", hStub) // catchall segment will get replaced by next route
+	tr.Insert("/admin/*", hStub) // catchall segment will get replaced by next route
 	tr.Insert("/admin/*", hAdminCatchall)
 
 	tr.Insert("/users/:userID/profile", hUserProfile)
@@ -124,7 +124,7 @@ func TestTree(t *testing.T) {
 		{r: "/admin/hi", h: hAdminCatchall, p: map[string]string{"*": "hi"}},
 		{r: "/admin/lots/of/:fun", h: hAdminCatchall, p: map[string]string{"*": "lots/of/:fun"}},
 		{r: "/admin/apps/333", h: hAdminAppShow, p: map[string]string{"id": "333"}},
-		{r: "/admin/apps/333/woot", h: hAdminAppShowCatchall, p: map[string]string{"id": "333", "*": "woot"}},
+		{r: "/admin/apps/333/woot", h: hAdminAppShowCatchall, p: map[string]string{"id": "333", "ff": "woot"}},
 
 		{r: "/hubs/123/view", h: hHubView1, p: map[string]string{"hubID": "123"}},
 		{r: "/hubs/123/view/index.html", h: hHubView2, p: map[string]string{"hubID": "123", "*": "index.html"}},
@@ -144,8 +144,8 @@ func TestTree(t *testing.T) {
 	for i, tt := range tests {
 		// params := make(map[string]string, 0)
 		rctx := newContext(context.Background())
-		handler := tr.Find(rctx, tt.r) //, params)
-		params := urlParams(rctx)
+		handler := tr.Find(rctx, []byte(tt.r)) //, params)
+		params := URLParams(rctx)
 		if fmt.Sprintf("%v", tt.h) != fmt.Sprintf("%v", handler) {
 			t.Errorf("input [%d]: find '%s' expecting handler:%v , got:%v", i, tt.r, tt.h, handler)
 		}
@@ -155,6 +155,244 @@ func TestTree(t *testing.T) {
 	}
 }
 
+func TestTreeTypedParams(t *testing.T) {
+	hOrder := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hOrderNamed := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hItem := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hPost := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hPostEdit := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+
+	tr := &tree{root: &node{}}
+	tr.Insert("/orders/:name", hOrderNamed)
+	tr.Insert("/orders/:id|int", hOrder)
+	tr.Insert("/items/:key|uuid", hItem)
+	tr.Insert("/posts/:title|slug", hPost)
+	tr.Insert("/posts/:title|slug/edit", hPostEdit)
+
+	tests := []struct {
+		r string            // input request path
+		h Handler           // output matched handler
+		p map[string]string // output params
+	}{
+		{r: "/orders/42", h: hOrder, p: map[string]string{"id": "42"}},
+		{r: "/orders/-7", h: hOrder, p: map[string]string{"id": "-7"}},
+		{r: "/orders/latest", h: hOrderNamed, p: map[string]string{"name": "latest"}},
+		{r: "/items/6ba7b810-9dad-11d1-80b4-00c04fd430c8", h: hItem, p: map[string]string{"key": "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}},
+		{r: "/items/6ba7b810", h: nil, p: emptyParams},
+		{r: "/posts/going-all-the-way", h: hPost, p: map[string]string{"title": "going-all-the-way"}},
+		{r: "/posts/going-all-the-way/edit", h: hPostEdit, p: map[string]string{"title": "going-all-the-way"}},
+		{r: "/posts/Going--all", h: nil, p: emptyParams},
+	}
+
+	for i, tt := range tests {
+		rctx := newContext(context.Background())
+		handler := tr.Find(rctx, []byte(tt.r))
+		params := URLParams(rctx)
+		if fmt.Sprintf("%v", tt.h) != fmt.Sprintf("%v", handler) {
+			t.Errorf("input [%d]: find '%s' expecting handler:%v , got:%v", i, tt.r, tt.h, handler)
+		}
+		if !reflect.DeepEqual(tt.p, params) {
+			t.Errorf("input [%d]: find '%s' expecting params:%v , got:%v", i, tt.r, tt.p, params)
+		}
+	}
+
+	recv := catchPanic(func() {
+		tr.Insert("/orders/:id|float", hOrder)
+	})
+	if recv == nil {
+		t.Fatal("registering an unknown param type did not panic")
+	}
+}
+
+func TestTreeMidPathWildcard(t *testing.T) {
+	hAsset := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hMeta := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hRaw := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hDocs := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+
+	tr := &tree{root: &node{}}
+	tr.Insert("/assets/*/meta.json", hMeta)
+	tr.Insert("/assets/*", hAsset)
+	tr.Insert("/assets/*/:version/raw", hRaw)
+	tr.Insert("/docs/*/index", hDocs)
+
+	tests := []struct {
+		r string            // input request path
+		h Handler           // output matched handler
+		p map[string]string // output params
+	}{
+		{r: "/assets/a/meta.json", h: hMeta, p: map[string]string{"*": "a"}},
+		{r: "/assets/a/b/c/meta.json", h: hMeta, p: map[string]string{"*": "a/b/c"}},
+		{r: "/assets/a/b/v2/raw", h: hRaw, p: map[string]string{"*": "a/b", "version": "v2"}},
+		{r: "/assets/a/b", h: hAsset, p: map[string]string{"*": "a/b"}},
+		{r: "/assets/meta.json", h: hAsset, p: map[string]string{"*": "meta.json"}},
+		{r: "/docs/guide/routing/index", h: hDocs, p: map[string]string{"*": "guide/routing"}},
+		{r: "/docs/index", h: nil, p: emptyParams},
+		{r: "/docs/guide", h: nil, p: emptyParams},
+	}
+
+	for i, tt := range tests {
+		rctx := newContext(context.Background())
+		handler := tr.Find(rctx, []byte(tt.r))
+		params := URLParams(rctx)
+		if fmt.Sprintf("%v", tt.h) != fmt.Sprintf("%v", handler) {
+			t.Errorf("input [%d]: find '%s' expecting handler:%v , got:%v", i, tt.r, tt.h, handler)
+		}
+		if !reflect.DeepEqual(tt.p, params) {
+			t.Errorf("input [%d]: find '%s' expecting params:%v , got:%v", i, tt.r, tt.p, params)
+		}
+	}
+
+	if cpath := canonicalPath("/assets/*/meta.json", "/ASSETS/A/B/META.JSON"); cpath != "/assets/A/B/meta.json" {
+		t.Errorf("got canonical path '%s'", cpath)
+	}
+	if !tr.Delete("/assets/*/meta.json") || tr.Find(newContext(context.Background()), []byte("/assets/a/meta.json")) == nil {
+		t.Fatalf("expecting /assets/*/meta.json to be deleted, leaving /assets/*")
+	}
+}
+
+func TestTreeNamedCatchAll(t *testing.T) {
+	hFile := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hMeta := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hMount := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+
+	tr := &tree{root: &node{}}
+	tr.Insert("/files/*path", hFile)
+	tr.Insert("/objects/*key/meta", hMeta)
+	tr.Insert("/objects/*", hMount)
+
+	tests := []struct {
+		r string            // input request path
+		h Handler           // output matched handler
+		p map[string]string // output params
+	}{
+		{r: "/files/a/b.txt", h: hFile, p: map[string]string{"path": "a/b.txt"}},
+		{r: "/objects/a/b/meta", h: hMeta, p: map[string]string{"key": "a/b"}},
+		{r: "/objects/a/b", h: hMount, p: map[string]string{"*": "a/b"}},
+	}
+	for i, tt := range tests {
+		rctx := newContext(context.Background())
+		handler := tr.Find(rctx, []byte(tt.r))
+		params := URLParams(rctx)
+		if fmt.Sprintf("%v", tt.h) != fmt.Sprintf("%v", handler) {
+			t.Errorf("input [%d]: find '%s' expecting handler:%v , got:%v", i, tt.r, tt.h, handler)
+		}
+		if !reflect.DeepEqual(tt.p, params) {
+			t.Errorf("input [%d]: find '%s' expecting params:%v , got:%v", i, tt.r, tt.p, params)
+		}
+	}
+
+	if cpath := canonicalPath("/objects/*key/meta", "/OBJECTS/A/B/META"); cpath != "/objects/A/B/meta" {
+		t.Errorf("got canonical path '%s'", cpath)
+	}
+}
+
+func TestTreeDelete(t *testing.T) {
+	hArticle := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hArticleEdit := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hArticleNew := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hAdmin := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+
+	tr := &tree{root: &node{}}
+	tr.Insert("/articles/:id", hArticle)
+	tr.Insert("/articles/:id/edit", hArticleEdit)
+	tr.Insert("/articles/new", hArticleNew)
+	tr.Insert("/admin/*", hAdmin)
+
+	if !tr.Delete("/articles/:articleID") {
+		t.Fatalf("expecting /articles/:id to be deleted")
+	}
+	if tr.Delete("/articles/:id") || tr.Delete("/articles/none") || tr.Delete("/articles") {
+		t.Fatalf("expecting missing routes not to be deleted")
+	}
+	if !tr.Delete("/admin/*") || tr.root.getEdge('/').prefix != "/articles/" {
+		t.Fatalf("expecting /admin/* to be deleted and its nodes pruned and merged")
+	}
+
+	tests := []struct {
+		r string  // input request path
+		h Handler // output matched handler
+	}{
+		{r: "/articles/1", h: nil},
+		{r: "/articles/1/edit", h: hArticleEdit},
+		{r: "/articles/new", h: hArticleNew},
+		{r: "/admin/users", h: nil},
+	}
+	for i, tt := range tests {
+		handler := tr.Find(newContext(context.Background()), []byte(tt.r))
+		if fmt.Sprintf("%v", tt.h) != fmt.Sprintf("%v", handler) {
+			t.Errorf("input [%d]: find '%s' expecting handler:%v , got:%v", i, tt.r, tt.h, handler)
+		}
+	}
+}
+
+func TestTreeStatic(t *testing.T) {
+	hAbout := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hAboutTeam := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hPage := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+
+	tr := &tree{root: &node{}}
+	tr.Insert("/about", hAbout)
+	tr.Insert("/:page", hPage)
+	tr.Insert("/about/team", hAboutTeam)
+	tr.Insert("/a", hPage) // splits the node of /about
+
+	if len(tr.static) != 3 {
+		t.Fatalf("expecting 3 static routes, got %v", tr.static)
+	}
+	for path, h := range map[string]Handler{"/about": hAbout, "/about/team": hAboutTeam, "/a": hPage} {
+		if n := tr.static[path]; n == nil || fmt.Sprintf("%v", n.handler) != fmt.Sprintf("%v", h) {
+			t.Fatalf("expecting the node of %s to be indexed", path)
+		}
+	}
+
+	rctx := newContext(context.Background())
+	if h := tr.Find(rctx, []byte("/about")); fmt.Sprintf("%v", h) != fmt.Sprintf("%v", hAbout) || len(rctx.Params) != 0 {
+		t.Fatalf("expecting /about to match without params")
+	}
+
+	tr.Delete("/about")
+	if _, ok := tr.static["/about"]; ok {
+		t.Fatalf("expecting /about to be dropped from the static routes")
+	}
+	if h := tr.Find(rctx, []byte("/about")); fmt.Sprintf("%v", h) != fmt.Sprintf("%v", hPage) || rctx.Params.Get("page") != "about" {
+		t.Fatalf("expecting /about to match /:page once deleted")
+	}
+}
+
+func TestTreeCompress(t *testing.T) {
+	hUsers := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	hGroups := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+
+	tr := &tree{root: &node{}}
+	tr.Insert("/admin/users", hUsers)
+	tr.Insert("/admin/groups", hGroups)
+	tr.Insert("/admin/users/:id", hUsers)
+	if n := tr.root.edges[ntStatic][0].node; n.prefix != "/admin/" || n.indices != "gu" {
+		t.Fatalf("expecting /admin/ to index its edges, got '%s' '%s'", n.prefix, n.indices)
+	}
+
+	// The node of /admin/ is left with a single edge, merged into it
+	tr.Delete("/admin/groups")
+	n := tr.root.edges[ntStatic][0].node
+	if n.prefix != "/admin/users" || n != tr.static["/admin/users"] {
+		t.Fatalf("expecting /admin/users to be merged in a single node, got '%s'", n.prefix)
+	}
+
+	rctx := newContext(context.Background())
+	if h := tr.Find(rctx, []byte("/admin/users/1")); fmt.Sprintf("%v", h) != fmt.Sprintf("%v", hUsers) || rctx.Params.Get("id") != "1" {
+		t.Fatalf("expecting /admin/users/1 to match /admin/users/:id")
+	}
+	if h := tr.Find(rctx, []byte("/admin/groups")); h != nil {
+		t.Fatalf("expecting /admin/groups not to match once deleted")
+	}
+
+	tr.Insert("/admin/groups", hGroups)
+	if h := tr.Find(rctx, []byte("/admin/groups")); fmt.Sprintf("%v", h) != fmt.Sprintf("%v", hGroups) {
+		t.Fatalf("expecting /admin/groups to match once inserted again")
+	}
+}
+
 func debugPrintTree(parent int, i int, n *node, label byte) bool {
 	numEdges := 0
 	for _, edges := range n.edges {
@@ -193,14 +431,37 @@ func BenchmarkTreeGet(b *testing.B) {
 	tr.Insert("/pinggggg", h2)
 	tr.Insert("/hello", h1)
 
+	// Routing contexts are pooled by the Mux
+	mctx := newContext(context.Background())
+	path := []byte("/ping/123/456")
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tr.Find(mctx, path)
+		mctx.reset()
+	}
+}
+
+func BenchmarkTreeGetStatic(b *testing.B) {
+	h1 := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+	h2 := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+
+	tr := &tree{root: &node{}}
+	tr.Insert("/", h1)
+	tr.Insert("/ping", h2)
+	tr.Insert("/pingall", h2)
+	tr.Insert("/ping/:id", h2)
+	tr.Insert("/pinggggg", h2)
+	tr.Insert("/hello", h1)
+
+	mctx := newContext(context.Background())
+	path := []byte("/pingall")
 	b.ReportAllocs()
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		// params := map[string]string{}
-		mctx := newContext(context.Background())
-		tr.Find(mctx, "/ping/123/456")
-		// tr.Find("/pingggg", params)
+		tr.Find(mctx, path)
 	}
 }
 
@@ -224,3 +485,33 @@ func BenchmarkTreeGet(b *testing.B) {
 // 		mx.ServeHTTP(w, r)
 // 	}
 // }
+
+func TestTreeFindAllocs(t *testing.T) {
+	h := HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {})
+
+	r := NewRouter()
+	r.Get("/ping", h)
+	r.Get("/ping/:id/:opt", h)
+
+	// The path is matched as bytes, and the param values cut from a single
+	// string of it. Served with a context of its own, ie. by a framework,
+	// the Mux wraps it in a pooled routing context.
+	ctx := context.WithValue(context.Background(), "tenant", "acme")
+	tests := []struct {
+		path   string
+		allocs float64
+	}{
+		{"/ping", 0},
+		{"/ping/123/456", 1},
+	}
+	for _, tt := range tests {
+		var fctx fasthttp.RequestCtx
+		fctx.Request.SetRequestURI(tt.path)
+		if allocs := testing.AllocsPerRun(100, func() { r.ServeHTTP(&fctx) }); allocs != tt.allocs {
+			t.Fatalf("%s: expecting %v allocations, got %v", tt.path, tt.allocs, allocs)
+		}
+		if allocs := testing.AllocsPerRun(100, func() { r.ServeHTTPC(ctx, &fctx) }); allocs != tt.allocs {
+			t.Fatalf("%s: expecting %v allocations with a context, got %v", tt.path, tt.allocs, allocs)
+		}
+	}
+}
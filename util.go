@@ -2,6 +2,10 @@ package chi
 
 import (
 	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"runtime"
 	"strings"
 
 	"github.com/valyala/fasthttp"
@@ -32,8 +36,18 @@ func chain(middlewares []interface{}, handlers ...interface{}) Handler {
 	case func(context.Context, *fasthttp.RequestCtx):
 		cxh = HandlerFunc(t)
 	case func(*fasthttp.RequestCtx):
-		cxh = HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
-			t(fctx)
+		cxh = WrapF(t)
+	case fasthttp.RequestHandler:
+		cxh = WrapF(t)
+	case http.Handler:
+		cxh = WrapHTTP(t)
+	case func(http.ResponseWriter, *http.Request):
+		cxh = WrapHTTP(http.HandlerFunc(t))
+	case func(context.Context, *fasthttp.RequestCtx) error:
+		cxh = errorHandler(t)
+	case func(*fasthttp.RequestCtx) error:
+		cxh = errorHandler(func(ctx context.Context, fctx *fasthttp.RequestCtx) error {
+			return t(fctx)
 		})
 	}
 
@@ -60,6 +74,29 @@ func mwrap(middleware interface{}) func(Handler) Handler {
 
 	case func(Handler) Handler:
 		return mw
+	case func(fasthttp.RequestHandler) fasthttp.RequestHandler:
+		return fasthttpMiddleware(mw)
+	case Middlewares:
+		return mw.Handler
+	}
+}
+
+// The user value carrying the context across fasthttp middlewares
+const fasthttpCtxKey = "chi.context"
+
+// fasthttpMiddleware adapts a fasthttp middleware to a chi middleware. The
+// fasthttp chain is built once, the request context going through it as a
+// user value of the request.
+func fasthttpMiddleware(mw func(fasthttp.RequestHandler) fasthttp.RequestHandler) func(Handler) Handler {
+	return func(next Handler) Handler {
+		h := mw(func(fctx *fasthttp.RequestCtx) {
+			ctx, _ := fctx.UserValue(fasthttpCtxKey).(context.Context)
+			next.ServeHTTPC(ctx, fctx)
+		})
+		return HandlerFunc(func(ctx context.Context, fctx *fasthttp.RequestCtx) {
+			fctx.SetUserValue(fasthttpCtxKey, ctx)
+			h(fctx)
+		})
 	}
 }
 
@@ -69,21 +106,72 @@ func assertMiddleware(middleware interface{}) interface{} {
 	default:
 		panic(fmt.Sprintf("chi: unsupported middleware signature: %T", t))
 	case func(Handler) Handler:
+	case func(fasthttp.RequestHandler) fasthttp.RequestHandler:
+	case Middlewares:
 	}
 	return middleware
 }
 
-// Respond with just the allowed methods, as required by RFC2616 for
-// 405 Method not allowed.
+// Respond with a 405 Method not allowed, the Allow header required by
+// RFC2616 being set by the router.
 func methodNotAllowedHandler(ctx context.Context, fctx *fasthttp.RequestCtx) {
-	methods := make([]string, len(methodMap))
-	i := 0
-	for m := range methodMap {
-		methods[i] = m // still faster than append to array with capacity
-		i++
-	}
-
-	fctx.Response.Header.Add("Allow", strings.Join(methods, ","))
 	fctx.SetStatusCode(405)
 	fctx.Write([]byte("Method Not Allowed"))
 }
+
+// Redirect the request to the same URL with its routing path `from`
+// replaced by `to`, keeping the query string. GET and HEAD requests are
+// answered with a 301, other methods with a 308 so they're replayed as is.
+// Decoded paths, unless `raw`, are escaped again, so the params of the
+// request can't smuggle CR/LF into the Location header, and requests whose
+// target would still be unsafe get a 400.
+func redirectRoutePath(rctx *Context, fctx *fasthttp.RequestCtx, from, to string, raw bool) {
+	path := string(fctx.URI().PathOriginal())
+	if !raw {
+		path, from, to = escapePath(string(fctx.Path())), escapePath(from), escapePath(to)
+	}
+	target := to
+	if strings.HasSuffix(path, from) {
+		target = path[:len(path)-len(from)] + to
+	}
+	if strings.HasPrefix(target, "//") {
+		// Not a protocol-relative URL to another host
+		target = "/%2F" + target[2:]
+	}
+	if q := fctx.URI().QueryString(); len(q) > 0 {
+		target += "?" + string(q)
+	}
+
+	code := fasthttp.StatusPermanentRedirect
+	if fctx.IsGet() || fctx.IsHead() {
+		code = fasthttp.StatusMovedPermanently
+	}
+	if err := Redirect(fctx, target, code); err != nil {
+		rctx.renderError(fctx, fasthttp.StatusBadRequest, err)
+	}
+}
+
+// middlewareName returns the name of a middleware func as `package.Func`,
+// naming closures after their enclosing func, ie. the middleware returned by
+// middleware.Throttle(10) is "middleware.ThrottleBacklog".
+func middlewareName(mw interface{}) string {
+	v := reflect.ValueOf(mw)
+	if v.Kind() != reflect.Func {
+		return fmt.Sprintf("%T", mw)
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return fmt.Sprintf("%T", mw)
+	}
+
+	name := path.Base(fn.Name())
+	name = strings.TrimSuffix(name, "-fm")
+	for {
+		p := strings.LastIndex(name, ".func")
+		if p < 0 || strings.Trim(name[p+5:], "0123456789.") != "" {
+			break
+		}
+		name = name[:p]
+	}
+	return name
+}